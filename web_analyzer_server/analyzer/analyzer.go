@@ -1,18 +1,36 @@
 package analyzer
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
 	"sync"
+	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/sashithaf16/peekalo/config"
 	"github.com/sashithaf16/peekalo/logger"
+	"github.com/sashithaf16/peekalo/metrics"
+	"github.com/sashithaf16/peekalo/robots"
+	"github.com/sashithaf16/peekalo/snapshot"
+	"github.com/sashithaf16/peekalo/tracing"
+	"github.com/sashithaf16/peekalo/urlnorm"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
 	"golang.org/x/net/html"
 )
 
+// ErrDisallowedByRobots is returned when robots.txt is enforced and disallows the requested URL
+var ErrDisallowedByRobots = errors.New("URL is disallowed by robots.txt")
+
 type HttpClientInterface interface {
 	Do(req *http.Request) (*http.Response, error)
 }
@@ -21,49 +39,253 @@ type Analyzer struct {
 	logger     logger.Logger
 	cfg        *config.Config
 	httpClient HttpClientInterface
+	robots     *robots.Checker
+	snapshots  snapshot.Store
+	previous   ResultLookup
+	cacheTTL   time.Duration
+	norm       *urlnorm.Normalizer
+}
+
+// Option configures optional collaborators of the Analyzer
+type Option func(*Analyzer)
+
+// WithRobotsChecker makes the analyzer consult robots.txt before fetching pages
+func WithRobotsChecker(checker *robots.Checker) Option {
+	return func(a *Analyzer) {
+		a.robots = checker
+	}
+}
+
+// WithSnapshotStore makes the analyzer keep the raw body and headers of every fetched page
+func WithSnapshotStore(store snapshot.Store) Option {
+	return func(a *Analyzer) {
+		a.snapshots = store
+	}
+}
+
+// ResultLookup finds the latest stored analysis of a URL that was made by fetching it, and when it was made
+type ResultLookup interface {
+	LatestFetched(ctx context.Context, pageURL string) (PageInfo, time.Time, bool)
+}
+
+// WithPreviousResults revalidates previously analyzed URLs with If-None-Match and If-Modified-Since
+// and reuses the previous analysis when the page answers 304. Analyses younger than ttl are reused
+// without fetching at all; a ttl of 0 always revalidates.
+func WithPreviousResults(lookup ResultLookup, ttl time.Duration) Option {
+	return func(a *Analyzer) {
+		a.previous = lookup
+		a.cacheTTL = ttl
+	}
 }
 
 type PageInfo struct {
+	StatusCode  int            `json:"status_code"`
+	FinalURL    string         `json:"final_url"` // URL after following redirects
+	Canonical   string         `json:"canonical,omitempty"`
 	HTMLVersion string         `json:"html_version"`
 	Title       string         `json:"title"`
 	Headings    map[string]int `json:"headings"`
 	Links       LinkStats      `json:"link_stats"`
-	HasLogin    bool           `json:"has_login"`
+	HasLogin    bool           `json:"has_login"` // the authentication score reached the login threshold or a mechanism was found
+	Auth        AuthInfo       `json:"auth"`
+	Forms       []FormInfo     `json:"forms,omitempty"`
+	Phishing    PhishingInfo   `json:"phishing"`
+	Robots      *RobotsInfo    `json:"robots,omitempty"`
+	Snapshot    string         `json:"snapshot,omitempty"` // ID of the stored capture of the raw body
+
+	ETag         string `json:"etag,omitempty"`          // sent as If-None-Match when the URL is analyzed again
+	LastModified string `json:"last_modified,omitempty"` // sent as If-Modified-Since when the URL is analyzed again
+	NotModified  bool   `json:"not_modified,omitempty"`  // the page answered 304 and the previous analysis was reused
+	Cached       bool   `json:"cached,omitempty"`        // the previous analysis was younger than the cache TTL and reused without fetching
+}
+
+type RobotsInfo struct {
+	Disallowed map[string]bool `json:"disallowed"` // keyed by user agent
+	Sitemaps   []string        `json:"sitemaps"`
 }
 
 type LinkStats struct {
-	Internal     int `json:"internal"`
-	External     int `json:"external"`
-	Inaccessible int `json:"inaccessible"`
+	Internal      int      `json:"internal"`
+	External      int      `json:"external"`
+	Inaccessible  int      `json:"inaccessible"`
+	InternalLinks []string `json:"internal_links,omitempty"` // unique normalized internal URLs
+	ExternalLinks []string `json:"external_links,omitempty"` // unique normalized external URLs
+	// TrackingVariants counts unique links that differed from their normalized URL only by tracking parameters
+	TrackingVariants int `json:"tracking_variants"`
 }
 
-func NewAnalyzer(logger logger.Logger, cfg *config.Config, httpClient HttpClientInterface) *Analyzer {
-	return &Analyzer{logger: logger, cfg: cfg, httpClient: httpClient}
+func NewAnalyzer(logger logger.Logger, cfg *config.Config, httpClient HttpClientInterface, opts ...Option) *Analyzer {
+	a := &Analyzer{logger: logger, cfg: cfg, httpClient: httpClient, norm: urlnorm.New(cfg.TrackingParams)}
+	for _, opt := range opts {
+		opt(a)
+	}
+	return a
+}
+
+// log returns the request-scoped logger carried by ctx, falling back to the analyzer's logger
+func (a *Analyzer) log(ctx context.Context) *logger.Logger {
+	return logger.FromContext(ctx, a.logger)
 }
 
 func (a *Analyzer) AnalyzeURL(ctx context.Context, pageURL string) (PageInfo, error) {
+	metrics.InFlightAnalyses.Inc()
+	defer metrics.InFlightAnalyses.Dec()
+	defer observeDuration(metrics.AnalysisDuration, time.Now())
+
+	ctx, span := tracing.Tracer().Start(ctx, "AnalyzeURL", trace.WithAttributes(semconv.URLFull(pageURL)))
+	defer span.End()
+
+	parsedURL, err := url.Parse(pageURL)
+	if err != nil {
+		a.log(ctx).Error().Err(err).Msgf("Invalid URL: %s", pageURL)
+		metrics.AnalysisErrorCount.WithLabelValues("invalid_url").Inc()
+		return PageInfo{}, fmt.Errorf("invalid base URL: %v", err)
+	}
+
+	robotsInfo, err := a.checkRobots(ctx, parsedURL)
+	if err != nil {
+		if errors.Is(err, ErrDisallowedByRobots) {
+			metrics.AnalysisErrorCount.WithLabelValues("robots_disallowed").Inc()
+		} else {
+			metrics.AnalysisErrorCount.WithLabelValues("robots").Inc()
+		}
+		return PageInfo{}, err
+	}
+
+	var prev *PageInfo
+	if a.previous != nil {
+		if info, analyzedAt, ok := a.previous.LatestFetched(ctx, pageURL); ok {
+			if a.cacheTTL > 0 && time.Since(analyzedAt) < a.cacheTTL {
+				a.log(ctx).Debug().Msgf("Reusing analysis of %s made at %s", pageURL, analyzedAt)
+				metrics.ConditionalFetchCount.WithLabelValues("cached").Inc()
+				return reuse(info, robotsInfo, true), nil
+			}
+			if info.ETag != "" || info.LastModified != "" {
+				prev = &info
+			}
+		}
+	}
+
 	req, err := http.NewRequestWithContext(ctx, "GET", pageURL, nil)
 	if err != nil {
+		metrics.AnalysisErrorCount.WithLabelValues("invalid_url").Inc()
 		return PageInfo{}, fmt.Errorf("failed to create request: %v", err)
 	}
+	if a.cfg.UserAgent != "" {
+		req.Header.Set("User-Agent", a.cfg.UserAgent)
+	}
+	if prev != nil {
+		if prev.ETag != "" {
+			req.Header.Set("If-None-Match", prev.ETag)
+		}
+		if prev.LastModified != "" {
+			req.Header.Set("If-Modified-Since", prev.LastModified)
+		}
+	}
 
-	resp, err := a.httpClient.Do(req)
-
+	resp, err := a.fetch(ctx, req)
 	if err != nil {
-		a.logger.Error().Err(err).Msgf("failed to fetch URL: %s", pageURL)
-		return PageInfo{}, fmt.Errorf("failed to fetch URL: %v", err)
+		a.log(ctx).Error().Err(err).Msgf("failed to fetch URL: %s", pageURL)
+		metrics.AnalysisErrorCount.WithLabelValues("fetch").Inc()
+		span.SetStatus(codes.Error, "fetch failed")
+		// wrapped so callers can recognize errors of the client, such as a refused redirect
+		return PageInfo{}, fmt.Errorf("failed to fetch URL: %w", err)
 	}
 	defer resp.Body.Close()
 
-	doc, err := html.Parse(resp.Body)
+	if prev != nil {
+		if resp.StatusCode == http.StatusNotModified {
+			metrics.ConditionalFetchCount.WithLabelValues("not_modified").Inc()
+			return reuse(*prev, robotsInfo, false), nil
+		}
+		metrics.ConditionalFetchCount.WithLabelValues("modified").Inc()
+	}
+
+	// links are resolved against the final URL when the client followed redirects
+	finalURL := parsedURL
+	if resp.Request != nil && resp.Request.URL != nil {
+		finalURL = resp.Request.URL
+	}
+
+	var raw *bytes.Buffer
+	var src io.Reader = resp.Body
+	if a.snapshots != nil {
+		raw = &bytes.Buffer{}
+		src = io.TeeReader(resp.Body, raw)
+	}
+	body := &countingReader{r: src}
+	info, err := a.analyzeDocument(ctx, body, finalURL)
+	metrics.ResponseBodySize.Observe(float64(body.n))
 	if err != nil {
-		a.logger.Error().Err(err).Msgf("failed to parse HTML for URL: %s", pageURL)
-		return PageInfo{}, fmt.Errorf("failed to parse HTML: %v", err)
+		span.SetStatus(codes.Error, err.Error())
+		return PageInfo{}, err
 	}
-	parsedURL, err := url.Parse(pageURL)
+	info.StatusCode = resp.StatusCode
+	info.FinalURL = finalURL.String()
+	info.Robots = robotsInfo
+	info.ETag = resp.Header.Get("ETag")
+	info.LastModified = resp.Header.Get("Last-Modified")
+	if raw != nil {
+		info.Snapshot = a.saveSnapshot(ctx, finalURL, resp, raw.Bytes())
+	}
+	return info, nil
+}
+
+// reuse returns a previous analysis in place of a new one, keeping the current robots.txt verdict
+func reuse(prev PageInfo, robotsInfo *RobotsInfo, cached bool) PageInfo {
+	prev.Robots = robotsInfo
+	prev.Cached = cached
+	prev.NotModified = !cached
+	return prev
+}
+
+// saveSnapshot stores the raw body of a fetched page and returns the capture ID. Failing to store it
+// does not fail the analysis.
+func (a *Analyzer) saveSnapshot(ctx context.Context, finalURL *url.URL, resp *http.Response, body []byte) string {
+	s := &snapshot.Snapshot{URL: finalURL.String(), StatusCode: resp.StatusCode, Header: resp.Header}
+	if err := a.snapshots.Put(ctx, s, body); err != nil {
+		a.log(ctx).Error().Err(err).Msgf("Failed to store snapshot of URL: %s", finalURL)
+		return ""
+	}
+	return s.ID
+}
+
+// AnalyzeDocument analyzes HTML the caller already has, without any fetching. Links are classified
+// against baseURL; when it is empty, relative links count as internal and absolute ones as external.
+func (a *Analyzer) AnalyzeDocument(ctx context.Context, r io.Reader, baseURL string) (PageInfo, error) {
+	ctx, span := tracing.Tracer().Start(ctx, "AnalyzeDocument", trace.WithAttributes(semconv.URLFull(baseURL)))
+	defer span.End()
+
+	base := &url.URL{}
+	if baseURL != "" {
+		parsed, err := url.Parse(baseURL)
+		if err != nil || !parsed.IsAbs() || parsed.Host == "" {
+			metrics.AnalysisErrorCount.WithLabelValues("invalid_url").Inc()
+			return PageInfo{}, fmt.Errorf("invalid base URL: %s", baseURL)
+		}
+		base = parsed
+	}
+
+	info, err := a.analyzeDocument(ctx, &countingReader{r: r}, base)
 	if err != nil {
-		a.logger.Error().Err(err).Msgf("Invalid URL: %s", pageURL)
-		return PageInfo{}, fmt.Errorf("invalid base URL: %v", err)
+		span.SetStatus(codes.Error, err.Error())
+		return PageInfo{}, err
+	}
+	info.FinalURL = baseURL
+	return info, nil
+}
+
+// analyzeDocument parses the HTML in body and runs the analyzers concurrently.
+// The fetch-related fields of the returned PageInfo are left for the caller to fill in.
+func (a *Analyzer) analyzeDocument(ctx context.Context, body *countingReader, baseURL *url.URL) (PageInfo, error) {
+	_, parseSpan := tracing.Tracer().Start(ctx, "ParseHTML")
+	doc, err := html.Parse(body)
+	parseSpan.SetAttributes(attribute.Int64("html.body_size", body.n))
+	parseSpan.End()
+	if err != nil {
+		a.log(ctx).Error().Err(err).Msgf("failed to parse HTML for URL: %s", baseURL)
+		metrics.AnalysisErrorCount.WithLabelValues("parse").Inc()
+		return PageInfo{}, fmt.Errorf("failed to parse HTML: %v", err)
 	}
 
 	var wg sync.WaitGroup
@@ -71,30 +293,105 @@ func (a *Analyzer) AnalyzeURL(ctx context.Context, pageURL string) (PageInfo, er
 	titleCh := make(chan string, 1)
 	headingCh := make(chan map[string]int, 1)
 	linksCh := make(chan LinkStats, 1)
-	loginCh := make(chan bool, 1)
+	loginCh := make(chan AuthInfo, 1)
+	canonicalCh := make(chan string, 1)
+	formsCh := make(chan []FormInfo, 1)
 
-	wg.Add(5)
+	wg.Add(7)
 	go a.getHTMLVersion(ctx, doc, versionCh, &wg)
 	go a.getPageTitle(ctx, doc, titleCh, &wg)
 	go a.getHeadingsCount(ctx, doc, headingCh, &wg)
-	go a.getLinkStats(ctx, doc, linksCh, &wg, parsedURL)
-	go a.detectLoginForm(ctx, doc, loginCh, &wg)
+	go a.getLinkStats(ctx, doc, linksCh, &wg, baseURL)
+	go a.detectLoginForm(ctx, doc, loginCh, &wg, baseURL)
+	go a.getCanonicalURL(ctx, doc, canonicalCh, &wg, baseURL)
+	go a.getForms(ctx, doc, formsCh, &wg, baseURL)
 	wg.Wait()
-	a.logger.Debug().Msg("All analysis goroutines completed")
+	a.log(ctx).Debug().Msg("All analysis goroutines completed")
+
+	// goroutines skip sending once the context is cancelled, so the channels may be empty
+	if err := ctx.Err(); err != nil {
+		metrics.AnalysisErrorCount.WithLabelValues("cancelled").Inc()
+		return PageInfo{}, fmt.Errorf("analysis cancelled: %v", err)
+	}
 
+	auth := <-loginCh
 	info := PageInfo{
+		Canonical:   <-canonicalCh,
 		HTMLVersion: <-versionCh,
 		Title:       <-titleCh,
 		Headings:    <-headingCh,
 		Links:       <-linksCh,
-		HasLogin:    <-loginCh,
+		HasLogin:    auth.hasLogin(),
+		Auth:        auth,
+		Forms:       <-formsCh,
+	}
+	info.Phishing = a.assessPhishing(ctx, doc, baseURL, info)
+	return info, nil
+}
+
+// fetch performs the outbound request inside a client span carrying the HTTP semantic attributes
+func (a *Analyzer) fetch(ctx context.Context, req *http.Request) (*http.Response, error) {
+	_, span := tracing.Tracer().Start(ctx, "GET", trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(
+		semconv.HTTPRequestMethodKey.String(req.Method),
+		semconv.URLFull(req.URL.String()),
+		semconv.ServerAddress(req.URL.Hostname()),
+	))
+	defer span.End()
+
+	start := time.Now()
+	resp, err := a.httpClient.Do(req)
+	observeDuration(metrics.FetchDuration, start)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+
+	metrics.FetchStatusCount.WithLabelValues(strconv.Itoa(resp.StatusCode)).Inc()
+	span.SetAttributes(semconv.HTTPResponseStatusCode(resp.StatusCode))
+	if resp.StatusCode >= 400 {
+		span.SetStatus(codes.Error, http.StatusText(resp.StatusCode))
+	}
+	return resp, nil
+}
+
+// checkRobots consults robots.txt for pageURL according to the configured policy.
+// It returns nil info when no checker is configured or the policy is "ignore".
+func (a *Analyzer) checkRobots(ctx context.Context, pageURL *url.URL) (*RobotsInfo, error) {
+	policy := robots.Policy(a.cfg.RobotsPolicy)
+	if a.robots == nil || !policy.Enabled() {
+		return nil, nil
+	}
+
+	rules, err := a.robots.Rules(ctx, pageURL)
+	if err != nil {
+		a.log(ctx).Error().Err(err).Msgf("failed to check robots.txt for URL: %s", pageURL)
+		return nil, err
+	}
+
+	info := &RobotsInfo{
+		Disallowed: make(map[string]bool, len(robots.CommonUserAgents)),
+		Sitemaps:   rules.Sitemaps,
+	}
+	for _, agent := range robots.CommonUserAgents {
+		info.Disallowed[agent] = !rules.Allowed(agent, pageURL.RequestURI())
+	}
+
+	if !rules.Allowed(a.robots.UserAgent(), pageURL.RequestURI()) {
+		if policy == robots.PolicyEnforce {
+			a.log(ctx).Info().Msgf("robots.txt disallows URL, skipping fetch: %s", pageURL)
+			return nil, fmt.Errorf("%w: %s", ErrDisallowedByRobots, pageURL)
+		}
+		a.log(ctx).Warn().Msgf("robots.txt disallows URL, fetching anyway: %s", pageURL)
 	}
 	return info, nil
 }
 
 func (a *Analyzer) getHeadingsCount(ctx context.Context, doc *html.Node, ch chan<- map[string]int, wg *sync.WaitGroup) {
-	a.logger.Debug().Msg("Analyzing headings count")
+	a.log(ctx).Debug().Msg("Analyzing headings count")
 	defer wg.Done()
+	ctx, end := startAnalyzer(ctx, "headings")
+	defer end()
 
 	if isCancelled(ctx) {
 		return
@@ -125,6 +422,8 @@ func (a *Analyzer) getHeadingsCount(ctx context.Context, doc *html.Node, ch chan
 
 func (a *Analyzer) getPageTitle(ctx context.Context, doc *html.Node, ch chan<- string, wg *sync.WaitGroup) {
 	defer wg.Done()
+	ctx, end := startAnalyzer(ctx, "title")
+	defer end()
 
 	if isCancelled(ctx) {
 		return
@@ -161,8 +460,10 @@ func (a *Analyzer) getPageTitle(ctx context.Context, doc *html.Node, ch chan<- s
 }
 
 func (a *Analyzer) getHTMLVersion(ctx context.Context, doc *html.Node, ch chan<- string, wg *sync.WaitGroup) {
-	a.logger.Debug().Msg("Analyzing HTML version")
+	a.log(ctx).Debug().Msg("Analyzing HTML version")
 	defer wg.Done()
+	ctx, end := startAnalyzer(ctx, "html_version")
+	defer end()
 
 	if isCancelled(ctx) {
 		return
@@ -195,14 +496,22 @@ func (a *Analyzer) getHTMLVersion(ctx context.Context, doc *html.Node, ch chan<-
 }
 
 func (a *Analyzer) getLinkStats(ctx context.Context, doc *html.Node, ch chan<- LinkStats, wg *sync.WaitGroup, baseURL *url.URL) {
-	a.logger.Debug().Msg("Analyzing link statistics")
+	a.log(ctx).Debug().Msg("Analyzing link statistics")
 	defer wg.Done()
+	ctx, end := startAnalyzer(ctx, "links")
+	defer end()
 
 	if isCancelled(ctx) {
 		return
 	}
 
 	var stats LinkStats
+	seen := make(map[string]bool)
+	seenTracked := make(map[string]bool)
+	baseHost := baseURL.Host
+	if canonicalBase, _ := a.norm.NormalizeURL(baseURL); canonicalBase.Host != "" {
+		baseHost = canonicalBase.Host
+	}
 
 	var traverse func(*html.Node)
 	traverse = func(n *html.Node) {
@@ -227,13 +536,33 @@ func (a *Analyzer) getLinkStats(ctx context.Context, doc *html.Node, ch chan<- L
 				resolved := baseURL.ResolveReference(linkURL)
 
 				scheme := strings.ToLower(resolved.Scheme)
+				if scheme == "" && baseURL.Scheme == "" {
+					// documents analyzed without a base URL keep relative links relative
+					scheme = "http"
+				}
 				switch scheme {
 				case "http", "https":
-					if strings.EqualFold(resolved.Host, baseURL.Host) {
+					resolved.Fragment = ""
+					canonical, tracked := a.norm.NormalizeURL(resolved)
+					if tracked && !seenTracked[resolved.String()] {
+						seenTracked[resolved.String()] = true
+						stats.TrackingVariants++
+					}
+					link := canonical.String()
+					internal := strings.EqualFold(canonical.Host, baseHost)
+					if internal {
 						stats.Internal++
 					} else {
 						stats.External++
 					}
+					if !seen[link] {
+						seen[link] = true
+						if internal {
+							stats.InternalLinks = append(stats.InternalLinks, link)
+						} else {
+							stats.ExternalLinks = append(stats.ExternalLinks, link)
+						}
+					}
 				default:
 					stats.Inaccessible++
 				}
@@ -254,31 +583,30 @@ func (a *Analyzer) getLinkStats(ctx context.Context, doc *html.Node, ch chan<- L
 	ch <- stats
 }
 
-// logic - Looks for a <form> element with either a password input or a link containing "login" text
-// html reference - https://www.w3schools.com/howto/howto_css_social_login.asp
-func (a *Analyzer) detectLoginForm(ctx context.Context, doc *html.Node, ch chan<- bool, wg *sync.WaitGroup) {
+// getCanonicalURL reads the href of <link rel="canonical"> resolved against the page URL
+func (a *Analyzer) getCanonicalURL(ctx context.Context, doc *html.Node, ch chan<- string, wg *sync.WaitGroup, baseURL *url.URL) {
+	a.log(ctx).Debug().Msg("Analyzing canonical URL")
 	defer wg.Done()
+	ctx, end := startAnalyzer(ctx, "canonical")
+	defer end()
 
 	if isCancelled(ctx) {
 		return
 	}
 
-	var found bool
-
+	var canonical string
 	var traverse func(*html.Node)
 	traverse = func(n *html.Node) {
-		if found || n == nil {
+		if canonical != "" {
 			return
 		}
-
-		if n.Type == html.ElementNode && n.Data == "form" {
-			if containsPasswordInput(n) || containsLoginAnchor(n) {
-				found = true
+		if n.Type == html.ElementNode && n.Data == "link" && hasRel(n, "canonical") {
+			if href, err := url.Parse(strings.TrimSpace(getAttr(n, "href"))); err == nil && href.String() != "" {
+				canonical = baseURL.ResolveReference(href).String()
 				return
 			}
 		}
-
-		for c := n.FirstChild; c != nil && !found; c = c.NextSibling {
+		for c := n.FirstChild; c != nil && canonical == ""; c = c.NextSibling {
 			traverse(c)
 		}
 	}
@@ -287,51 +615,26 @@ func (a *Analyzer) detectLoginForm(ctx context.Context, doc *html.Node, ch chan<
 	if isCancelled(ctx) {
 		return
 	}
-	ch <- found
+	ch <- canonical
 }
 
-func containsPasswordInput(n *html.Node) bool {
-	var found bool
-	var walk func(*html.Node)
-	walk = func(node *html.Node) {
-		if found || node == nil {
-			return
-		}
-		if node.Type == html.ElementNode && node.Data == "input" {
-			for _, attr := range node.Attr {
-				if attr.Key == "type" && strings.EqualFold(attr.Val, "password") {
-					found = true
-					return
-				}
-			}
-		}
-		for c := node.FirstChild; c != nil && !found; c = c.NextSibling {
-			walk(c)
+func getAttr(n *html.Node, key string) string {
+	for _, attr := range n.Attr {
+		if strings.EqualFold(attr.Key, key) {
+			return attr.Val
 		}
 	}
-	walk(n)
-	return found
+	return ""
 }
 
-func containsLoginAnchor(n *html.Node) bool {
-	var found bool
-	var walk func(*html.Node)
-	walk = func(node *html.Node) {
-		if found || node == nil {
-			return
-		}
-		if node.Type == html.ElementNode && node.Data == "a" {
-			if strings.Contains(strings.ToLower(getText(node)), "login") || strings.Contains(strings.ToLower(getText(node)), "sign in") || strings.Contains(strings.ToLower(getText(node)), "continue with") {
-				found = true
-				return
-			}
-		}
-		for c := node.FirstChild; c != nil && !found; c = c.NextSibling {
-			walk(c)
+// hasRel reports whether the space separated rel attribute of n contains value
+func hasRel(n *html.Node, value string) bool {
+	for _, rel := range strings.Fields(getAttr(n, "rel")) {
+		if strings.EqualFold(rel, value) {
+			return true
 		}
 	}
-	walk(n)
-	return found
+	return false
 }
 
 func getText(n *html.Node) string {
@@ -345,6 +648,33 @@ func getText(n *html.Node) string {
 	return sb.String()
 }
 
+// countingReader counts the bytes read through it
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+func observeDuration(h prometheus.Observer, start time.Time) {
+	h.Observe(time.Since(start).Seconds())
+}
+
+// startAnalyzer opens the span of one analyzer goroutine. The returned func ends the
+// span and records the analyzer duration.
+func startAnalyzer(ctx context.Context, name string) (context.Context, func()) {
+	start := time.Now()
+	ctx, span := tracing.Tracer().Start(ctx, "analyzer."+name)
+	return ctx, func() {
+		span.End()
+		observeDuration(metrics.AnalyzerDuration.WithLabelValues(name), start)
+	}
+}
+
 func isCancelled(ctx context.Context) bool {
 	select {
 	case <-ctx.Done():
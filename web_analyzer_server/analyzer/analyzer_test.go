@@ -6,13 +6,23 @@ import (
 	"errors"
 	"io"
 	"net/http"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	mocks "github.com/sashithaf16/peekalo/_mocks"
 	"github.com/sashithaf16/peekalo/config"
 	"github.com/sashithaf16/peekalo/logger"
+	"github.com/sashithaf16/peekalo/metrics"
+	"github.com/sashithaf16/peekalo/robots"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
 )
 
 func TestAnalyzeURL_Success(t *testing.T) {
@@ -63,6 +73,7 @@ func TestAnalyzeURL_Success(t *testing.T) {
 	assert.Equal(t, 1, result.Links.Internal)
 	assert.Equal(t, 1, result.Links.External)
 	assert.Equal(t, 1, result.Links.Inaccessible)
+	assert.Equal(t, []string{"https://external.com/"}, result.Links.ExternalLinks)
 	assert.True(t, result.HasLogin)
 
 	mockClient.AssertExpectations(t)
@@ -78,12 +89,14 @@ func TestAnalyzeURL_ErrorFetchingURL(t *testing.T) {
 	mockClient.On("Do", mock.Anything).Return((*http.Response)(nil), errors.New("mocked network error"))
 
 	an := NewAnalyzer(logger, mockCfg, mockClient)
+	fetchErrors := testutil.ToFloat64(metrics.AnalysisErrorCount.WithLabelValues("fetch"))
 
 	ctx := context.Background()
 	_, err := an.AnalyzeURL(ctx, "http://example.com")
 
 	expectedErr := "failed to fetch URL: mocked network error"
 	assert.EqualError(t, err, expectedErr, "unexpected error message")
+	assert.Equal(t, fetchErrors+1, testutil.ToFloat64(metrics.AnalysisErrorCount.WithLabelValues("fetch")))
 }
 
 func TestAnalyzeURL_SocialLoginDetected(t *testing.T) {
@@ -127,3 +140,545 @@ func TestAnalyzeURL_SocialLoginDetected(t *testing.T) {
 
 	mockClient.AssertExpectations(t)
 }
+
+func TestAnalyzeURL_RobotsPolicy(t *testing.T) {
+	robotsTxt := "User-agent: *\nDisallow: /private\nSitemap: http://example.com/sitemap.xml\n"
+	isRobots := func(req *http.Request) bool { return req.URL.Path == "/robots.txt" }
+
+	t.Run("enforce skips disallowed page", func(t *testing.T) {
+		cfg := &config.Config{LogLevel: "debug", UserAgent: "Peekalo/1.0", RobotsPolicy: "enforce"}
+		log := logger.CreateLogger(cfg.LogLevel)
+
+		mockClient := new(mocks.MockHTTPClient)
+		mockClient.On("Do", mock.MatchedBy(isRobots)).Return(&http.Response{
+			StatusCode: 200,
+			Body:       io.NopCloser(strings.NewReader(robotsTxt)),
+		}, nil).Once()
+
+		checker := robots.NewChecker(log, mockClient, cfg.UserAgent, time.Minute)
+		an := NewAnalyzer(log, cfg, mockClient, WithRobotsChecker(checker))
+
+		_, err := an.AnalyzeURL(context.Background(), "http://example.com/private/page")
+		assert.ErrorIs(t, err, ErrDisallowedByRobots)
+		mockClient.AssertExpectations(t)
+	})
+
+	t.Run("warn fetches and reports", func(t *testing.T) {
+		cfg := &config.Config{LogLevel: "debug", UserAgent: "Peekalo/1.0", RobotsPolicy: "warn"}
+		log := logger.CreateLogger(cfg.LogLevel)
+
+		mockClient := new(mocks.MockHTTPClient)
+		mockClient.On("Do", mock.MatchedBy(isRobots)).Return(&http.Response{
+			StatusCode: 200,
+			Body:       io.NopCloser(strings.NewReader(robotsTxt)),
+		}, nil).Once()
+		mockClient.On("Do", mock.MatchedBy(func(req *http.Request) bool { return !isRobots(req) })).Return(&http.Response{
+			StatusCode: 200,
+			Body:       io.NopCloser(strings.NewReader("<html><head><title>Private</title></head></html>")),
+		}, nil).Once()
+
+		checker := robots.NewChecker(log, mockClient, cfg.UserAgent, time.Minute)
+		an := NewAnalyzer(log, cfg, mockClient, WithRobotsChecker(checker))
+
+		result, err := an.AnalyzeURL(context.Background(), "http://example.com/private/page")
+		assert.NoError(t, err)
+		assert.Equal(t, "Private", result.Title)
+		if assert.NotNil(t, result.Robots) {
+			assert.True(t, result.Robots.Disallowed["*"])
+			assert.True(t, result.Robots.Disallowed["Googlebot"])
+			assert.Equal(t, []string{"http://example.com/sitemap.xml"}, result.Robots.Sitemaps)
+		}
+		mockClient.AssertExpectations(t)
+	})
+}
+
+func TestAnalyzeURL_CanonicalAndRedirect(t *testing.T) {
+	mockHTML := `
+		<html>
+		<head>
+			<link rel="canonical" href="/products">
+		</head>
+		<body>
+			<a href="/products#reviews">Products</a>
+			<a href="/products">Products again</a>
+			<a href="https://new.example.com/about">About</a>
+		</body>
+		</html>
+	`
+	finalReq, _ := http.NewRequest("GET", "https://new.example.com/products?ref=old", nil)
+
+	mockClient := new(mocks.MockHTTPClient)
+	mockClient.On("Do", mock.Anything).Return(&http.Response{
+		StatusCode: 200,
+		Body:       io.NopCloser(bytes.NewBufferString(mockHTML)),
+		Request:    finalReq,
+	}, nil)
+
+	cfg := &config.Config{LogLevel: "debug"}
+	an := NewAnalyzer(logger.CreateLogger(cfg.LogLevel), cfg, mockClient)
+
+	result, err := an.AnalyzeURL(context.Background(), "http://old.example.com/products")
+
+	assert.NoError(t, err)
+	assert.Equal(t, 200, result.StatusCode)
+	assert.Equal(t, "https://new.example.com/products?ref=old", result.FinalURL)
+	assert.Equal(t, "https://new.example.com/products", result.Canonical)
+	assert.Equal(t, 3, result.Links.Internal)
+	assert.Equal(t, []string{"https://new.example.com/products", "https://new.example.com/about"}, result.Links.InternalLinks)
+}
+
+// fakeResults returns a fixed previous analysis
+type fakeResults struct {
+	info PageInfo
+	at   time.Time
+}
+
+func (f fakeResults) LatestFetched(ctx context.Context, pageURL string) (PageInfo, time.Time, bool) {
+	return f.info, f.at, !f.at.IsZero()
+}
+
+func TestAnalyzeURL_ConditionalFetch(t *testing.T) {
+	cfg := &config.Config{LogLevel: "debug"}
+	prev := PageInfo{StatusCode: 200, Title: "Before", ETag: `"v1"`, LastModified: "Sat, 01 Mar 2025 10:00:00 GMT"}
+	conditional := func(req *http.Request) bool {
+		return req.Header.Get("If-None-Match") == `"v1"` && req.Header.Get("If-Modified-Since") == prev.LastModified
+	}
+
+	t.Run("not modified", func(t *testing.T) {
+		mockClient := new(mocks.MockHTTPClient)
+		mockClient.On("Do", mock.MatchedBy(conditional)).Return(&http.Response{
+			StatusCode: http.StatusNotModified,
+			Body:       io.NopCloser(strings.NewReader("")),
+		}, nil).Once()
+		an := NewAnalyzer(logger.CreateLogger(cfg.LogLevel), cfg, mockClient, WithPreviousResults(fakeResults{prev, time.Now().Add(-time.Hour)}, time.Minute))
+
+		result, err := an.AnalyzeURL(context.Background(), "https://example.com")
+		assert.NoError(t, err)
+		assert.True(t, result.NotModified)
+		assert.False(t, result.Cached)
+		assert.Equal(t, "Before", result.Title)
+		assert.Equal(t, 200, result.StatusCode)
+		mockClient.AssertExpectations(t)
+	})
+
+	t.Run("modified", func(t *testing.T) {
+		mockClient := new(mocks.MockHTTPClient)
+		mockClient.On("Do", mock.MatchedBy(conditional)).Return(&http.Response{
+			StatusCode: 200,
+			Header:     http.Header{"Etag": {`"v2"`}},
+			Body:       io.NopCloser(strings.NewReader("<title>After</title>")),
+		}, nil).Once()
+		an := NewAnalyzer(logger.CreateLogger(cfg.LogLevel), cfg, mockClient, WithPreviousResults(fakeResults{prev, time.Now().Add(-time.Hour)}, 0))
+
+		result, err := an.AnalyzeURL(context.Background(), "https://example.com")
+		assert.NoError(t, err)
+		assert.False(t, result.NotModified)
+		assert.Equal(t, "After", result.Title)
+		assert.Equal(t, `"v2"`, result.ETag)
+		assert.Empty(t, result.LastModified)
+	})
+
+	t.Run("within cache TTL", func(t *testing.T) {
+		mockClient := new(mocks.MockHTTPClient)
+		an := NewAnalyzer(logger.CreateLogger(cfg.LogLevel), cfg, mockClient, WithPreviousResults(fakeResults{prev, time.Now().Add(-10 * time.Second)}, time.Minute))
+
+		result, err := an.AnalyzeURL(context.Background(), "https://example.com")
+		assert.NoError(t, err)
+		assert.True(t, result.Cached)
+		assert.Equal(t, "Before", result.Title)
+		mockClient.AssertNotCalled(t, "Do", mock.Anything)
+	})
+}
+
+func TestAnalyzeDocument(t *testing.T) {
+	mockHTML := `
+		<!DOCTYPE html>
+		<html>
+		<head><title>Draft Template</title></head>
+		<body>
+			<h1>Welcome</h1>
+			<a href="/pricing">Pricing</a>
+			<a href="https://docs.example.com/start">Docs</a>
+			<a href="https://www.example.com/blog">Blog</a>
+		</body>
+		</html>
+	`
+	// the document is analyzed without touching the network
+	mockClient := new(mocks.MockHTTPClient)
+	cfg := &config.Config{LogLevel: "debug"}
+	an := NewAnalyzer(logger.CreateLogger(cfg.LogLevel), cfg, mockClient)
+
+	result, err := an.AnalyzeDocument(context.Background(), strings.NewReader(mockHTML), "https://www.example.com/landing")
+	assert.NoError(t, err)
+	assert.Equal(t, "Draft Template", result.Title)
+	assert.Equal(t, "HTML 5", result.HTMLVersion)
+	assert.Equal(t, 0, result.StatusCode)
+	assert.Equal(t, "https://www.example.com/landing", result.FinalURL)
+	assert.Equal(t, []string{"https://www.example.com/pricing", "https://www.example.com/blog"}, result.Links.InternalLinks)
+	assert.Equal(t, 1, result.Links.External)
+
+	// without a base URL relative links are internal
+	result, err = an.AnalyzeDocument(context.Background(), strings.NewReader(mockHTML), "")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"/pricing"}, result.Links.InternalLinks)
+	assert.Equal(t, 2, result.Links.External)
+	assert.Equal(t, 0, result.Links.Inaccessible)
+
+	_, err = an.AnalyzeDocument(context.Background(), strings.NewReader(mockHTML), "/relative")
+	assert.Error(t, err)
+	mockClient.AssertNotCalled(t, "Do", mock.Anything)
+}
+
+func TestAnalyzeDocument_NormalizesLinks(t *testing.T) {
+	mockHTML := `
+		<html>
+		<body>
+			<a href="/pricing?utm_source=news&plan=pro">Pricing</a>
+			<a href="/pricing?plan=pro&gclid=abc">Pricing</a>
+			<a href="HTTPS://WWW.Example.com:443/pricing?plan=pro#faq">Pricing</a>
+			<a href="https://external.com/a/../b?ref=home">Partner</a>
+		</body>
+		</html>
+	`
+	cfg := &config.Config{LogLevel: "debug", TrackingParams: []string{"utm_*", "gclid", "ref"}}
+	an := NewAnalyzer(logger.CreateLogger(cfg.LogLevel), cfg, new(mocks.MockHTTPClient))
+
+	result, err := an.AnalyzeDocument(context.Background(), strings.NewReader(mockHTML), "https://www.example.com/")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"https://www.example.com/pricing?plan=pro"}, result.Links.InternalLinks)
+	assert.Equal(t, []string{"https://external.com/b"}, result.Links.ExternalLinks)
+	assert.Equal(t, 3, result.Links.TrackingVariants)
+}
+
+func TestAnalyzeURL_Spans(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+
+	mockClient := new(mocks.MockHTTPClient)
+	mockClient.On("Do", mock.Anything).Return(&http.Response{
+		StatusCode: 200,
+		Body:       io.NopCloser(bytes.NewBufferString("<html><head><title>Traced</title></head></html>")),
+	}, nil)
+
+	cfg := &config.Config{LogLevel: "debug"}
+	an := NewAnalyzer(logger.CreateLogger(cfg.LogLevel), cfg, mockClient)
+
+	_, err := an.AnalyzeURL(context.Background(), "http://example.com")
+	assert.NoError(t, err)
+
+	names := make(map[string]bool)
+	var root trace.SpanContext
+	for _, span := range recorder.Ended() {
+		names[span.Name()] = true
+		if span.Name() == "AnalyzeURL" {
+			root = span.SpanContext()
+		}
+	}
+	for _, name := range []string{"AnalyzeURL", "GET", "ParseHTML", "analyzer.headings", "analyzer.title", "analyzer.html_version", "analyzer.links", "analyzer.login", "analyzer.canonical"} {
+		assert.True(t, names[name], "missing span %s", name)
+	}
+	for _, span := range recorder.Ended() {
+		assert.Equal(t, root.TraceID(), span.SpanContext().TraceID(), "span %s belongs to the analysis trace", span.Name())
+	}
+}
+
+func TestAnalyzeDocument_Auth(t *testing.T) {
+	tests := []struct {
+		name       string
+		html       string
+		hasLogin   bool
+		mechanisms []string
+		providers  []string
+		purpose    string
+		formAction string
+	}{
+		{
+			name:       "password form",
+			html:       `<form action="/session"><input name="user" autocomplete="username"><input type="password" name="pass" autocomplete="current-password"><button>Log in</button></form>`,
+			hasLogin:   true,
+			mechanisms: []string{AuthPassword},
+			purpose:    "login",
+			formAction: "https://www.example.com/session",
+		},
+		{
+			name:       "signup form",
+			html:       `<form action="/users"><input type="email" name="email"><input type="password"><input type="password"><input type="submit" value="Create account"></form>`,
+			hasLogin:   true,
+			mechanisms: []string{AuthPassword},
+			purpose:    "signup",
+			formAction: "https://www.example.com/users",
+		},
+		{
+			name:       "password field outside a form",
+			html:       `<div id="app"><input type="password" id="pw"><button>Sign in</button></div>`,
+			hasLogin:   true,
+			mechanisms: []string{AuthPassword},
+			purpose:    "login",
+		},
+		{
+			name:       "identity provider buttons",
+			html:       `<a href="https://accounts.google.com/o/oauth2/v2/auth?client_id=1">Continue with Google</a><a href="https://github.com/login/oauth/authorize">Sign in with GitHub</a>`,
+			hasLogin:   true,
+			mechanisms: []string{AuthOAuth},
+			providers:  []string{"github", "google"},
+			purpose:    "login",
+		},
+		{
+			name:       "passkey",
+			html:       `<input type="text" autocomplete="username webauthn"><button>Sign in with a passkey</button><script>navigator.credentials.get({publicKey: opts})</script>`,
+			hasLogin:   true,
+			mechanisms: []string{AuthPasskey},
+			purpose:    "login",
+		},
+		{
+			name:       "single sign-on without a provider",
+			html:       `<a href="/sso/start">Log in with SSO</a>`,
+			hasLogin:   true,
+			mechanisms: []string{AuthOAuth},
+			providers:  []string{"sso"},
+			purpose:    "login",
+		},
+		{
+			name:       "identity provider buttons only",
+			html:       `<button type="button">Sign in with Google</button><button type="button">Sign in with Microsoft</button>`,
+			hasLogin:   true,
+			mechanisms: []string{AuthOAuth},
+			providers:  []string{"google", "microsoft"},
+			purpose:    "login",
+		},
+		{
+			name:       "email and identity provider",
+			html:       `<form action="/identify"><input type="email" name="email"><button>Next</button><button type="button">Continue with Google</button></form>`,
+			hasLogin:   true,
+			mechanisms: []string{AuthOAuth},
+			providers:  []string{"google"},
+			purpose:    "login",
+		},
+		{
+			name:       "localized login form without a password field",
+			html:       `<html lang="de"><form action="/anmelden"><input type="email" name="email"><button>Anmelden</button></form></html>`,
+			hasLogin:   true,
+			formAction: "https://www.example.com/anmelden",
+		},
+		{
+			name:     "login link inside a form",
+			html:     `<form><a href="/login">Login</a></form>`,
+			hasLogin: true,
+		},
+		{
+			name: "continue without an identity provider",
+			html: `<button>Continue with email</button><a href="/cart/checkout">Continue with checkout</a>`,
+		},
+		{
+			name:       "magic link",
+			html:       `<form action="/magic"><input type="email" name="email"><button>Email me a link</button></form>`,
+			hasLogin:   true,
+			mechanisms: []string{AuthMagicLink},
+			purpose:    "login",
+			formAction: "https://www.example.com/magic",
+		},
+		{
+			name:       "page language keywords",
+			html:       `<html lang="de-DE"><form action="konto"><input type="password" autocomplete="new-password"><button>Registrieren</button></form></html>`,
+			hasLogin:   true,
+			mechanisms: []string{AuthPassword},
+			purpose:    "signup",
+			formAction: "https://www.example.com/account/konto",
+		},
+		{
+			name: "navigation link only",
+			html: `<nav><a href="/login">Sign in</a></nav><form action="/search"><input name="q"><button>Search</button></form>`,
+		},
+	}
+	cfg := &config.Config{LogLevel: "debug"}
+	an := NewAnalyzer(logger.CreateLogger(cfg.LogLevel), cfg, new(mocks.MockHTTPClient))
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := an.AnalyzeDocument(context.Background(), strings.NewReader(tt.html), "https://www.example.com/account/")
+			require.NoError(t, err)
+			assert.Equal(t, tt.hasLogin, result.HasLogin, "score %d", result.Auth.Score)
+			assert.Equal(t, tt.mechanisms, result.Auth.Mechanisms)
+			assert.Equal(t, tt.providers, result.Auth.Providers)
+			assert.Equal(t, tt.purpose, result.Auth.Purpose)
+			if tt.formAction != "" {
+				assert.Equal(t, tt.formAction, result.Auth.FormAction)
+			}
+			if tt.hasLogin {
+				assert.NotEmpty(t, result.Auth.Evidence)
+			}
+		})
+	}
+}
+
+func TestAnalyzeDocument_AuthKeywords(t *testing.T) {
+	doc := `<html lang="sv"><body><a href="/oauth/start">Logga in med Google</a></body></html>`
+	cfg := &config.Config{LogLevel: "debug"}
+	an := NewAnalyzer(logger.CreateLogger(cfg.LogLevel), cfg, new(mocks.MockHTTPClient))
+	result, err := an.AnalyzeDocument(context.Background(), strings.NewReader(doc), "https://www.example.se/")
+	require.NoError(t, err)
+	assert.Empty(t, result.Auth.Providers, "no default keywords match Swedish")
+
+	cfg.AuthKeywords = map[string]config.AuthKeywords{"sv": {Login: []string{"logga in"}, SSO: []string{"logga in med"}}}
+	result, err = an.AnalyzeDocument(context.Background(), strings.NewReader(doc), "https://www.example.se/")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"google"}, result.Auth.Providers)
+	assert.Equal(t, []AuthEvidence{
+		{Signal: "sso_button", Detail: "logga in med google", Weight: 50},
+		{Signal: "login_button", Detail: "logga in med google", Weight: 10},
+	}, result.Auth.Evidence)
+}
+
+func TestAnalyzeDocument_Forms(t *testing.T) {
+	doc := `
+		<form action="/search"><input name="q"><select name="sort"></select></form>
+		<form method="post" action="https://accounts.example.co.uk/session">
+			<input type="hidden" name="authenticity_token" value="secret">
+			<input type="email" name="email">
+			<input type="password" name="password" autocomplete="off">
+		</form>
+		<form method="POST" action="http://shop.example.co.uk/cart"><input type="number" name="qty"></form>
+		<form method="post" action="https://collector.example.net/submit"><input type="file" name="cv"><textarea name="note"></textarea></form>
+	`
+	cfg := &config.Config{LogLevel: "debug"}
+	an := NewAnalyzer(logger.CreateLogger(cfg.LogLevel), cfg, new(mocks.MockHTTPClient))
+
+	result, err := an.AnalyzeDocument(context.Background(), strings.NewReader(doc), "https://www.example.co.uk/apply")
+	require.NoError(t, err)
+	require.Len(t, result.Forms, 4)
+
+	search := result.Forms[0]
+	assert.Equal(t, "GET", search.Method)
+	assert.Equal(t, "https://www.example.co.uk/search", search.Action)
+	assert.Equal(t, map[string]int{"text": 1, "select": 1}, search.InputTypes)
+	assert.Empty(t, search.Issues)
+
+	login := result.Forms[1]
+	assert.Equal(t, "POST", login.Method)
+	assert.Equal(t, []string{"authenticity_token"}, login.HiddenFields)
+	assert.True(t, login.CSRFToken)
+	assert.Equal(t, 1, login.PasswordFields)
+	assert.Equal(t, []string{"off"}, login.PasswordAutocomplete)
+	assert.False(t, login.CrossSite, "subdomains share the registrable domain")
+	assert.Equal(t, []string{FormPasswordAutocompleteOff}, login.Issues)
+
+	cart := result.Forms[2]
+	assert.True(t, cart.InsecureSubmit)
+	assert.Equal(t, []string{FormInsecureSubmit, FormMissingCSRFToken}, cart.Issues)
+
+	upload := result.Forms[3]
+	assert.True(t, upload.FileUpload)
+	assert.True(t, upload.CrossSite)
+	assert.Equal(t, []string{FormCrossSiteSubmit, FormUploadWithoutMultipart}, upload.Issues)
+
+	// password forms on plain HTTP pages are flagged, as are passwords sent in the query string
+	result, err = an.AnalyzeDocument(context.Background(), strings.NewReader(`<form><input type="password" name="pin"></form>`), "http://www.example.com/")
+	require.NoError(t, err)
+	require.Len(t, result.Forms, 1)
+	assert.Equal(t, []string{FormPasswordOverHTTP, FormPasswordInGet}, result.Forms[0].Issues)
+	assert.Equal(t, []string{""}, result.Forms[0].PasswordAutocomplete)
+}
+
+func TestAnalyzeDocument_Phishing(t *testing.T) {
+	signals := func(info PhishingInfo) []string {
+		var names []string
+		for _, s := range info.Signals {
+			names = append(names, s.Signal)
+		}
+		return names
+	}
+	kit := `<html><head><title>PayPal: Log in to your account</title>
+		<link rel="stylesheet" href="https://www.paypalobjects.com/web/res/app.css">
+		<script src="https://cdn.example.net/a.js"></script><script src="https://cdn.example.net/b.js"></script>
+		<link rel="icon" href="https://www.paypalobjects.com/favicon.ico"></head>
+		<body><img src="https://cdn.example.net/logo.png">
+		<iframe src="https://tracker.example.org/t" width="0" height="0"></iframe>
+		<form method="post" action="https://collector.example.ru/gate.php">
+			<input type="email" name="login_email"><input type="password" name="login_password">
+			<button type="submit">Log In</button>
+		</form></body></html>`
+	cfg := &config.Config{LogLevel: "debug"}
+	an := NewAnalyzer(logger.CreateLogger(cfg.LogLevel), cfg, new(mocks.MockHTTPClient))
+
+	tests := []struct {
+		name    string
+		doc     string
+		url     string
+		risk    string
+		signals []string
+	}{
+		{
+			name:    "phishing kit on a lookalike host",
+			doc:     kit,
+			url:     "https://paypa1-secure.example.com/signin",
+			risk:    PhishingRiskHigh,
+			signals: []string{"password_form_external", "lookalike_host", "brand_title_mismatch", "brand_resources", "external_resources", "hidden_iframe"},
+		},
+		{
+			name:    "the brand's own page",
+			doc:     `<title>PayPal: Log in</title><form method="post" action="/signin"><input type="password" name="pw"></form>`,
+			url:     "https://www.paypal.com/signin",
+			risk:    PhishingRiskLow,
+			signals: nil,
+		},
+		{
+			name:    "punycode host imitating a brand",
+			doc:     `<title>Sign in</title>`,
+			url:     "https://xn--pypal-4ve.com/",
+			risk:    PhishingRiskMedium,
+			signals: []string{"punycode_host", "lookalike_host"},
+		},
+		{
+			name:    "brand in a subdomain",
+			doc:     `<title>Account verification</title><form action="data:text/html;base64,PGgxPkhpPC9oMT4="><input name="card"></form>`,
+			url:     "https://netflix.com.billing-update.example/",
+			risk:    PhishingRiskHigh,
+			signals: []string{"data_url_form", "brand_in_hostname"},
+		},
+		{
+			name:    "dictionary word one typo from a brand",
+			doc:     `<title>Cloud</title>`,
+			url:     "https://cloud.google.com/",
+			risk:    PhishingRiskLow,
+			signals: nil,
+		},
+		{
+			name:    "common host label",
+			doc:     `<title>Markets</title>`,
+			url:     "https://finance.yahoo.com/",
+			risk:    PhishingRiskLow,
+			signals: nil,
+		},
+		{
+			name:    "brand under another public suffix",
+			doc:     `<title>Google</title>`,
+			url:     "https://www.google.co.in/",
+			risk:    PhishingRiskLow,
+			signals: nil,
+		},
+		{
+			name:    "ordinary page",
+			doc:     `<title>Apple pie recipes</title><iframe src="https://www.youtube.com/embed/x" width="560" height="315" style="border: 0"></iframe>`,
+			url:     "https://recipes.example.com/",
+			risk:    PhishingRiskLow,
+			signals: []string{"brand_title_mismatch"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := an.AnalyzeDocument(context.Background(), strings.NewReader(tt.doc), tt.url)
+			require.NoError(t, err)
+			assert.Equal(t, tt.risk, result.Phishing.Risk, "score %d", result.Phishing.Score)
+			assert.ElementsMatch(t, tt.signals, signals(result.Phishing))
+		})
+	}
+
+	result, err := an.AnalyzeDocument(context.Background(), strings.NewReader(kit), "https://paypa1-secure.example.com/signin")
+	require.NoError(t, err)
+	assert.Equal(t, 100, result.Phishing.Score)
+	assert.Contains(t, result.Phishing.Signals, PhishingSignal{
+		Signal: "password_form_external",
+		Detail: `form action="https://collector.example.ru/gate.php"`,
+		Weight: 40,
+	})
+	assert.Contains(t, result.Phishing.Signals, PhishingSignal{Signal: "hidden_iframe", Detail: `iframe src="https://tracker.example.org/t" width=0`, Weight: 15})
+}
@@ -0,0 +1,568 @@
+package analyzer
+
+import (
+	"context"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/sashithaf16/peekalo/config"
+	"golang.org/x/net/html"
+)
+
+// Authentication mechanisms reported in AuthInfo.Mechanisms
+const (
+	AuthPassword  = "password"
+	AuthOAuth     = "oauth"
+	AuthPasskey   = "passkey"
+	AuthMagicLink = "magic_link"
+)
+
+// loginScoreThreshold is the score from which a page is reported to have a login (PageInfo.HasLogin).
+// Pages offering an authentication mechanism are reported to have one whatever their score.
+const loginScoreThreshold = 50
+
+// maxAuthEvidence bounds the evidence reported for pages with many matching elements
+const maxAuthEvidence = 20
+
+// Signals found by the detector and their weights. The score of a page is the sum of the weights of
+// the distinct signals found, capped at 100.
+var authSignalWeights = map[string]int{
+	"password_input":              60,
+	"password_input_outside_form": 50,
+	"provider_link":               50,
+	"passkey_autocomplete":        50,
+	"magic_link_form":             50,
+	"provider_script":             40,
+	"webauthn_script":             35,
+	"sso_button":                  50,
+	"login_keyword":               50,
+	"signup_keyword":              25,
+	"passkey_keyword":             25,
+	"login_button":                10,
+}
+
+// AuthInfo describes the authentication surface of a page
+type AuthInfo struct {
+	Score      int            `json:"score"`                 // 0 to 100, HasLogin is set from 50
+	Mechanisms []string       `json:"mechanisms,omitempty"`  // password, oauth, passkey or magic_link
+	Providers  []string       `json:"providers,omitempty"`   // identity providers offered, e.g. "google"
+	Purpose    string         `json:"purpose,omitempty"`     // "login", "signup" or "login_and_signup"
+	FormAction string         `json:"form_action,omitempty"` // resolved action of the main authentication form
+	Evidence   []AuthEvidence `json:"evidence,omitempty"`    // what triggered each decision
+}
+
+// hasLogin reports whether the page lets users sign in, so that HasLogin never disagrees with Mechanisms
+func (info AuthInfo) hasLogin() bool {
+	return info.Score >= loginScoreThreshold || len(info.Mechanisms) > 0
+}
+
+// AuthEvidence is one element that contributed to the score
+type AuthEvidence struct {
+	Signal string `json:"signal"`
+	Detail string `json:"detail"`
+	Weight int    `json:"weight"`
+}
+
+// identityProvider recognizes sign-in endpoints of an identity provider by host, and by path on
+// hosts that also serve other content
+type identityProvider struct {
+	name         string
+	hosts        []string // exact hosts, or suffixes starting with "."
+	pathPrefixes []string
+}
+
+var identityProviders = []identityProvider{
+	{name: "google", hosts: []string{"accounts.google.com", "oauth2.googleapis.com"}},
+	{name: "microsoft", hosts: []string{"login.microsoftonline.com", "login.live.com", "login.microsoft.com"}},
+	{name: "apple", hosts: []string{"appleid.apple.com"}},
+	{name: "github", hosts: []string{"github.com"}, pathPrefixes: []string{"/login/oauth"}},
+	{name: "gitlab", hosts: []string{"gitlab.com"}, pathPrefixes: []string{"/oauth"}},
+	{name: "facebook", hosts: []string{"facebook.com", "www.facebook.com"}, pathPrefixes: []string{"/dialog/oauth", "/v"}},
+	{name: "linkedin", hosts: []string{"www.linkedin.com", "linkedin.com"}, pathPrefixes: []string{"/oauth"}},
+	{name: "x", hosts: []string{"twitter.com", "api.twitter.com", "x.com"}, pathPrefixes: []string{"/i/oauth2", "/oauth"}},
+	{name: "slack", hosts: []string{"slack.com"}, pathPrefixes: []string{"/oauth", "/openid"}},
+	{name: "salesforce", hosts: []string{"login.salesforce.com"}},
+	{name: "okta", hosts: []string{".okta.com", ".oktapreview.com"}},
+	{name: "auth0", hosts: []string{".auth0.com"}},
+	{name: "onelogin", hosts: []string{".onelogin.com"}},
+	{name: "cognito", hosts: []string{".amazoncognito.com"}},
+}
+
+// providerNames are matched in the text of SSO buttons such as "Continue with Google"
+var providerNames = map[string]string{
+	"google": "google", "microsoft": "microsoft", "apple": "apple", "github": "github", "gitlab": "gitlab",
+	"facebook": "facebook", "linkedin": "linkedin", "twitter": "x", "x": "x", "slack": "slack",
+	"okta": "okta", "amazon": "amazon", "sso": "sso",
+}
+
+// explicitSSOPhrases name single sign-on without an identity provider, as in "Log in with SSO"
+var explicitSSOPhrases = []string{"sso", "single sign-on", "single sign on"}
+
+// webAuthnAPIs in inline scripts hint at passkey sign-in
+var webAuthnAPIs = []string{"navigator.credentials.get", "navigator.credentials.create", "PublicKeyCredential"}
+
+// detectLoginForm scores the authentication surface of the page: password forms, identity provider
+// buttons, passkeys and magic links, inside or outside <form> elements
+func (a *Analyzer) detectLoginForm(ctx context.Context, doc *html.Node, ch chan<- AuthInfo, wg *sync.WaitGroup, baseURL *url.URL) {
+	defer wg.Done()
+	ctx, end := startAnalyzer(ctx, "login")
+	defer end()
+
+	if isCancelled(ctx) {
+		return
+	}
+
+	keywords := a.cfg.AuthKeywords
+	if len(keywords) == 0 {
+		keywords = config.DefaultAuthKeywords
+	}
+	info := detectAuth(doc, baseURL, keywords)
+
+	if isCancelled(ctx) {
+		return
+	}
+	ch <- info
+}
+
+// authDetector collects evidence while walking one document
+type authDetector struct {
+	base       *url.URL
+	keywords   config.AuthKeywords
+	evidence   []AuthEvidence
+	seen       map[AuthEvidence]bool
+	providers  map[string]bool
+	login      bool
+	signup     bool
+	formAction string
+	formScore  int
+}
+
+func detectAuth(doc *html.Node, baseURL *url.URL, keywords map[string]config.AuthKeywords) AuthInfo {
+	d := &authDetector{
+		base:      baseURL,
+		keywords:  keywordsFor(documentLang(doc), keywords),
+		seen:      make(map[AuthEvidence]bool),
+		providers: make(map[string]bool),
+	}
+	d.walk(doc, false)
+	return d.result()
+}
+
+func (d *authDetector) add(signal, detail string) {
+	e := AuthEvidence{Signal: signal, Detail: detail, Weight: authSignalWeights[signal]}
+	if d.seen[e] {
+		return
+	}
+	d.seen[e] = true
+	d.evidence = append(d.evidence, e)
+}
+
+func (d *authDetector) walk(n *html.Node, inForm bool) {
+	if n.Type == html.ElementNode {
+		switch n.Data {
+		case "form":
+			d.link(getAttr(n, "action"))
+			d.form(n)
+			inForm = true
+		case "input":
+			if strings.EqualFold(getAttr(n, "type"), "password") && !inForm {
+				d.add("password_input_outside_form", describe(n))
+				d.login = true
+			}
+			if hasToken(getAttr(n, "autocomplete"), "webauthn") {
+				d.add("passkey_autocomplete", describe(n))
+			}
+		case "a":
+			d.link(getAttr(n, "href"))
+			d.control(n, inForm)
+		case "button":
+			d.link(getAttr(n, "formaction"))
+			d.control(n, inForm)
+		case "script":
+			if src := getAttr(n, "src"); src != "" {
+				if name := d.provider(src); name != "" {
+					d.add("provider_script", name+": "+src)
+					d.providers[name] = true
+				}
+			} else {
+				script := getText(n)
+				for _, api := range webAuthnAPIs {
+					if strings.Contains(script, api) {
+						d.add("webauthn_script", api)
+						break
+					}
+				}
+			}
+			return
+		case "style", "template":
+			return
+		}
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		d.walk(c, inForm)
+	}
+}
+
+// form scores one <form> and keeps the action of the highest scoring one
+func (d *authDetector) form(n *html.Node) {
+	var passwords, emails int
+	var newPassword, currentPassword bool
+	forEachElement(n, "input", func(input *html.Node) {
+		autocomplete := getAttr(input, "autocomplete")
+		switch strings.ToLower(getAttr(input, "type")) {
+		case "password":
+			passwords++
+			newPassword = newPassword || hasToken(autocomplete, "new-password")
+			currentPassword = currentPassword || hasToken(autocomplete, "current-password")
+		case "email":
+			emails++
+		default:
+			if hasToken(autocomplete, "email") || strings.Contains(strings.ToLower(getAttr(input, "name")), "email") {
+				emails++
+			}
+		}
+	})
+
+	text := normalizeText(controlText(n))
+	action := getAttr(n, "action")
+	score := 0
+	if passwords > 0 {
+		d.add("password_input", formLabel(action))
+		score += authSignalWeights["password_input"]
+		// a second password field confirms a new password
+		if passwords > 1 || newPassword {
+			d.signup = true
+		}
+		if currentPassword || (passwords == 1 && !newPassword) {
+			d.login = true
+		}
+	}
+	if phrase := matchPhrase(text, d.keywords.Signup); phrase != "" {
+		d.add("signup_keyword", phrase)
+		score += authSignalWeights["signup_keyword"]
+		d.signup = true
+	}
+	if phrase := matchPhrase(text, d.keywords.Login); phrase != "" {
+		d.add("login_keyword", phrase)
+		score += authSignalWeights["login_keyword"]
+		d.login = true
+	}
+	if passwords == 0 && emails > 0 {
+		if phrase := matchPhrase(text, d.keywords.MagicLink); phrase != "" {
+			d.add("magic_link_form", phrase)
+			score += authSignalWeights["magic_link_form"]
+			d.login = true
+		}
+	}
+	if d.provider(action) != "" {
+		score += authSignalWeights["provider_link"]
+	}
+	if score > d.formScore {
+		d.formScore = score
+		d.formAction = d.resolve(action)
+	}
+}
+
+// control looks at the text of links and buttons for identity provider, passkey and login wording
+func (d *authDetector) control(n *html.Node, inForm bool) {
+	text := normalizeText(controlText(n))
+	if text == "" {
+		return
+	}
+	if phrase := matchPhrase(text, d.keywords.SSO); phrase != "" {
+		// "continue with" alone is also used for email, checkout or passkeys
+		name := providerInText(text[strings.Index(text, phrase)+len(phrase):])
+		if name == "" && matchPhrase(text, explicitSSOPhrases) != "" {
+			name = "sso"
+		}
+		if name != "" {
+			d.add("sso_button", text)
+			d.providers[name] = true
+		}
+	}
+	if phrase := matchPhrase(text, d.keywords.Passkey); phrase != "" {
+		d.add("passkey_keyword", text)
+	}
+	if !inForm {
+		if phrase := matchPhrase(text, d.keywords.Login); phrase != "" {
+			d.add("login_button", text)
+		}
+	}
+}
+
+// link records identity provider endpoints linked from the page
+func (d *authDetector) link(rawURL string) {
+	if name := d.provider(rawURL); name != "" {
+		d.add("provider_link", name+": "+rawURL)
+		d.providers[name] = true
+	}
+}
+
+// provider returns the identity provider serving rawURL, if any
+func (d *authDetector) provider(rawURL string) string {
+	if rawURL == "" {
+		return ""
+	}
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil || u.Host == "" {
+		return ""
+	}
+	host := strings.ToLower(u.Hostname())
+	for _, p := range identityProviders {
+		if !matchesHost(host, p.hosts) {
+			continue
+		}
+		if len(p.pathPrefixes) == 0 {
+			return p.name
+		}
+		for _, prefix := range p.pathPrefixes {
+			if strings.HasPrefix(u.Path, prefix) {
+				return p.name
+			}
+		}
+	}
+	return ""
+}
+
+func (d *authDetector) resolve(action string) string {
+	if d.base == nil {
+		return action
+	}
+	ref, err := url.Parse(strings.TrimSpace(action))
+	if err != nil {
+		return action
+	}
+	resolved := d.base.ResolveReference(ref)
+	resolved.Fragment = ""
+	return resolved.String()
+}
+
+func (d *authDetector) result() AuthInfo {
+	best := make(map[string]int)
+	for _, e := range d.evidence {
+		if e.Weight > best[e.Signal] {
+			best[e.Signal] = e.Weight
+		}
+	}
+	info := AuthInfo{}
+	for signal, weight := range best {
+		info.Score += weight
+		switch signal {
+		case "password_input", "password_input_outside_form":
+			info.Mechanisms = append(info.Mechanisms, AuthPassword)
+		case "provider_link", "provider_script", "sso_button":
+			info.Mechanisms = append(info.Mechanisms, AuthOAuth)
+		case "passkey_autocomplete", "webauthn_script", "passkey_keyword":
+			info.Mechanisms = append(info.Mechanisms, AuthPasskey)
+		case "magic_link_form":
+			info.Mechanisms = append(info.Mechanisms, AuthMagicLink)
+		}
+	}
+	if info.Score > 100 {
+		info.Score = 100
+	}
+	info.Mechanisms = uniqueSorted(info.Mechanisms)
+	for name := range d.providers {
+		info.Providers = append(info.Providers, name)
+	}
+	sort.Strings(info.Providers)
+
+	if len(info.Mechanisms) > 0 {
+		switch {
+		case d.login && d.signup:
+			info.Purpose = "login_and_signup"
+		case d.signup:
+			info.Purpose = "signup"
+		default:
+			info.Purpose = "login"
+		}
+	}
+	if d.formScore > 0 {
+		info.FormAction = d.formAction
+	}
+	info.Evidence = d.evidence
+	if len(info.Evidence) > maxAuthEvidence {
+		info.Evidence = info.Evidence[:maxAuthEvidence]
+	}
+	return info
+}
+
+// documentLang returns the primary language subtag of the <html lang> attribute
+func documentLang(doc *html.Node) string {
+	var lang string
+	forEachElement(doc, "html", func(n *html.Node) {
+		if lang == "" {
+			lang = getAttr(n, "lang")
+		}
+	})
+	lang, _, _ = strings.Cut(strings.ToLower(strings.TrimSpace(lang)), "-")
+	return lang
+}
+
+// keywordsFor merges the keywords of the page language with English, which most sites mix in.
+// Pages of unknown language are matched against every language.
+func keywordsFor(lang string, keywords map[string]config.AuthKeywords) config.AuthKeywords {
+	var langs []string
+	if _, ok := keywords[lang]; ok {
+		langs = append(langs, lang)
+		if lang != "en" {
+			langs = append(langs, "en")
+		}
+	} else {
+		for l := range keywords {
+			langs = append(langs, l)
+		}
+		sort.Strings(langs)
+	}
+	var merged config.AuthKeywords
+	for _, l := range langs {
+		kw := keywords[l]
+		merged.Login = append(merged.Login, kw.Login...)
+		merged.Signup = append(merged.Signup, kw.Signup...)
+		merged.SSO = append(merged.SSO, kw.SSO...)
+		merged.MagicLink = append(merged.MagicLink, kw.MagicLink...)
+		merged.Passkey = append(merged.Passkey, kw.Passkey...)
+	}
+	return merged
+}
+
+// controlText is the visible text of n plus the values and labels of its controls
+func controlText(n *html.Node) string {
+	var sb strings.Builder
+	var walk func(*html.Node)
+	walk = func(node *html.Node) {
+		switch node.Type {
+		case html.TextNode:
+			sb.WriteString(node.Data)
+			sb.WriteByte(' ')
+		case html.ElementNode:
+			switch node.Data {
+			case "script", "style", "template":
+				return
+			case "input":
+				if t := strings.ToLower(getAttr(node, "type")); t == "submit" || t == "button" {
+					sb.WriteString(getAttr(node, "value"))
+					sb.WriteByte(' ')
+				}
+			}
+			if label := getAttr(node, "aria-label"); label != "" {
+				sb.WriteString(label)
+				sb.WriteByte(' ')
+			}
+		}
+		for c := node.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(n)
+	return sb.String()
+}
+
+// normalizeText lowercases text and collapses whitespace
+func normalizeText(s string) string {
+	return strings.Join(strings.Fields(strings.ToLower(s)), " ")
+}
+
+// matchPhrase returns the first phrase found in text as whole words
+func matchPhrase(text string, phrases []string) string {
+	for _, phrase := range phrases {
+		phrase = normalizeText(phrase)
+		if phrase != "" && containsWords(text, phrase) {
+			return phrase
+		}
+	}
+	return ""
+}
+
+func containsWords(text, phrase string) bool {
+	for start := 0; start < len(text); {
+		i := strings.Index(text[start:], phrase)
+		if i < 0 {
+			return false
+		}
+		i += start
+		end := i + len(phrase)
+		before, _ := utf8.DecodeLastRuneInString(text[:i])
+		after, _ := utf8.DecodeRuneInString(text[end:])
+		if (i == 0 || !isWordRune(before)) && (end == len(text) || !isWordRune(after)) {
+			return true
+		}
+		start = i + 1
+	}
+	return false
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// providerInText returns the identity provider named in the text following an SSO phrase
+func providerInText(text string) string {
+	for _, word := range strings.FieldsFunc(text, func(r rune) bool { return !isWordRune(r) }) {
+		if name, ok := providerNames[word]; ok {
+			return name
+		}
+	}
+	return ""
+}
+
+func matchesHost(host string, patterns []string) bool {
+	for _, p := range patterns {
+		if host == p || strings.HasPrefix(p, ".") && strings.HasSuffix(host, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasToken reports whether the space separated attribute value contains token
+func hasToken(value, token string) bool {
+	for _, t := range strings.Fields(value) {
+		if strings.EqualFold(t, token) {
+			return true
+		}
+	}
+	return false
+}
+
+// forEachElement calls fn for every element named tag below n, including n itself
+func forEachElement(n *html.Node, tag string, fn func(*html.Node)) {
+	if n.Type == html.ElementNode && n.Data == tag {
+		fn(n)
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		forEachElement(c, tag, fn)
+	}
+}
+
+// describe names an input for evidence, e.g. `input name="pass"`
+func describe(n *html.Node) string {
+	for _, key := range []string{"name", "id", "autocomplete"} {
+		if v := getAttr(n, key); v != "" {
+			return n.Data + " " + key + `="` + v + `"`
+		}
+	}
+	return n.Data
+}
+
+func formLabel(action string) string {
+	if action == "" {
+		return "form"
+	}
+	return `form action="` + action + `"`
+}
+
+func uniqueSorted(values []string) []string {
+	sort.Strings(values)
+	out := values[:0]
+	for i, v := range values {
+		if i == 0 || v != values[i-1] {
+			out = append(out, v)
+		}
+	}
+	return out
+}
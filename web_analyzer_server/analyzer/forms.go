@@ -0,0 +1,209 @@
+package analyzer
+
+import (
+	"context"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/publicsuffix"
+)
+
+// Issues reported in FormInfo.Issues
+const (
+	FormPasswordOverHTTP        = "password_over_http"        // a password form on a page served over plain HTTP
+	FormInsecureSubmit          = "insecure_submit"           // an HTTPS page submits the form to an HTTP URL
+	FormCrossSiteSubmit         = "cross_site_submit"         // the form submits to a different registrable domain
+	FormPasswordInGet           = "password_in_get"           // a password would be sent in the query string
+	FormMissingCSRFToken        = "missing_csrf_token"        // a same-site POST form has no CSRF-token-like field
+	FormPasswordAutocompleteOff = "password_autocomplete_off" // autocomplete="off" keeps password managers from filling the field
+	FormUploadWithoutMultipart  = "upload_without_multipart"  // a file input in a form that does not send multipart/form-data
+)
+
+// maxForms bounds the inventory of pages with very many forms
+const maxForms = 100
+
+// maxActionLength bounds reported actions, which can be data: URLs of any size
+const maxActionLength = 200
+
+// csrfFieldNames are substrings of the names of hidden fields that carry CSRF tokens in common frameworks
+var csrfFieldNames = []string{"csrf", "xsrf", "authenticity_token", "requestverificationtoken", "antiforgery", "anti-forgery", "_token", "nonce"}
+
+// FormInfo describes one <form> of a page
+type FormInfo struct {
+	Method               string         `json:"method"` // GET, POST or DIALOG
+	Action               string         `json:"action"` // resolved against the page URL
+	Enctype              string         `json:"enctype,omitempty"`
+	InputTypes           map[string]int `json:"input_types"`             // input types, plus textarea and select
+	HiddenFields         []string       `json:"hidden_fields,omitempty"` // names of hidden inputs, values are not reported
+	CSRFToken            bool           `json:"csrf_token"`              // a hidden field is named like a CSRF token
+	PasswordFields       int            `json:"password_fields"`
+	PasswordAutocomplete []string       `json:"password_autocomplete,omitempty"` // autocomplete of each password field, "" when unset
+	FileUpload           bool           `json:"file_upload"`
+	InsecureSubmit       bool           `json:"insecure_submit"` // submits from HTTPS to HTTP
+	CrossSite            bool           `json:"cross_site"`      // submits to a different registrable domain
+	Issues               []string       `json:"issues,omitempty"`
+}
+
+func (a *Analyzer) getForms(ctx context.Context, doc *html.Node, ch chan<- []FormInfo, wg *sync.WaitGroup, baseURL *url.URL) {
+	defer wg.Done()
+	ctx, end := startAnalyzer(ctx, "forms")
+	defer end()
+
+	if isCancelled(ctx) {
+		return
+	}
+
+	var forms []FormInfo
+	forEachElement(doc, "form", func(n *html.Node) {
+		if len(forms) < maxForms {
+			forms = append(forms, inspectForm(n, baseURL))
+		}
+	})
+
+	if isCancelled(ctx) {
+		return
+	}
+	ch <- forms
+}
+
+// inspectForm inventories the fields of a form and checks how it submits
+func inspectForm(n *html.Node, baseURL *url.URL) FormInfo {
+	form := FormInfo{
+		Method:     strings.ToUpper(strings.TrimSpace(getAttr(n, "method"))),
+		Enctype:    strings.ToLower(strings.TrimSpace(getAttr(n, "enctype"))),
+		InputTypes: make(map[string]int),
+	}
+	if form.Method == "" {
+		form.Method = "GET"
+	}
+
+	var walk func(*html.Node)
+	walk = func(c *html.Node) {
+		if c.Type == html.ElementNode {
+			switch c.Data {
+			case "input":
+				form.input(c)
+			case "textarea", "select":
+				form.InputTypes[c.Data]++
+			}
+		}
+		for child := c.FirstChild; child != nil; child = child.NextSibling {
+			walk(child)
+		}
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		walk(c)
+	}
+
+	action, target := resolveAction(getAttr(n, "action"), baseURL)
+	form.Action = action
+	if target != nil && baseURL != nil {
+		form.InsecureSubmit = baseURL.Scheme == "https" && target.Scheme == "http"
+		form.CrossSite = (target.Scheme == "http" || target.Scheme == "https") && !sameSite(baseURL.Hostname(), target.Hostname())
+	}
+	form.Issues = form.issues(baseURL)
+	return form
+}
+
+func (f *FormInfo) input(n *html.Node) {
+	inputType := strings.ToLower(strings.TrimSpace(getAttr(n, "type")))
+	if inputType == "" {
+		inputType = "text"
+	}
+	f.InputTypes[inputType]++
+	switch inputType {
+	case "hidden":
+		name := getAttr(n, "name")
+		if name == "" {
+			return
+		}
+		f.HiddenFields = append(f.HiddenFields, name)
+		lower := strings.ToLower(name)
+		for _, csrf := range csrfFieldNames {
+			if strings.Contains(lower, csrf) {
+				f.CSRFToken = true
+				break
+			}
+		}
+	case "password":
+		f.PasswordFields++
+		f.PasswordAutocomplete = append(f.PasswordAutocomplete, strings.ToLower(strings.TrimSpace(getAttr(n, "autocomplete"))))
+	case "file":
+		f.FileUpload = true
+	}
+}
+
+func (f *FormInfo) issues(baseURL *url.URL) []string {
+	var issues []string
+	if f.PasswordFields > 0 && baseURL != nil && baseURL.Scheme == "http" {
+		issues = append(issues, FormPasswordOverHTTP)
+	}
+	if f.InsecureSubmit {
+		issues = append(issues, FormInsecureSubmit)
+	}
+	if f.CrossSite {
+		issues = append(issues, FormCrossSiteSubmit)
+	}
+	if f.PasswordFields > 0 && f.Method == "GET" {
+		issues = append(issues, FormPasswordInGet)
+	}
+	// tokens are only expected on forms posting back to the site that issued them
+	if f.Method == "POST" && !f.CSRFToken && !f.CrossSite {
+		issues = append(issues, FormMissingCSRFToken)
+	}
+	for _, autocomplete := range f.PasswordAutocomplete {
+		if autocomplete == "off" {
+			issues = append(issues, FormPasswordAutocompleteOff)
+			break
+		}
+	}
+	if f.FileUpload && f.Enctype != "multipart/form-data" {
+		issues = append(issues, FormUploadWithoutMultipart)
+	}
+	return issues
+}
+
+// resolveAction resolves a form action against the page URL; an empty action submits to the page itself.
+// The parsed target is nil when the action cannot be parsed.
+func resolveAction(action string, baseURL *url.URL) (string, *url.URL) {
+	action = strings.TrimSpace(action)
+	target, err := url.Parse(action)
+	if err != nil {
+		return truncate(action, maxActionLength), nil
+	}
+	if baseURL != nil {
+		target = baseURL.ResolveReference(target)
+	}
+	return truncate(target.String(), maxActionLength), target
+}
+
+// registrableDomain returns the public suffix plus one label of host, e.g. "example.co.uk" for
+// "www.example.co.uk". IP addresses and hosts without a public suffix are returned as they are.
+func registrableDomain(host string) string {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	if net.ParseIP(host) != nil {
+		return host
+	}
+	if domain, err := publicsuffix.EffectiveTLDPlusOne(host); err == nil {
+		return domain
+	}
+	return host
+}
+
+func sameSite(a, b string) bool {
+	return registrableDomain(a) == registrableDomain(b)
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n] + "…"
+}
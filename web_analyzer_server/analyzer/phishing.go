@@ -0,0 +1,442 @@
+package analyzer
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/idna"
+	"golang.org/x/net/publicsuffix"
+)
+
+// Risk levels reported in PhishingInfo.Risk
+const (
+	PhishingRiskLow    = "low"
+	PhishingRiskMedium = "medium"
+	PhishingRiskHigh   = "high"
+)
+
+// Scores from which a page is reported as medium and high risk
+const (
+	phishingMediumScore = 30
+	phishingHighScore   = 60
+)
+
+// maxPhishingSignals bounds the signals reported for pages with many matching elements
+const maxPhishingSignals = 20
+
+// External resources are reported from minExternalResources resources when more than
+// maxExternalShare percent of them load from other sites
+const (
+	minExternalResources = 5
+	maxExternalShare     = 80
+)
+
+// Signals found by the heuristics and their weights. Like the authentication score, the risk score is
+// the sum of the weights of the distinct signals found, capped at 100.
+var phishingSignalWeights = map[string]int{
+	"password_form_external": 40,
+	"data_url_form":          40,
+	"lookalike_host":         35,
+	"brand_in_hostname":      30,
+	"login_form_external":    30,
+	"brand_title_mismatch":   25,
+	"punycode_host":          20,
+	"brand_resources":        20,
+	"hidden_iframe":          15,
+	"external_resources":     15,
+}
+
+// PhishingInfo is the phishing risk of a page, meant for screening reported URLs. A high score is a
+// reason for a closer look, not a verdict.
+type PhishingInfo struct {
+	Score   int              `json:"score"`             // 0 to 100
+	Risk    string           `json:"risk"`              // low, medium from 30 or high from 60
+	Signals []PhishingSignal `json:"signals,omitempty"` // what contributed to the score
+}
+
+// PhishingSignal is one finding that contributed to the score
+type PhishingSignal struct {
+	Signal string `json:"signal"`
+	Detail string `json:"detail"`
+	Weight int    `json:"weight"`
+}
+
+// brand is a frequently impersonated brand and the sites it owns
+type brand struct {
+	name     string
+	keywords []string // matched as whole words in titles; without spaces they also match host labels
+	labels   []string // registrable domains the brand owns, without their public suffix: "google" owns google.com and google.co.in
+}
+
+var brands = []brand{
+	{name: "PayPal", keywords: []string{"paypal"}, labels: []string{"paypal", "paypalobjects"}},
+	{name: "Apple", keywords: []string{"apple", "icloud", "apple id"}, labels: []string{"apple", "icloud"}},
+	{name: "Microsoft", keywords: []string{"microsoft", "office 365", "office365", "onedrive", "sharepoint"},
+		labels: []string{"microsoft", "microsoftonline", "live", "office", "office365", "outlook", "onedrive", "sharepoint", "msauth", "msftauth"}},
+	{name: "Google", keywords: []string{"google", "gmail"}, labels: []string{"google", "gmail", "googleusercontent", "gstatic", "youtube"}},
+	{name: "Amazon", keywords: []string{"amazon"}, labels: []string{"amazon", "media-amazon", "amazonaws"}},
+	{name: "Netflix", keywords: []string{"netflix"}, labels: []string{"netflix", "nflxext", "nflximg"}},
+	{name: "Facebook", keywords: []string{"facebook"}, labels: []string{"facebook", "fb", "fbcdn", "meta"}},
+	{name: "Instagram", keywords: []string{"instagram"}, labels: []string{"instagram", "cdninstagram"}},
+	{name: "WhatsApp", keywords: []string{"whatsapp"}, labels: []string{"whatsapp"}},
+	{name: "LinkedIn", keywords: []string{"linkedin"}, labels: []string{"linkedin", "licdn"}},
+	{name: "Dropbox", keywords: []string{"dropbox"}, labels: []string{"dropbox", "dropboxstatic"}},
+	{name: "DocuSign", keywords: []string{"docusign"}, labels: []string{"docusign"}},
+	{name: "Adobe", keywords: []string{"adobe"}, labels: []string{"adobe", "adobelogin"}},
+	{name: "DHL", keywords: []string{"dhl"}, labels: []string{"dhl"}},
+	{name: "FedEx", keywords: []string{"fedex"}, labels: []string{"fedex"}},
+	{name: "Chase", keywords: []string{"chase bank", "jpmorgan chase"}, labels: []string{"chase", "jpmorganchase"}},
+	{name: "Wells Fargo", keywords: []string{"wells fargo", "wellsfargo"}, labels: []string{"wellsfargo"}},
+	{name: "Bank of America", keywords: []string{"bank of america", "bankofamerica"}, labels: []string{"bankofamerica", "bofa"}},
+	{name: "Coinbase", keywords: []string{"coinbase"}, labels: []string{"coinbase"}},
+	{name: "Binance", keywords: []string{"binance"}, labels: []string{"binance"}},
+	{name: "MetaMask", keywords: []string{"metamask"}, labels: []string{"metamask"}},
+}
+
+// commonHostWords are dictionary words frequent in hostnames. They are one typo away from some brand
+// keywords, "finance" from "binance", but are not taken as imitations of them.
+var commonHostWords = map[string]bool{
+	"account": true, "accounts": true, "admin": true, "alliance": true, "answers": true, "app": true, "apps": true,
+	"assets": true, "auth": true, "balance": true, "bank": true, "billing": true, "blog": true, "business": true,
+	"careers": true, "cdn": true, "cloud": true, "community": true, "compliance": true, "developer": true,
+	"developers": true, "docs": true, "download": true, "events": true, "finance": true,
+	"forum": true, "forums": true, "global": true, "health": true, "help": true, "images": true, "insurance": true,
+	"login": true, "mail": true, "maps": true, "marketing": true, "media": true, "mobile": true, "money": true,
+	"music": true, "news": true, "online": true, "pay": true, "payments": true, "photos": true, "portal": true,
+	"search": true, "secure": true, "security": true, "services": true, "shop": true, "shopping": true,
+	"sports": true, "static": true, "status": true, "store": true, "support": true, "travel": true, "video": true,
+	"weather": true, "www": true,
+}
+
+// confusables maps digits and Cyrillic and Greek letters to the Latin letters they imitate in hostnames
+var confusables = map[rune]rune{
+	'0': 'o', '1': 'l', '3': 'e', '4': 'a', '5': 's', '7': 't',
+	'а': 'a', 'е': 'e', 'о': 'o', 'р': 'p', 'с': 'c', 'у': 'y', 'х': 'x', 'і': 'i', 'ј': 'j', 'ѕ': 's', 'ԁ': 'd', 'ӏ': 'l', 'һ': 'h', 'ԛ': 'q', 'ԝ': 'w',
+	'α': 'a', 'ο': 'o', 'ρ': 'p', 'ν': 'v', 'ι': 'i', 'κ': 'k', 'τ': 't',
+}
+
+// assessPhishing scores the phishing risk of a page from the results of the other analyzers and the document.
+// It runs once they are done, since it builds on the authentication surface and the form inventory.
+func (a *Analyzer) assessPhishing(ctx context.Context, doc *html.Node, baseURL *url.URL, info PageInfo) PhishingInfo {
+	_, end := startAnalyzer(ctx, "phishing")
+	defer end()
+	return detectPhishing(doc, baseURL, info)
+}
+
+// phishingDetector collects the signals of one page
+type phishingDetector struct {
+	base    *url.URL
+	host    string // ASCII hostname of the page
+	domain  string // registrable domain of host
+	signals []PhishingSignal
+	seen    map[PhishingSignal]bool
+}
+
+func detectPhishing(doc *html.Node, baseURL *url.URL, info PageInfo) PhishingInfo {
+	d := &phishingDetector{base: baseURL, seen: make(map[PhishingSignal]bool)}
+	if baseURL != nil && baseURL.Hostname() != "" {
+		d.host = strings.TrimSuffix(strings.ToLower(baseURL.Hostname()), ".")
+		if ascii, err := idna.Lookup.ToASCII(d.host); err == nil {
+			d.host = ascii
+		}
+		d.domain = registrableDomain(d.host)
+	}
+
+	d.forms(info)
+	if d.host != "" {
+		d.hostname()
+		d.title(info.Title)
+		d.resources(doc, info.HasLogin)
+	}
+	d.iframes(doc)
+	return d.result()
+}
+
+func (d *phishingDetector) add(signal, detail string) {
+	s := PhishingSignal{Signal: signal, Detail: detail, Weight: phishingSignalWeights[signal]}
+	if d.seen[s] {
+		return
+	}
+	d.seen[s] = true
+	d.signals = append(d.signals, s)
+}
+
+// forms flags credentials that leave the site and forms whose action is an inline document
+func (d *phishingDetector) forms(info PageInfo) {
+	passwordExternal := false
+	for _, f := range info.Forms {
+		if strings.HasPrefix(strings.ToLower(f.Action), "data:") {
+			d.add("data_url_form", formLabel(truncate(f.Action, 60)))
+		}
+		if f.PasswordFields > 0 && f.CrossSite {
+			passwordExternal = true
+			d.add("password_form_external", formLabel(f.Action))
+		}
+	}
+	// sign-in forms that ask for the password on a later step have no password field yet
+	if passwordExternal || !info.HasLogin || info.Auth.FormAction == "" || d.host == "" {
+		return
+	}
+	target, err := url.Parse(info.Auth.FormAction)
+	if err == nil && (target.Scheme == "http" || target.Scheme == "https") && !sameSite(d.host, target.Hostname()) {
+		d.add("login_form_external", formLabel(info.Auth.FormAction))
+	}
+}
+
+// hostname flags punycode hosts and hosts that imitate or name a brand they do not belong to
+func (d *phishingDetector) hostname() {
+	unicodeHost := d.host
+	for _, label := range strings.Split(d.host, ".") {
+		if strings.HasPrefix(label, "xn--") {
+			unicodeHost, _ = idna.Lookup.ToUnicode(d.host)
+			d.add("punycode_host", fmt.Sprintf("%s (%s)", d.host, unicodeHost))
+			break
+		}
+	}
+
+	// labels of the public suffix, such as "com" in "paypal.com.example.com", are not compared
+	name := unicodeHost
+	if suffix, _ := publicsuffix.PublicSuffix(unicodeHost); suffix != "" && suffix != unicodeHost {
+		name = strings.TrimSuffix(unicodeHost, "."+suffix)
+	}
+	for _, token := range strings.FieldsFunc(name, func(r rune) bool { return r == '.' || r == '-' }) {
+		skeleton := hostSkeleton(token)
+		for _, b := range brands {
+			if b.owns(d.domain) {
+				continue
+			}
+			for _, kw := range b.keywords {
+				if strings.Contains(kw, " ") {
+					continue
+				}
+				switch {
+				case token == kw || len(kw) >= 6 && strings.Contains(token, kw):
+					d.add("brand_in_hostname", fmt.Sprintf("%s names %s", d.host, b.name))
+				case skeleton == kw || len(kw) >= 6 && editDistanceOne(skeleton, kw) && !commonHostWords[token] && !strings.HasSuffix(kw, token):
+					d.add("lookalike_host", fmt.Sprintf("%q imitates %s", token, b.name))
+				}
+			}
+		}
+	}
+}
+
+// title flags brands named in the title of a page on a host they do not own
+func (d *phishingDetector) title(title string) {
+	text := normalizeText(title)
+	if text == "" {
+		return
+	}
+	for _, b := range brands {
+		if b.owns(d.domain) {
+			continue
+		}
+		if kw := matchPhrase(text, b.keywords); kw != "" {
+			d.add("brand_title_mismatch", fmt.Sprintf("title names %s on %s", b.name, d.domain))
+		}
+	}
+}
+
+// resources compares the sites the page loads scripts, styles, images and frames from with its own.
+// Logos and styles hotlinked from a brand only count on pages with a login.
+func (d *phishingDetector) resources(doc *html.Node, hasLogin bool) {
+	total, external := 0, 0
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			var ref string
+			switch n.Data {
+			case "script", "img", "iframe", "embed", "source", "video", "audio":
+				ref = getAttr(n, "src")
+			case "link":
+				if hasRel(n, "stylesheet") || hasRel(n, "icon") || hasRel(n, "preload") || hasRel(n, "modulepreload") {
+					ref = getAttr(n, "href")
+				}
+			}
+			if target := d.resolve(ref); target != nil {
+				total++
+				if !sameSite(d.host, target.Hostname()) {
+					external++
+					if hasLogin {
+						d.brandResource(target)
+					}
+				}
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(doc)
+
+	if total >= minExternalResources && external*100 > total*maxExternalShare {
+		d.add("external_resources", fmt.Sprintf("%d of %d resources load from other sites", external, total))
+	}
+}
+
+func (d *phishingDetector) brandResource(target *url.URL) {
+	domain := registrableDomain(target.Hostname())
+	for _, b := range brands {
+		if b.owns(domain) && !b.owns(d.domain) {
+			d.add("brand_resources", fmt.Sprintf("loads %s from %s", b.name, target.Hostname()))
+		}
+	}
+}
+
+// resolve returns the absolute HTTP(S) URL of a resource reference, nil for anything else
+func (d *phishingDetector) resolve(ref string) *url.URL {
+	ref = strings.TrimSpace(ref)
+	if ref == "" {
+		return nil
+	}
+	parsed, err := url.Parse(ref)
+	if err != nil {
+		return nil
+	}
+	target := d.base.ResolveReference(parsed)
+	if target.Scheme != "http" && target.Scheme != "https" {
+		return nil
+	}
+	return target
+}
+
+// iframes flags frames that are present but not shown, a way to load credential harvesters or trackers unseen
+func (d *phishingDetector) iframes(doc *html.Node) {
+	forEachElement(doc, "iframe", func(n *html.Node) {
+		if reason := hiddenReason(n); reason != "" {
+			detail := "iframe " + reason
+			if src := getAttr(n, "src"); src != "" {
+				detail = fmt.Sprintf(`iframe src="%s" %s`, truncate(src, maxActionLength), reason)
+			}
+			d.add("hidden_iframe", detail)
+		}
+	})
+}
+
+// hiddenReason describes why an element is invisible, "" when it is not
+func hiddenReason(n *html.Node) string {
+	for _, attr := range n.Attr {
+		if attr.Key == "hidden" {
+			return "hidden"
+		}
+	}
+	for _, key := range []string{"width", "height"} {
+		if tinySize(getAttr(n, key)) {
+			return key + "=" + strings.TrimSpace(getAttr(n, key))
+		}
+	}
+	for _, decl := range strings.Split(strings.ToLower(getAttr(n, "style")), ";") {
+		prop, value, ok := strings.Cut(decl, ":")
+		if !ok {
+			continue
+		}
+		prop = strings.TrimSpace(prop)
+		value = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(value), "!important"))
+		switch {
+		case prop == "display" && value == "none",
+			prop == "visibility" && value == "hidden",
+			prop == "opacity" && isZero(value),
+			(prop == "width" || prop == "height") && tinySize(value):
+			return "style " + prop + ":" + value
+		}
+	}
+	return ""
+}
+
+// tinySize reports whether a width or height of at most one pixel is set
+func tinySize(value string) bool {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return false
+	}
+	size, err := strconv.ParseFloat(strings.TrimSuffix(value, "px"), 64)
+	return err == nil && size <= 1
+}
+
+func isZero(value string) bool {
+	f, err := strconv.ParseFloat(value, 64)
+	return err == nil && f == 0
+}
+
+func (d *phishingDetector) result() PhishingInfo {
+	best := make(map[string]int)
+	for _, s := range d.signals {
+		best[s.Signal] = s.Weight
+	}
+	info := PhishingInfo{Risk: PhishingRiskLow}
+	for _, weight := range best {
+		info.Score += weight
+	}
+	if info.Score > 100 {
+		info.Score = 100
+	}
+	switch {
+	case info.Score >= phishingHighScore:
+		info.Risk = PhishingRiskHigh
+	case info.Score >= phishingMediumScore:
+		info.Risk = PhishingRiskMedium
+	}
+	info.Signals = d.signals
+	if len(info.Signals) > maxPhishingSignals {
+		info.Signals = info.Signals[:maxPhishingSignals]
+	}
+	return info
+}
+
+// owns reports whether the registrable domain belongs to the brand, under any public suffix
+func (b brand) owns(domain string) bool {
+	label := domain
+	if suffix, _ := publicsuffix.PublicSuffix(domain); suffix != domain {
+		label = strings.TrimSuffix(domain, "."+suffix)
+	}
+	for _, l := range b.labels {
+		if label == l {
+			return true
+		}
+	}
+	return false
+}
+
+// hostSkeleton maps look-alike characters of a host label to the letters they imitate, e.g. "paypa1" to "paypal"
+func hostSkeleton(label string) string {
+	label = strings.ReplaceAll(label, "rn", "m")
+	label = strings.ReplaceAll(label, "vv", "w")
+	return strings.Map(func(r rune) rune {
+		if c, ok := confusables[r]; ok {
+			return c
+		}
+		return r
+	}, label)
+}
+
+// editDistanceOne reports whether a and b differ by exactly one inserted, deleted, replaced or
+// swapped adjacent character, the typos squatters register
+func editDistanceOne(a, b string) bool {
+	ra, rb := []rune(a), []rune(b)
+	if len(ra) < len(rb) {
+		ra, rb = rb, ra
+	}
+	switch len(ra) - len(rb) {
+	case 0:
+		var diff []int
+		for i := range ra {
+			if ra[i] != rb[i] {
+				diff = append(diff, i)
+			}
+		}
+		if len(diff) == 1 {
+			return true
+		}
+		return len(diff) == 2 && diff[1] == diff[0]+1 && ra[diff[0]] == rb[diff[1]] && ra[diff[1]] == rb[diff[0]]
+	case 1:
+		i := 0
+		for i < len(rb) && ra[i] == rb[i] {
+			i++
+		}
+		return string(ra[i+1:]) == string(rb[i:])
+	}
+	return false
+}
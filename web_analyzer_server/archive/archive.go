@@ -0,0 +1,132 @@
+package archive
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/sashithaf16/peekalo/analyzer"
+)
+
+// Format names the kind of archive a capture was read from
+type Format string
+
+const (
+	FormatHAR  Format = "har"
+	FormatWARC Format = "warc"
+)
+
+const defaultConcurrency = 4
+
+// ErrUnknownFormat is returned when the input is neither a HAR nor a WARC file
+var ErrUnknownFormat = errors.New("input is not a HAR or WARC file")
+
+// Record is one captured HTML response. Body holds the decoded payload; Error explains why it is
+// unavailable, for example when the capture left it out or used an unsupported content encoding.
+type Record struct {
+	URL        string
+	StatusCode int
+	Header     http.Header
+	CapturedAt time.Time
+	Body       []byte
+	Error      string
+}
+
+// DocumentAnalyzer analyzes HTML without fetching it
+type DocumentAnalyzer interface {
+	AnalyzeDocument(ctx context.Context, r io.Reader, baseURL string) (analyzer.PageInfo, error)
+}
+
+// Page is the analysis of one record. Info is nil when the analysis failed.
+type Page struct {
+	URL        string             `json:"url"`
+	StatusCode int                `json:"status_code"`
+	Header     http.Header        `json:"headers,omitempty"`
+	CapturedAt time.Time          `json:"captured_at,omitempty"`
+	Info       *analyzer.PageInfo `json:"page_info,omitempty"`
+	Error      string             `json:"error,omitempty"`
+}
+
+// Read detects whether r holds a HAR or a WARC file, gzip-compressed or not, and returns its HTML responses
+// in capture order
+func Read(r io.Reader) ([]Record, Format, error) {
+	br := bufio.NewReader(r)
+	if magic, _ := br.Peek(2); bytes.Equal(magic, []byte{0x1f, 0x8b}) {
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to decompress archive: %v", err)
+		}
+		defer gz.Close()
+		br = bufio.NewReader(gz)
+	}
+
+	head, _ := br.Peek(512)
+	head = bytes.TrimLeft(bytes.TrimPrefix(head, []byte("\xef\xbb\xbf")), " \t\r\n")
+	switch {
+	case bytes.HasPrefix(head, []byte("{")):
+		records, err := ReadHAR(br)
+		return records, FormatHAR, err
+	case bytes.HasPrefix(head, []byte("WARC/")):
+		records, err := ReadWARC(br)
+		return records, FormatWARC, err
+	default:
+		return nil, "", ErrUnknownFormat
+	}
+}
+
+// Analyze runs the analyzer on every record, concurrently, without fetching anything. Links are
+// resolved against the record URL and the captured status code is reported as the page status.
+func Analyze(ctx context.Context, an DocumentAnalyzer, records []Record, concurrency int) []Page {
+	if concurrency <= 0 {
+		concurrency = defaultConcurrency
+	}
+	pages := make([]Page, len(records))
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i, rec := range records {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, rec Record) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			pages[i] = analyzeRecord(ctx, an, rec)
+		}(i, rec)
+	}
+	wg.Wait()
+	return pages
+}
+
+func analyzeRecord(ctx context.Context, an DocumentAnalyzer, rec Record) Page {
+	page := Page{URL: rec.URL, StatusCode: rec.StatusCode, Header: rec.Header, CapturedAt: rec.CapturedAt}
+	if rec.Error != "" {
+		page.Error = rec.Error
+		return page
+	}
+	info, err := an.AnalyzeDocument(ctx, bytes.NewReader(rec.Body), rec.URL)
+	if err != nil {
+		page.Error = err.Error()
+		return page
+	}
+	info.StatusCode = rec.StatusCode
+	page.Info = &info
+	return page
+}
+
+// isHTML reports whether a response content type is an HTML document
+func isHTML(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && (mediaType == "text/html" || mediaType == "application/xhtml+xml")
+}
+
+// hasBody reports whether responses with the status code can carry a document
+func hasBody(status int) bool {
+	return status >= 200 && status != http.StatusNoContent && status != http.StatusNotModified
+}
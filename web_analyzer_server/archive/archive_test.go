@@ -0,0 +1,115 @@
+package archive
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sashithaf16/peekalo/analyzer"
+	"github.com/sashithaf16/peekalo/config"
+	"github.com/sashithaf16/peekalo/logger"
+)
+
+const harLog = `{"log": {"version": "1.2", "entries": [
+	{"startedDateTime": "2025-03-01T10:00:00.000Z", "request": {"url": "https://example.com/"},
+	 "response": {"status": 200, "headers": [{"name": "Content-Type", "value": "text/html; charset=utf-8"}, {"name": "Content-Encoding", "value": "gzip"}],
+	  "content": {"mimeType": "text/html; charset=utf-8", "text": "<html><head><title>Home</title></head><body><a href=\"/login\">Sign in</a></body></html>"}}},
+	{"startedDateTime": "2025-03-01T10:00:01.000Z", "request": {"url": "https://example.com/app.js"},
+	 "response": {"status": 200, "headers": [], "content": {"mimeType": "application/javascript", "text": "alert(1)"}}},
+	{"startedDateTime": "2025-03-01T10:00:02.000Z", "request": {"url": "https://example.com/gone"},
+	 "response": {"status": 404, "headers": [], "content": {"mimeType": "text/html", "encoding": "base64", "text": "PHRpdGxlPk5vdCBmb3VuZDwvdGl0bGU+"}}},
+	{"startedDateTime": "2025-03-01T10:00:03.000Z", "request": {"url": "https://example.com/cached"},
+	 "response": {"status": 304, "headers": [], "content": {"mimeType": "text/html"}}},
+	{"startedDateTime": "2025-03-01T10:00:04.000Z", "request": {"url": "https://example.com/blank"},
+	 "response": {"status": 200, "headers": [], "content": {"mimeType": "text/html"}}}
+]}}`
+
+func warcRecord(warcType, uri, contentType, block string) string {
+	return fmt.Sprintf("WARC/1.0\r\nWARC-Type: %s\r\nWARC-Target-URI: %s\r\nWARC-Date: 2025-03-01T10:00:00Z\r\nContent-Type: %s\r\nContent-Length: %d\r\n\r\n%s\r\n\r\n",
+		warcType, uri, contentType, len(block), block)
+}
+
+func testWARC(t *testing.T) string {
+	var gz bytes.Buffer
+	zw := gzip.NewWriter(&gz)
+	_, err := zw.Write([]byte("<html><head><title>Pricing</title></head></html>"))
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+
+	return warcRecord("warcinfo", "", "application/warc-fields", "software: test\r\n") +
+		warcRecord("request", "https://example.com/", "application/http; msgtype=request", "GET / HTTP/1.1\r\nHost: example.com\r\n\r\n") +
+		warcRecord("response", "https://example.com/", "application/http; msgtype=response",
+			"HTTP/1.1 200 OK\r\nContent-Type: text/html\r\nTransfer-Encoding: chunked\r\n\r\n1c\r\n<html><title>Home</title><a \r\nf\r\nhref=\"/a\">A</a>\r\n0\r\n\r\n") +
+		warcRecord("response", "<https://example.com/pricing>", "application/http; msgtype=response",
+			"HTTP/1.1 500 Internal Server Error\r\nContent-Type: text/html\r\nContent-Encoding: gzip\r\nContent-Length: "+fmt.Sprint(gz.Len())+"\r\n\r\n"+gz.String()) +
+		warcRecord("response", "https://example.com/logo.png", "application/http; msgtype=response",
+			"HTTP/1.1 200 OK\r\nContent-Type: image/png\r\n\r\nPNG") +
+		warcRecord("response", "https://example.com/br", "application/http; msgtype=response",
+			"HTTP/1.1 200 OK\r\nContent-Type: text/html\r\nContent-Encoding: br\r\n\r\nxyz")
+}
+
+func TestReadHAR(t *testing.T) {
+	records, format, err := Read(strings.NewReader(harLog))
+	require.NoError(t, err)
+	assert.Equal(t, FormatHAR, format)
+	require.Len(t, records, 3, "scripts and 304 responses are skipped")
+
+	assert.Equal(t, "https://example.com/", records[0].URL)
+	assert.Equal(t, "gzip", records[0].Header.Get("Content-Encoding"))
+	assert.Equal(t, 2025, records[0].CapturedAt.Year())
+	assert.Equal(t, "<title>Not found</title>", string(records[1].Body))
+	assert.Equal(t, 404, records[1].StatusCode)
+	assert.Equal(t, "response body was not captured", records[2].Error)
+}
+
+func TestReadWARC(t *testing.T) {
+	var gz bytes.Buffer
+	zw := gzip.NewWriter(&gz)
+	_, err := zw.Write([]byte(testWARC(t)))
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+
+	for name, input := range map[string][]byte{"plain": []byte(testWARC(t)), "gzip": gz.Bytes()} {
+		t.Run(name, func(t *testing.T) {
+			records, format, err := Read(bytes.NewReader(input))
+			require.NoError(t, err)
+			assert.Equal(t, FormatWARC, format)
+			require.Len(t, records, 3)
+
+			assert.Equal(t, "https://example.com/", records[0].URL)
+			assert.Equal(t, `<html><title>Home</title><a href="/a">A</a>`, string(records[0].Body))
+			assert.Equal(t, "https://example.com/pricing", records[1].URL)
+			assert.Equal(t, 500, records[1].StatusCode)
+			assert.Contains(t, string(records[1].Body), "Pricing")
+			assert.Contains(t, records[2].Error, "unsupported content encoding")
+		})
+	}
+
+	_, err = ReadWARC(strings.NewReader("WARC/1.0\r\nContent-Length: 10\r\n\r\nshort"))
+	assert.Error(t, err)
+	_, _, err = Read(strings.NewReader("<html></html>"))
+	assert.ErrorIs(t, err, ErrUnknownFormat)
+}
+
+func TestAnalyze(t *testing.T) {
+	records, _, err := Read(strings.NewReader(harLog))
+	require.NoError(t, err)
+	an := analyzer.NewAnalyzer(logger.CreateLogger("error"), &config.Config{}, nil)
+
+	pages := Analyze(context.Background(), an, records, 2)
+	require.Len(t, pages, 3)
+	require.NotNil(t, pages[0].Info)
+	assert.Equal(t, "Home", pages[0].Info.Title)
+	assert.Equal(t, 200, pages[0].Info.StatusCode)
+	assert.Equal(t, []string{"https://example.com/login"}, pages[0].Info.Links.InternalLinks)
+	require.NotNil(t, pages[1].Info)
+	assert.Equal(t, 404, pages[1].Info.StatusCode)
+	assert.Nil(t, pages[2].Info)
+	assert.Equal(t, "response body was not captured", pages[2].Error)
+}
@@ -0,0 +1,79 @@
+package archive
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// harFile holds the parts of a HAR 1.2 log the importer reads
+type harFile struct {
+	Log struct {
+		Entries []harEntry `json:"entries"`
+	} `json:"log"`
+}
+
+type harEntry struct {
+	StartedDateTime string `json:"startedDateTime"`
+	Request         struct {
+		URL string `json:"url"`
+	} `json:"request"`
+	Response struct {
+		Status  int `json:"status"`
+		Headers []struct {
+			Name  string `json:"name"`
+			Value string `json:"value"`
+		} `json:"headers"`
+		Content struct {
+			MimeType string  `json:"mimeType"`
+			Text     *string `json:"text"`
+			Encoding string  `json:"encoding"`
+		} `json:"content"`
+	} `json:"response"`
+}
+
+// ReadHAR returns the HTML responses of a HAR file. HAR content is stored decoded, so the bodies
+// are used as captured even when the headers name a content encoding.
+func ReadHAR(r io.Reader) ([]Record, error) {
+	var har harFile
+	if err := json.NewDecoder(r).Decode(&har); err != nil {
+		return nil, fmt.Errorf("failed to decode HAR file: %v", err)
+	}
+
+	var records []Record
+	for _, e := range har.Log.Entries {
+		header := make(http.Header)
+		for _, h := range e.Response.Headers {
+			header.Add(h.Name, h.Value)
+		}
+		contentType := e.Response.Content.MimeType
+		if contentType == "" {
+			contentType = header.Get("Content-Type")
+		}
+		if !isHTML(contentType) || !hasBody(e.Response.Status) {
+			continue
+		}
+
+		rec := Record{URL: e.Request.URL, StatusCode: e.Response.Status, Header: header}
+		if t, err := time.Parse(time.RFC3339Nano, e.StartedDateTime); err == nil {
+			rec.CapturedAt = t
+		}
+		switch text := e.Response.Content.Text; {
+		case text == nil:
+			rec.Error = "response body was not captured"
+		case e.Response.Content.Encoding == "base64":
+			body, err := base64.StdEncoding.DecodeString(*text)
+			if err != nil {
+				rec.Error = fmt.Sprintf("failed to decode response body: %v", err)
+			}
+			rec.Body = body
+		default:
+			rec.Body = []byte(*text)
+		}
+		records = append(records, rec)
+	}
+	return records, nil
+}
@@ -0,0 +1,131 @@
+package archive
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ReadWARC returns the HTML responses of a WARC file. Only response records holding an HTTP
+// response are read; request, metadata and revisit records are skipped. Chunked transfer
+// encoding and gzip or deflate content encoding are decoded.
+func ReadWARC(r io.Reader) ([]Record, error) {
+	br := bufio.NewReader(r)
+	tp := textproto.NewReader(br)
+
+	var records []Record
+	for n := 1; ; n++ {
+		version, err := nextVersionLine(tp)
+		if err == io.EOF {
+			return records, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read WARC record %d: %v", n, err)
+		}
+		if !strings.HasPrefix(version, "WARC/") {
+			return nil, fmt.Errorf("WARC record %d starts with %q instead of a version line", n, version)
+		}
+		header, err := tp.ReadMIMEHeader()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read headers of WARC record %d: %v", n, err)
+		}
+		length, err := strconv.ParseInt(header.Get("Content-Length"), 10, 64)
+		if err != nil || length < 0 {
+			return nil, fmt.Errorf("WARC record %d has an invalid Content-Length", n)
+		}
+		block := make([]byte, length)
+		if _, err := io.ReadFull(br, block); err != nil {
+			return nil, fmt.Errorf("failed to read block of WARC record %d: %v", n, err)
+		}
+
+		if header.Get("WARC-Type") != "response" || !strings.HasPrefix(header.Get("Content-Type"), "application/http") {
+			continue
+		}
+		rec, ok := readHTTPResponse(block)
+		if !ok {
+			continue
+		}
+		rec.URL = strings.Trim(header.Get("WARC-Target-URI"), "<>")
+		if t, err := time.Parse(time.RFC3339Nano, header.Get("WARC-Date")); err == nil {
+			rec.CapturedAt = t
+		}
+		records = append(records, rec)
+	}
+}
+
+// nextVersionLine skips the blank lines that separate records
+func nextVersionLine(tp *textproto.Reader) (string, error) {
+	for {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return "", err
+		}
+		if line != "" {
+			return line, nil
+		}
+	}
+}
+
+// readHTTPResponse parses a response block and reports whether it is an HTML document
+func readHTTPResponse(block []byte) (Record, bool) {
+	resp, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(block)), nil)
+	if err != nil {
+		return Record{}, false
+	}
+	defer resp.Body.Close()
+	if !isHTML(resp.Header.Get("Content-Type")) || !hasBody(resp.StatusCode) {
+		return Record{}, false
+	}
+
+	rec := Record{StatusCode: resp.StatusCode, Header: resp.Header}
+	// crawlers may truncate long payloads, so a partial body is still analyzed
+	body, err := io.ReadAll(resp.Body)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		rec.Error = fmt.Sprintf("failed to read response body: %v", err)
+		return rec, true
+	}
+	rec.Body, err = decodeContent(resp.Header.Get("Content-Encoding"), body)
+	if err != nil {
+		rec.Error = err.Error()
+	}
+	return rec, true
+}
+
+func decodeContent(encoding string, body []byte) ([]byte, error) {
+	var r io.ReadCloser
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "", "identity":
+		return body, nil
+	case "gzip", "x-gzip":
+		gz, err := gzip.NewReader(bytes.NewReader(body))
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode gzip content: %v", err)
+		}
+		r = gz
+	case "deflate":
+		// servers send zlib-wrapped or raw deflate data under the same name
+		if zr, err := zlib.NewReader(bytes.NewReader(body)); err == nil {
+			r = zr
+		} else {
+			r = flate.NewReader(bytes.NewReader(body))
+		}
+	default:
+		return nil, fmt.Errorf("unsupported content encoding %q", encoding)
+	}
+	defer r.Close()
+	decoded, err := io.ReadAll(r)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return nil, fmt.Errorf("failed to decode %s content: %v", encoding, err)
+	}
+	return decoded, nil
+}
@@ -0,0 +1,230 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sashithaf16/peekalo/ratelimit"
+)
+
+type Scope string
+
+const (
+	ScopeAnalyze Scope = "analyze" // may call the analysis endpoints
+	ScopeAdmin   Scope = "admin"   // may call every endpoint, including administration
+)
+
+// Key is an API key as configured. Only the SHA-256 hash of the secret is ever stored.
+type Key struct {
+	ID             string  `json:"id"`
+	Hash           string  `json:"hash"` // hex encoded SHA-256 of the raw key
+	Scopes         []Scope `json:"scopes"`
+	DailyQuota     int     `json:"daily_quota"`      // requests per UTC day, 0 means unlimited
+	RateLimitRPS   float64 `json:"rate_limit_rps"`   // per-key sustained rate, 0 uses the global limit only
+	RateLimitBurst int     `json:"rate_limit_burst"` // per-key burst size
+}
+
+// HasScope reports whether the key grants scope. The admin scope grants every scope.
+func (k *Key) HasScope(scope Scope) bool {
+	for _, s := range k.Scopes {
+		if s == scope || s == ScopeAdmin {
+			return true
+		}
+	}
+	return false
+}
+
+// HashKey returns the hex encoded SHA-256 of a raw API key, the form keys are configured in
+func HashKey(raw string) string {
+	sum := sha256.Sum256([]byte(raw))
+	return hex.EncodeToString(sum[:])
+}
+
+// LoadKeys reads keys from a JSON file and from a JSON string (usually an environment variable).
+// Both hold an array of Key objects; either may be empty.
+func LoadKeys(path, inline string) ([]Key, error) {
+	var keys []Key
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read API keys file: %v", err)
+		}
+		var fileKeys []Key
+		if err := json.Unmarshal(data, &fileKeys); err != nil {
+			return nil, fmt.Errorf("failed to parse API keys file: %v", err)
+		}
+		keys = append(keys, fileKeys...)
+	}
+	if strings.TrimSpace(inline) != "" {
+		var envKeys []Key
+		if err := json.Unmarshal([]byte(inline), &envKeys); err != nil {
+			return nil, fmt.Errorf("failed to parse API keys: %v", err)
+		}
+		keys = append(keys, envKeys...)
+	}
+
+	seen := make(map[string]bool, len(keys))
+	for i := range keys {
+		k := &keys[i]
+		k.Hash = strings.ToLower(k.Hash)
+		if k.ID == "" {
+			return nil, fmt.Errorf("API key %d has no id", i)
+		}
+		if seen[k.ID] {
+			return nil, fmt.Errorf("duplicate API key id: %s", k.ID)
+		}
+		seen[k.ID] = true
+		if decoded, err := hex.DecodeString(k.Hash); err != nil || len(decoded) != sha256.Size {
+			return nil, fmt.Errorf("API key %s: hash must be a hex encoded SHA-256", k.ID)
+		}
+		if len(k.Scopes) == 0 {
+			return nil, fmt.Errorf("API key %s has no scopes", k.ID)
+		}
+		for _, s := range k.Scopes {
+			if s != ScopeAnalyze && s != ScopeAdmin {
+				return nil, fmt.Errorf("API key %s has unknown scope: %s", k.ID, s)
+			}
+		}
+	}
+	return keys, nil
+}
+
+type dailyUsage struct {
+	day   string
+	count int
+}
+
+// Usage is the quota state of a key for the current UTC day
+type Usage struct {
+	KeyID      string    `json:"key_id"`
+	Scopes     []Scope   `json:"scopes"`
+	Used       int       `json:"used"`
+	DailyQuota int       `json:"daily_quota"`
+	ResetsAt   time.Time `json:"resets_at"`
+}
+
+// Store authenticates API keys and tracks their daily quotas and rate limits in memory
+type Store struct {
+	keys     []Key
+	limiters map[string]*ratelimit.Limiter
+
+	mu    sync.Mutex
+	usage map[string]*dailyUsage
+	now   func() time.Time
+}
+
+func NewStore(keys []Key) *Store {
+	s := &Store{
+		keys:     keys,
+		limiters: make(map[string]*ratelimit.Limiter),
+		usage:    make(map[string]*dailyUsage),
+		now:      time.Now,
+	}
+	for _, k := range keys {
+		if k.RateLimitRPS > 0 {
+			s.limiters[k.ID] = ratelimit.NewLimiter(k.RateLimitRPS, k.RateLimitBurst)
+		}
+	}
+	return s
+}
+
+// Authenticate returns the key matching the raw secret. Every configured hash is compared
+// in constant time so the lookup does not leak which keys exist.
+func (s *Store) Authenticate(raw string) (*Key, bool) {
+	if raw == "" {
+		return nil, false
+	}
+	hash := []byte(HashKey(raw))
+	var match *Key
+	for i := range s.keys {
+		if subtle.ConstantTimeCompare(hash, []byte(s.keys[i].Hash)) == 1 {
+			match = &s.keys[i]
+		}
+	}
+	return match, match != nil
+}
+
+// AllowRate applies the key's own rate limit, if it has one
+func (s *Store) AllowRate(k *Key) (bool, time.Duration) {
+	limiter, ok := s.limiters[k.ID]
+	if !ok {
+		return true, 0
+	}
+	return limiter.Allow(k.ID)
+}
+
+// ConsumeQuota counts one request against the key's daily quota. When the quota is
+// exhausted it returns false and the time the quota resets.
+func (s *Store) ConsumeQuota(k *Key) (bool, time.Time) {
+	return s.ConsumeQuotaN(k, 1)
+}
+
+// ConsumeQuotaN counts n requests against the key's daily quota, all or none of them. When
+// fewer than n remain it returns false, leaves the quota untouched and returns the time it resets.
+func (s *Store) ConsumeQuotaN(k *Key, n int) (bool, time.Time) {
+	now := s.now().UTC()
+	resetAt := nextUTCMidnight(now)
+	if k.DailyQuota <= 0 || n <= 0 {
+		return true, resetAt
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	day := now.Format(time.DateOnly)
+	u, ok := s.usage[k.ID]
+	if !ok || u.day != day {
+		u = &dailyUsage{day: day}
+		s.usage[k.ID] = u
+	}
+	if u.count+n > k.DailyQuota {
+		return false, resetAt
+	}
+	u.count += n
+	return true, resetAt
+}
+
+// Usage reports today's quota usage for every key
+func (s *Store) Usage() []Usage {
+	now := s.now().UTC()
+	day := now.Format(time.DateOnly)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	usage := make([]Usage, 0, len(s.keys))
+	for _, k := range s.keys {
+		u := Usage{KeyID: k.ID, Scopes: k.Scopes, DailyQuota: k.DailyQuota, ResetsAt: nextUTCMidnight(now)}
+		if d, ok := s.usage[k.ID]; ok && d.day == day {
+			u.Used = d.count
+		}
+		usage = append(usage, u)
+	}
+	return usage
+}
+
+func nextUTCMidnight(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d+1, 0, 0, 0, 0, time.UTC)
+}
+
+type contextKey struct{}
+
+// WithKey returns a copy of ctx carrying the authenticated key
+func WithKey(ctx context.Context, k *Key) context.Context {
+	return context.WithValue(ctx, contextKey{}, k)
+}
+
+// KeyFromContext returns the authenticated key stored by WithKey
+func KeyFromContext(ctx context.Context) (*Key, bool) {
+	k, ok := ctx.Value(contextKey{}).(*Key)
+	return k, ok
+}
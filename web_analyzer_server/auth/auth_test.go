@@ -0,0 +1,96 @@
+package auth
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadKeys(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "keys.json")
+	fileKeys := `[{"id":"ci","hash":"` + HashKey("ci-secret") + `","scopes":["analyze"],"daily_quota":100}]`
+	assert.NoError(t, os.WriteFile(path, []byte(fileKeys), 0o600))
+	envKeys := `[{"id":"ops","hash":"` + HashKey("ops-secret") + `","scopes":["admin"]}]`
+
+	keys, err := LoadKeys(path, envKeys)
+	assert.NoError(t, err)
+	assert.Len(t, keys, 2)
+	assert.Equal(t, "ci", keys[0].ID)
+	assert.Equal(t, 100, keys[0].DailyQuota)
+	assert.Equal(t, "ops", keys[1].ID)
+
+	keys, err = LoadKeys("", "")
+	assert.NoError(t, err)
+	assert.Empty(t, keys)
+
+	invalid := []string{
+		`[{"id":"bad","hash":"not-hex","scopes":["analyze"]}]`,
+		`[{"id":"bad","hash":"` + HashKey("x") + `","scopes":["root"]}]`,
+		`[{"id":"bad","hash":"` + HashKey("x") + `"}]`,
+		`[{"id":"dup","hash":"` + HashKey("x") + `","scopes":["analyze"]},{"id":"dup","hash":"` + HashKey("y") + `","scopes":["analyze"]}]`,
+	}
+	for _, inline := range invalid {
+		_, err := LoadKeys("", inline)
+		assert.Error(t, err, inline)
+	}
+}
+
+func TestStore_AuthenticateAndScopes(t *testing.T) {
+	store := NewStore([]Key{
+		{ID: "ci", Hash: HashKey("ci-secret"), Scopes: []Scope{ScopeAnalyze}},
+		{ID: "ops", Hash: HashKey("ops-secret"), Scopes: []Scope{ScopeAdmin}},
+	})
+
+	k, ok := store.Authenticate("ci-secret")
+	assert.True(t, ok)
+	assert.Equal(t, "ci", k.ID)
+	assert.True(t, k.HasScope(ScopeAnalyze))
+	assert.False(t, k.HasScope(ScopeAdmin))
+
+	k, ok = store.Authenticate("ops-secret")
+	assert.True(t, ok)
+	assert.True(t, k.HasScope(ScopeAnalyze), "admin implies every scope")
+
+	_, ok = store.Authenticate("wrong")
+	assert.False(t, ok)
+	_, ok = store.Authenticate("")
+	assert.False(t, ok)
+}
+
+func TestStore_DailyQuota(t *testing.T) {
+	now := time.Date(2025, 6, 1, 23, 0, 0, 0, time.UTC)
+	store := NewStore([]Key{{ID: "ci", Hash: HashKey("ci-secret"), Scopes: []Scope{ScopeAnalyze}, DailyQuota: 2}})
+	store.now = func() time.Time { return now }
+	k, _ := store.Authenticate("ci-secret")
+
+	ok, _ := store.ConsumeQuota(k)
+	assert.True(t, ok)
+	ok, _ = store.ConsumeQuota(k)
+	assert.True(t, ok)
+	ok, resetAt := store.ConsumeQuota(k)
+	assert.False(t, ok)
+	assert.Equal(t, time.Date(2025, 6, 2, 0, 0, 0, 0, time.UTC), resetAt)
+	assert.Equal(t, 2, store.Usage()[0].Used)
+
+	now = now.Add(time.Hour)
+	ok, _ = store.ConsumeQuota(k)
+	assert.True(t, ok, "quota resets at UTC midnight")
+}
+
+func TestStore_ConsumeQuotaN(t *testing.T) {
+	store := NewStore([]Key{{ID: "ci", Hash: HashKey("ci-secret"), Scopes: []Scope{ScopeAnalyze}, DailyQuota: 5}})
+	k, _ := store.Authenticate("ci-secret")
+
+	ok, _ := store.ConsumeQuotaN(k, 3)
+	assert.True(t, ok)
+	ok, _ = store.ConsumeQuotaN(k, 3)
+	assert.False(t, ok, "only 2 requests remain")
+	assert.Equal(t, 3, store.Usage()[0].Used, "a refused charge leaves the quota untouched")
+	ok, _ = store.ConsumeQuotaN(k, 2)
+	assert.True(t, ok)
+	assert.Equal(t, 5, store.Usage()[0].Used)
+}
@@ -0,0 +1,333 @@
+// Command peekalo analyzes web pages from the command line, crawls a site, analyzes HAR and WARC captures,
+// checks a static site build or runs the HTTP API.
+// It exits 1 when a page violates the configured thresholds and 2 on usage errors.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/sashithaf16/peekalo/analyzer"
+	"github.com/sashithaf16/peekalo/archive"
+	"github.com/sashithaf16/peekalo/config"
+	"github.com/sashithaf16/peekalo/crawl"
+	"github.com/sashithaf16/peekalo/hostpolicy"
+	"github.com/sashithaf16/peekalo/logger"
+	"github.com/sashithaf16/peekalo/robots"
+	"github.com/sashithaf16/peekalo/server"
+	"github.com/sashithaf16/peekalo/staticsite"
+	"github.com/sashithaf16/peekalo/urlnorm"
+)
+
+const (
+	exitOK         = 0
+	exitViolations = 1
+	exitUsage      = 2
+)
+
+const usage = `Usage: peekalo <command> [flags]
+
+Commands:
+  analyze   analyze one or more URLs
+  crawl     analyze a URL and the internal pages it links to
+  import    analyze the HTML responses captured in HAR or WARC files
+  site      analyze the HTML files of a static site build without network access
+  serve     run the HTTP API
+
+Run "peekalo <command> -h" to see the flags of a command.
+`
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	code := run(ctx, os.Args[1:], os.Stdout, os.Stderr)
+	stop()
+	os.Exit(code)
+}
+
+func run(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, usage)
+		return exitUsage
+	}
+	switch args[0] {
+	case "analyze":
+		return runAnalyze(ctx, args[1:], stdout, stderr)
+	case "crawl":
+		return runCrawl(ctx, args[1:], stdout, stderr)
+	case "import":
+		return runImport(ctx, args[1:], stdout, stderr)
+	case "site":
+		return runSite(ctx, args[1:], stdout, stderr)
+	case "serve":
+		return runServe(args[1:], stderr)
+	case "help", "-h", "-help", "--help":
+		fmt.Fprint(stdout, usage)
+		return exitOK
+	default:
+		fmt.Fprintf(stderr, "unknown command %q\n\n%s", args[0], usage)
+		return exitUsage
+	}
+}
+
+// analysisFlags are shared by the analyze and crawl commands
+type analysisFlags struct {
+	json       bool
+	userAgent  string
+	robots     string
+	timeout    time.Duration
+	logLevel   string
+	thresholds Thresholds
+}
+
+func (f *analysisFlags) register(fs *flag.FlagSet, cfg *config.Config) {
+	fs.BoolVar(&f.json, "json", false, "print JSON instead of a table")
+	fs.StringVar(&f.userAgent, "user-agent", cfg.UserAgent, "User-Agent sent on fetches")
+	fs.StringVar(&f.robots, "robots", cfg.RobotsPolicy, "robots.txt policy: enforce, warn or ignore")
+	fs.DurationVar(&f.timeout, "timeout", 30*time.Second, "timeout of each page fetch")
+	fs.StringVar(&f.logLevel, "log-level", "error", "log level of messages written to stderr")
+	f.thresholds.register(fs)
+}
+
+func (t *Thresholds) register(fs *flag.FlagSet) {
+	fs.IntVar(&t.MaxStatus, "max-status", 399, "fail pages that return a higher HTTP status")
+	fs.IntVar(&t.MaxBrokenLinks, "max-broken-links", -1, "fail pages with more inaccessible links, -1 disables the check")
+	fs.BoolVar(&t.RequireTitle, "require-title", false, "fail pages without a title")
+	fs.IntVar(&t.MaxPhishingScore, "max-phishing-score", -1, "fail pages with a higher phishing risk score, -1 disables the check")
+}
+
+// analyzer builds an analyzer from the flags, applying robots.txt the same way the server does
+func (f *analysisFlags) analyzer(cfg *config.Config, stderr io.Writer) (*analyzer.Analyzer, error) {
+	policy := robots.Policy(f.robots)
+	switch policy {
+	case robots.PolicyEnforce, robots.PolicyWarn, robots.PolicyIgnore:
+	default:
+		return nil, fmt.Errorf("unknown robots policy %q", f.robots)
+	}
+	cfg.UserAgent = f.userAgent
+	cfg.RobotsPolicy = f.robots
+
+	log := logger.CreateLoggerWithWriter(f.logLevel, stderr)
+	if cfg.AuthKeywordsFile != "" {
+		keywords, err := config.LoadAuthKeywords(cfg.AuthKeywordsFile)
+		if err != nil {
+			return nil, err
+		}
+		cfg.AuthKeywords = keywords
+	}
+	hosts, err := hostpolicy.New(cfg.AllowedHosts, cfg.DeniedHosts)
+	if err != nil {
+		return nil, fmt.Errorf("invalid host lists: %v", err)
+	}
+	client := &http.Client{Timeout: f.timeout}
+	if hosts.Enabled() {
+		// the CLI fetches the pages it is given, so every request and redirect hop goes through the policy
+		client = hosts.Client(client)
+	}
+	var opts []analyzer.Option
+	if policy.Enabled() {
+		opts = append(opts, analyzer.WithRobotsChecker(robots.NewChecker(log, client, cfg.UserAgent, time.Duration(cfg.RobotsCacheTTL)*time.Second)))
+	}
+	return analyzer.NewAnalyzer(log, cfg, client, opts...), nil
+}
+
+func runAnalyze(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	cfg := config.GetConfig()
+	fs := flag.NewFlagSet("analyze", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: peekalo analyze [flags] URL...")
+		fs.PrintDefaults()
+	}
+	var f analysisFlags
+	f.register(fs, cfg)
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return exitUsage
+	}
+	an, err := f.analyzer(cfg, stderr)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitUsage
+	}
+
+	reports := make([]pageReport, 0, fs.NArg())
+	for _, u := range fs.Args() {
+		info, err := an.AnalyzeURL(ctx, u)
+		reports = append(reports, newPageReport(crawl.Page{URL: u}, info, err, f.thresholds))
+	}
+	return finish(reports, f.json, false, stdout, stderr)
+}
+
+func runCrawl(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	cfg := config.GetConfig()
+	fs := flag.NewFlagSet("crawl", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: peekalo crawl [flags] URL")
+		fs.PrintDefaults()
+	}
+	var f analysisFlags
+	f.register(fs, cfg)
+	var opts crawl.Options
+	fs.IntVar(&opts.MaxPages, "max-pages", 50, "maximum number of pages analyzed")
+	fs.IntVar(&opts.MaxDepth, "depth", 2, "maximum number of link hops from the start page")
+	fs.IntVar(&opts.Concurrency, "concurrency", cfg.SitemapConcurrency, "pages analyzed in parallel")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return exitUsage
+	}
+	an, err := f.analyzer(cfg, stderr)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitUsage
+	}
+
+	opts.Normalizer = urlnorm.New(cfg.TrackingParams)
+	pages, err := crawl.Crawl(ctx, an, fs.Arg(0), opts)
+	if pages == nil && err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitUsage
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "crawl stopped early: %v\n", err)
+	}
+
+	reports := make([]pageReport, 0, len(pages))
+	for _, p := range pages {
+		var info analyzer.PageInfo
+		var pageErr error
+		if p.Info != nil {
+			info = *p.Info
+		} else {
+			pageErr = errors.New(p.Error)
+		}
+		reports = append(reports, newPageReport(p, info, pageErr, f.thresholds))
+	}
+	return finish(reports, f.json, true, stdout, stderr)
+}
+
+func runImport(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	cfg := config.GetConfig()
+	fs := flag.NewFlagSet("import", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: peekalo import [flags] FILE...")
+		fs.PrintDefaults()
+	}
+	var (
+		asJSON      bool
+		logLevel    string
+		concurrency int
+		t           Thresholds
+	)
+	fs.BoolVar(&asJSON, "json", false, "print JSON instead of a table")
+	fs.StringVar(&logLevel, "log-level", "error", "log level of messages written to stderr")
+	fs.IntVar(&concurrency, "concurrency", cfg.SitemapConcurrency, "responses analyzed in parallel")
+	t.register(fs)
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return exitUsage
+	}
+
+	an := analyzer.NewAnalyzer(logger.CreateLoggerWithWriter(logLevel, stderr), cfg, nil)
+	reports := []pageReport{}
+	for _, name := range fs.Args() {
+		records, err := readArchive(name)
+		if err != nil {
+			fmt.Fprintf(stderr, "%s: %v\n", name, err)
+			return exitUsage
+		}
+		for _, p := range archive.Analyze(ctx, an, records, concurrency) {
+			var info analyzer.PageInfo
+			var pageErr error
+			if p.Info != nil {
+				info = *p.Info
+			} else {
+				pageErr = errors.New(p.Error)
+			}
+			reports = append(reports, newPageReport(crawl.Page{URL: p.URL}, info, pageErr, t))
+		}
+	}
+	return finish(reports, asJSON, false, stdout, stderr)
+}
+
+func readArchive(name string) ([]archive.Record, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	records, _, err := archive.Read(f)
+	return records, err
+}
+
+func runSite(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	cfg := config.GetConfig()
+	fs := flag.NewFlagSet("site", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: peekalo site [flags] DIR")
+		fs.PrintDefaults()
+	}
+	var (
+		asJSON   bool
+		logLevel string
+		t        siteThresholds
+		opts     staticsite.Options
+	)
+	fs.BoolVar(&asJSON, "json", false, "print JSON instead of a table")
+	fs.StringVar(&logLevel, "log-level", "error", "log level of messages written to stderr")
+	fs.StringVar(&opts.BaseURL, "base-url", "http://localhost/", "URL the directory is served at")
+	fs.IntVar(&opts.Concurrency, "concurrency", cfg.SitemapConcurrency, "files analyzed in parallel")
+	fs.IntVar(&t.MaxBrokenLinks, "max-broken-links", 0, "fail pages with more broken internal links, -1 disables the check")
+	fs.BoolVar(&t.RequireTitle, "require-title", false, "fail pages without a title")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return exitUsage
+	}
+	if info, err := os.Stat(fs.Arg(0)); err != nil || !info.IsDir() {
+		fmt.Fprintf(stderr, "%s is not a directory\n", fs.Arg(0))
+		return exitUsage
+	}
+
+	an := analyzer.NewAnalyzer(logger.CreateLoggerWithWriter(logLevel, stderr), cfg, nil)
+	report, err := staticsite.Analyze(ctx, an, os.DirFS(fs.Arg(0)), opts)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return exitUsage
+	}
+	return finishSite(report, t, asJSON, stdout, stderr)
+}
+
+func runServe(args []string, stderr io.Writer) int {
+	cfg := config.GetConfig()
+	fs := flag.NewFlagSet("serve", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.StringVar(&cfg.ListenAddr, "addr", cfg.ListenAddr, "address the HTTP API listens on")
+	fs.StringVar(&cfg.LogLevel, "log-level", cfg.LogLevel, "log level")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	server.Run(cfg, logger.CreateLogger(cfg.LogLevel))
+	return exitOK
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sashithaf16/peekalo/analyzer"
+)
+
+func testSite(t *testing.T) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, `<!DOCTYPE html><html><head><title>Home</title></head><body>
+			<a href="/about">About</a><a href="/missing">Missing</a><a href="mailto:hi@example.com">Mail</a></body></html>`)
+	})
+	mux.HandleFunc("/about", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<!DOCTYPE html><html><head><title>About</title></head><body><a href="/">Home</a></body></html>`)
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestAnalyzeCommand(t *testing.T) {
+	site := testSite(t)
+
+	var stdout, stderr bytes.Buffer
+	code := run(context.Background(), []string{"analyze", "-robots", "ignore", "-require-title", site.URL}, &stdout, &stderr)
+	assert.Equal(t, exitOK, code, stderr.String())
+	assert.Contains(t, stdout.String(), "TITLE")
+	assert.Contains(t, stdout.String(), "Home")
+
+	stdout.Reset()
+	code = run(context.Background(), []string{"analyze", "-robots", "ignore", "-json", "-max-broken-links", "0", site.URL, site.URL + "/missing"}, &stdout, &stderr)
+	assert.Equal(t, exitViolations, code)
+
+	var reports []pageReport
+	require.NoError(t, json.Unmarshal(stdout.Bytes(), &reports))
+	require.Len(t, reports, 2)
+	assert.Equal(t, []string{"1 inaccessible links"}, reports[0].Violations)
+	assert.Equal(t, []string{"status 404"}, reports[1].Violations)
+}
+
+func TestCrawlCommand(t *testing.T) {
+	site := testSite(t)
+
+	var stdout, stderr bytes.Buffer
+	code := run(context.Background(), []string{"crawl", "-robots", "ignore", "-json", "-depth", "1", site.URL}, &stdout, &stderr)
+	assert.Equal(t, exitViolations, code, "the missing page returns 404")
+
+	var reports []pageReport
+	require.NoError(t, json.Unmarshal(stdout.Bytes(), &reports))
+	require.Len(t, reports, 3)
+	assert.Equal(t, site.URL+"/", reports[0].URL, "the start URL is crawled in its canonical form")
+	assert.Equal(t, 1, reports[1].Depth)
+}
+
+func TestImportCommand(t *testing.T) {
+	har := filepath.Join(t.TempDir(), "capture.har")
+	require.NoError(t, os.WriteFile(har, []byte(`{"log": {"entries": [
+		{"request": {"url": "https://example.com/"}, "response": {"status": 200, "headers": [], "content": {"mimeType": "text/html", "text": "<title>Home</title>"}}},
+		{"request": {"url": "https://example.com/error"}, "response": {"status": 500, "headers": [], "content": {"mimeType": "text/html", "text": "<title>Oops</title>"}}}
+	]}}`), 0o644))
+
+	var stdout, stderr bytes.Buffer
+	code := run(context.Background(), []string{"import", "-json", har}, &stdout, &stderr)
+	assert.Equal(t, exitViolations, code, stderr.String())
+
+	var reports []pageReport
+	require.NoError(t, json.Unmarshal(stdout.Bytes(), &reports))
+	require.Len(t, reports, 2)
+	assert.Empty(t, reports[0].Violations)
+	assert.Equal(t, []string{"status 500"}, reports[1].Violations)
+
+	assert.Equal(t, exitUsage, run(context.Background(), []string{"import", filepath.Join(t.TempDir(), "missing.har")}, &stdout, &stderr))
+}
+
+func TestSiteCommand(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "guide"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "index.html"), []byte(`<html><head><title>Home</title></head><body><a href="/guide/">Guide</a></body></html>`), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "guide", "index.html"), []byte(`<html><head><title>Guide</title></head><body><a href="/missing">Missing</a></body></html>`), 0o644))
+
+	var stdout, stderr bytes.Buffer
+	code := run(context.Background(), []string{"site", "-json", "-base-url", "https://docs.example.com", dir}, &stdout, &stderr)
+	assert.Equal(t, exitViolations, code, stderr.String())
+
+	var report siteReport
+	require.NoError(t, json.Unmarshal(stdout.Bytes(), &report))
+	require.Len(t, report.Pages, 2)
+	assert.Equal(t, "guide/index.html", report.Pages[0].Path)
+	assert.Equal(t, []string{"1 broken links"}, report.Pages[0].Violations)
+	assert.Empty(t, report.Pages[1].Violations)
+	assert.Equal(t, 1, report.Summary.BrokenLinks)
+
+	stdout.Reset()
+	code = run(context.Background(), []string{"site", "-max-broken-links", "-1", dir}, &stdout, &stderr)
+	assert.Equal(t, exitOK, code)
+	assert.Contains(t, stdout.String(), "2 pages, 0 failed, 1 broken links on 1 pages")
+
+	assert.Equal(t, exitUsage, run(context.Background(), []string{"site", filepath.Join(dir, "index.html")}, &stdout, &stderr))
+}
+
+func TestUsage(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	assert.Equal(t, exitUsage, run(context.Background(), nil, &stdout, &stderr))
+	assert.Equal(t, exitUsage, run(context.Background(), []string{"bogus"}, &stdout, &stderr))
+	assert.Equal(t, exitUsage, run(context.Background(), []string{"analyze"}, &stdout, &stderr))
+	assert.Equal(t, exitUsage, run(context.Background(), []string{"analyze", "-robots", "sometimes", "https://example.com"}, &stdout, &stderr))
+}
+
+func TestThresholds(t *testing.T) {
+	th := Thresholds{MaxStatus: 399, MaxBrokenLinks: 2, RequireTitle: true, MaxPhishingScore: 50}
+	assert.Empty(t, th.Check(analyzer.PageInfo{StatusCode: 200, Title: "Home"}, nil))
+	assert.Equal(t, []string{"status 500", "3 inaccessible links", "missing title", "phishing score 75"},
+		th.Check(analyzer.PageInfo{StatusCode: 500, Links: analyzer.LinkStats{Inaccessible: 3}, Phishing: analyzer.PhishingInfo{Score: 75}}, nil))
+	assert.Len(t, th.Check(analyzer.PageInfo{}, fmt.Errorf("timeout")), 1)
+}
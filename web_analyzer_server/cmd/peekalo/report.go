@@ -0,0 +1,227 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/sashithaf16/peekalo/analyzer"
+	"github.com/sashithaf16/peekalo/crawl"
+	"github.com/sashithaf16/peekalo/staticsite"
+)
+
+// maxTitleWidth truncates titles in the table so rows stay on one line
+const maxTitleWidth = 40
+
+// Thresholds are the checks that make the command exit non-zero
+type Thresholds struct {
+	MaxStatus        int  // highest acceptable HTTP status code
+	MaxBrokenLinks   int  // highest acceptable number of inaccessible links, negative disables the check
+	RequireTitle     bool // pages must have a non-empty title
+	MaxPhishingScore int  // highest acceptable phishing risk score, negative disables the check
+}
+
+// Check returns a description of every threshold the analysis violates. A failed analysis is always a violation.
+func (t Thresholds) Check(info analyzer.PageInfo, err error) []string {
+	if err != nil {
+		return []string{"analysis failed: " + err.Error()}
+	}
+	var violations []string
+	if info.StatusCode > t.MaxStatus {
+		violations = append(violations, fmt.Sprintf("status %d", info.StatusCode))
+	}
+	if t.MaxBrokenLinks >= 0 && info.Links.Inaccessible > t.MaxBrokenLinks {
+		violations = append(violations, fmt.Sprintf("%d inaccessible links", info.Links.Inaccessible))
+	}
+	if t.RequireTitle && strings.TrimSpace(info.Title) == "" {
+		violations = append(violations, "missing title")
+	}
+	if t.MaxPhishingScore >= 0 && info.Phishing.Score > t.MaxPhishingScore {
+		violations = append(violations, fmt.Sprintf("phishing score %d", info.Phishing.Score))
+	}
+	return violations
+}
+
+// pageReport is one output row
+type pageReport struct {
+	URL        string             `json:"url"`
+	Depth      int                `json:"depth"`
+	Info       *analyzer.PageInfo `json:"page_info,omitempty"`
+	Error      string             `json:"error,omitempty"`
+	Violations []string           `json:"violations,omitempty"`
+}
+
+func newPageReport(page crawl.Page, info analyzer.PageInfo, err error, t Thresholds) pageReport {
+	r := pageReport{URL: page.URL, Depth: page.Depth, Violations: t.Check(info, err)}
+	if err != nil {
+		r.Error = err.Error()
+	} else {
+		r.Info = &info
+	}
+	return r
+}
+
+// finish prints the reports and returns the exit code
+func finish(reports []pageReport, asJSON, withDepth bool, stdout, stderr io.Writer) int {
+	var err error
+	if asJSON {
+		enc := json.NewEncoder(stdout)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(reports)
+	} else {
+		err = writeTable(stdout, reports, withDepth)
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "failed to write output: %v\n", err)
+		return exitUsage
+	}
+
+	failed := 0
+	for _, r := range reports {
+		if len(r.Violations) > 0 {
+			failed++
+		}
+	}
+	if failed > 0 {
+		fmt.Fprintf(stderr, "%d of %d pages violate the thresholds\n", failed, len(reports))
+		return exitViolations
+	}
+	return exitOK
+}
+
+func writeTable(w io.Writer, reports []pageReport, withDepth bool) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	header := "URL\tSTATUS\tTITLE\tINTERNAL\tEXTERNAL\tINACCESSIBLE\tLOGIN\tRESULT"
+	if withDepth {
+		header = "DEPTH\t" + header
+	}
+	fmt.Fprintln(tw, header)
+
+	for _, r := range reports {
+		status, title, internal, external, inaccessible, login := "-", "-", "-", "-", "-", "-"
+		if r.Info != nil {
+			status = strconv.Itoa(r.Info.StatusCode)
+			title = truncate(r.Info.Title, maxTitleWidth)
+			internal = strconv.Itoa(r.Info.Links.Internal)
+			external = strconv.Itoa(r.Info.Links.External)
+			inaccessible = strconv.Itoa(r.Info.Links.Inaccessible)
+			login = strconv.FormatBool(r.Info.HasLogin)
+		}
+		result := "ok"
+		if len(r.Violations) > 0 {
+			result = "FAIL: " + strings.Join(r.Violations, "; ")
+		}
+		row := strings.Join([]string{r.URL, status, title, internal, external, inaccessible, login, result}, "\t")
+		if withDepth {
+			row = strconv.Itoa(r.Depth) + "\t" + row
+		}
+		fmt.Fprintln(tw, row)
+	}
+	return tw.Flush()
+}
+
+// siteThresholds are the checks of the site command. Files have no HTTP status and their
+// links are checked against the directory, so broken links replace inaccessible ones.
+type siteThresholds struct {
+	MaxBrokenLinks int  // highest acceptable number of broken internal links, negative disables the check
+	RequireTitle   bool // pages must have a non-empty title
+}
+
+func (t siteThresholds) Check(p staticsite.Page) []string {
+	if p.Info == nil {
+		return []string{"analysis failed: " + p.Error}
+	}
+	var violations []string
+	if t.MaxBrokenLinks >= 0 && len(p.BrokenLinks) > t.MaxBrokenLinks {
+		violations = append(violations, fmt.Sprintf("%d broken links", len(p.BrokenLinks)))
+	}
+	if t.RequireTitle && strings.TrimSpace(p.Info.Title) == "" {
+		violations = append(violations, "missing title")
+	}
+	return violations
+}
+
+// sitePageReport is one file of the site command output
+type sitePageReport struct {
+	staticsite.Page
+	Violations []string `json:"violations,omitempty"`
+}
+
+type siteReport struct {
+	BaseURL string             `json:"base_url"`
+	Pages   []sitePageReport   `json:"pages"`
+	Summary staticsite.Summary `json:"summary"`
+}
+
+// finishSite prints the site report and returns the exit code
+func finishSite(report *staticsite.Report, t siteThresholds, asJSON bool, stdout, stderr io.Writer) int {
+	out := siteReport{BaseURL: report.BaseURL, Pages: make([]sitePageReport, 0, len(report.Pages)), Summary: report.Summary}
+	failed := 0
+	for _, p := range report.Pages {
+		r := sitePageReport{Page: p, Violations: t.Check(p)}
+		if len(r.Violations) > 0 {
+			failed++
+		}
+		out.Pages = append(out.Pages, r)
+	}
+
+	var err error
+	if asJSON {
+		enc := json.NewEncoder(stdout)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(out)
+	} else {
+		err = writeSiteTable(stdout, out)
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "failed to write output: %v\n", err)
+		return exitUsage
+	}
+
+	if failed > 0 {
+		fmt.Fprintf(stderr, "%d of %d pages violate the thresholds\n", failed, len(out.Pages))
+		return exitViolations
+	}
+	return exitOK
+}
+
+func writeSiteTable(w io.Writer, report siteReport) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "PATH\tTITLE\tINTERNAL\tEXTERNAL\tBROKEN\tLOGIN\tRESULT")
+	for _, r := range report.Pages {
+		title, internal, external, login := "-", "-", "-", "-"
+		if r.Info != nil {
+			title = truncate(r.Info.Title, maxTitleWidth)
+			internal = strconv.Itoa(r.Info.Links.Internal)
+			external = strconv.Itoa(r.Info.Links.External)
+			login = strconv.FormatBool(r.Info.HasLogin)
+		}
+		result := "ok"
+		if len(r.Violations) > 0 {
+			result = "FAIL: " + strings.Join(r.Violations, "; ")
+		}
+		fmt.Fprintln(tw, strings.Join([]string{r.Path, title, internal, external, strconv.Itoa(len(r.BrokenLinks)), login, result}, "\t"))
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	s := report.Summary
+	_, err := fmt.Fprintf(w, "\n%d pages, %d failed, %d broken links on %d pages, %d missing titles, %d external links\n",
+		s.Pages, s.Failed, s.BrokenLinks, s.PagesWithBrokenLinks, s.MissingTitles, s.ExternalLinks)
+	if err == nil && len(s.Orphans) > 0 {
+		_, err = fmt.Fprintf(w, "orphan pages: %s\n", strings.Join(s.Orphans, ", "))
+	}
+	return err
+}
+
+func truncate(s string, n int) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if r := []rune(s); len(r) > n {
+		return string(r[:n-1]) + "…"
+	}
+	return s
+}
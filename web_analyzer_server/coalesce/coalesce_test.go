@@ -0,0 +1,102 @@
+package coalesce
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// waitForWaiters blocks until n callers wait on the execution for key
+func waitForWaiters[T any](t *testing.T, g *Group[T], key string, n int) {
+	require.Eventually(t, func() bool {
+		g.mu.Lock()
+		defer g.mu.Unlock()
+		c, ok := g.calls[key]
+		return ok && c.waiters == n
+	}, time.Second, time.Millisecond)
+}
+
+func TestGroupSharesExecution(t *testing.T) {
+	var g Group[string]
+	var runs atomic.Int32
+	release := make(chan struct{})
+	fn := func(ctx context.Context) (string, error) {
+		runs.Add(1)
+		<-release
+		return "result", nil
+	}
+
+	const callers = 5
+	var wg sync.WaitGroup
+	var sharedCount atomic.Int32
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			v, shared, err := g.Do(context.Background(), "https://example.com/", fn)
+			assert.NoError(t, err)
+			assert.Equal(t, "result", v)
+			if shared {
+				sharedCount.Add(1)
+			}
+		}()
+	}
+	waitForWaiters(t, &g, "https://example.com/", callers)
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), runs.Load())
+	assert.Equal(t, int32(callers-1), sharedCount.Load())
+
+	// a finished execution is not reused
+	_, shared, err := g.Do(context.Background(), "https://example.com/", func(ctx context.Context) (string, error) {
+		return "again", errors.New("failed")
+	})
+	assert.False(t, shared)
+	assert.EqualError(t, err, "failed")
+}
+
+func TestGroupCancellation(t *testing.T) {
+	var g Group[string]
+	started := make(chan context.Context, 2)
+	fn := func(ctx context.Context) (string, error) {
+		started <- ctx
+		<-ctx.Done()
+		return "", ctx.Err()
+	}
+
+	firstCtx, cancelFirst := context.WithCancel(context.Background())
+	secondCtx, cancelSecond := context.WithCancel(context.Background())
+	errs := make(chan error, 2)
+	go func() {
+		_, _, err := g.Do(firstCtx, "key", fn)
+		errs <- err
+	}()
+	execCtx := <-started
+	go func() {
+		_, _, err := g.Do(secondCtx, "key", fn)
+		errs <- err
+	}()
+	waitForWaiters(t, &g, "key", 2)
+
+	// the first caller leaving does not cancel the execution the second one waits for
+	cancelFirst()
+	assert.ErrorIs(t, <-errs, context.Canceled)
+	assert.NoError(t, execCtx.Err())
+
+	cancelSecond()
+	assert.ErrorIs(t, <-errs, context.Canceled)
+	require.Eventually(t, func() bool { return execCtx.Err() != nil }, time.Second, time.Millisecond)
+
+	// the next caller starts a fresh execution
+	v, shared, err := g.Do(context.Background(), "key", func(ctx context.Context) (string, error) { return "fresh", nil })
+	assert.NoError(t, err)
+	assert.False(t, shared)
+	assert.Equal(t, "fresh", v)
+}
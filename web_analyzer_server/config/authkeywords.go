@@ -0,0 +1,92 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// AuthKeywords are the phrases that mark authentication surfaces in one language. Matching is
+// case-insensitive on the visible text, button values and labels of a page.
+type AuthKeywords struct {
+	Login     []string `json:"login"`      // e.g. "log in", "sign in"
+	Signup    []string `json:"signup"`     // e.g. "sign up", "create account"
+	SSO       []string `json:"sso"`        // phrases introducing an identity provider, e.g. "continue with"
+	MagicLink []string `json:"magic_link"` // e.g. "email me a link"
+	Passkey   []string `json:"passkey"`    // e.g. "passkey"
+}
+
+// DefaultAuthKeywords are used for languages without configured keywords, keyed by ISO 639-1 code
+var DefaultAuthKeywords = map[string]AuthKeywords{
+	"en": {
+		Login:     []string{"log in", "login", "sign in", "signin", "log on"},
+		Signup:    []string{"sign up", "signup", "register", "create account", "create an account", "join now"},
+		SSO:       []string{"continue with", "sign in with", "log in with", "login with", "sign up with", "single sign-on", "sso"},
+		MagicLink: []string{"magic link", "email me a link", "send me a link", "sign-in link", "login link", "email a sign-in link"},
+		Passkey:   []string{"passkey", "security key", "webauthn"},
+	},
+	"de": {
+		Login:     []string{"anmelden", "einloggen", "login"},
+		Signup:    []string{"registrieren", "konto erstellen", "jetzt registrieren"},
+		SSO:       []string{"weiter mit", "anmelden mit", "fortfahren mit"},
+		MagicLink: []string{"anmeldelink", "link per e-mail", "magischer link"},
+		Passkey:   []string{"passkey", "sicherheitsschlüssel"},
+	},
+	"fr": {
+		Login:     []string{"se connecter", "connexion", "s'identifier"},
+		Signup:    []string{"s'inscrire", "inscription", "créer un compte"},
+		SSO:       []string{"continuer avec", "se connecter avec", "connexion avec"},
+		MagicLink: []string{"lien magique", "lien de connexion", "recevoir un lien"},
+		Passkey:   []string{"clé d'accès", "passkey", "clé de sécurité"},
+	},
+	"es": {
+		Login:     []string{"iniciar sesión", "acceder", "ingresar", "entrar"},
+		Signup:    []string{"registrarse", "regístrate", "crear cuenta", "crear una cuenta"},
+		SSO:       []string{"continuar con", "iniciar sesión con", "acceder con"},
+		MagicLink: []string{"enlace mágico", "enlace de acceso", "enviarme un enlace"},
+		Passkey:   []string{"llave de acceso", "passkey", "clave de seguridad"},
+	},
+	"pt": {
+		Login:     []string{"entrar", "iniciar sessão", "fazer login"},
+		Signup:    []string{"cadastre-se", "criar conta", "registar", "inscrever-se"},
+		SSO:       []string{"continuar com", "entrar com", "fazer login com"},
+		MagicLink: []string{"link mágico", "link de acesso", "enviar um link"},
+		Passkey:   []string{"chave de acesso", "passkey", "chave de segurança"},
+	},
+	"nl": {
+		Login:     []string{"inloggen", "aanmelden"},
+		Signup:    []string{"registreren", "account aanmaken"},
+		SSO:       []string{"doorgaan met", "inloggen met", "aanmelden met"},
+		MagicLink: []string{"magische link", "inloglink", "stuur me een link"},
+		Passkey:   []string{"toegangssleutel", "passkey", "beveiligingssleutel"},
+	},
+	"it": {
+		Login:     []string{"accedi", "accesso", "entra"},
+		Signup:    []string{"registrati", "crea account", "crea un account"},
+		SSO:       []string{"continua con", "accedi con"},
+		MagicLink: []string{"link magico", "link di accesso", "inviami un link"},
+		Passkey:   []string{"passkey", "chiave di sicurezza"},
+	},
+}
+
+// LoadAuthKeywords reads a JSON object of keyword lists keyed by language from path. Languages in
+// the file replace the defaults for that language; the other defaults are kept.
+func LoadAuthKeywords(path string) (map[string]AuthKeywords, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read auth keywords file: %v", err)
+	}
+	var custom map[string]AuthKeywords
+	if err := json.Unmarshal(data, &custom); err != nil {
+		return nil, fmt.Errorf("failed to parse auth keywords file: %v", err)
+	}
+	keywords := make(map[string]AuthKeywords, len(DefaultAuthKeywords)+len(custom))
+	for lang, kw := range DefaultAuthKeywords {
+		keywords[lang] = kw
+	}
+	for lang, kw := range custom {
+		keywords[strings.ToLower(lang)] = kw
+	}
+	return keywords, nil
+}
@@ -1,13 +1,253 @@
 package config
 
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/sashithaf16/peekalo/hostpolicy"
+	"github.com/sashithaf16/peekalo/urlnorm"
+	"github.com/sashithaf16/peekalo/webhook"
+)
+
 type Config struct {
-	LogLevel string // Log level for the application (e.g., "debug", "info", "warn", "error")
-	CacheTTL int    // Cache TTL in seconds
+	ListenAddr     string // Address the HTTP server listens on
+	LogLevel       string // Log level for the application (e.g., "debug", "info", "warn", "error")
+	CacheTTL       int    // Stored analyses younger than this many seconds are reused without fetching, 0 always revalidates
+	UserAgent      string // User-Agent sent on outbound fetches and matched against robots.txt groups
+	RobotsPolicy   string // How robots.txt is applied to fetches ("enforce", "warn" or "ignore")
+	RobotsCacheTTL int    // How long a fetched robots.txt is cached per host, in seconds
+
+	TrackingParams []string // Query parameters stripped when URLs are normalized; a trailing "*" matches a prefix
+
+	AuthKeywords     map[string]AuthKeywords // Login detection keywords keyed by language; empty uses DefaultAuthKeywords
+	AuthKeywordsFile string                  // JSON file of keywords per language replacing the defaults of those languages
+
+	AllowedHosts []string // Hosts, "*.domain" wildcards or CIDRs that may be analyzed; empty allows every host not denied
+	DeniedHosts  []string // Hosts, "*.domain" wildcards or CIDRs that are never analyzed, taking precedence over AllowedHosts
+
+	WebhookDeniedHosts []string // Hosts, "*.domain" wildcards or CIDRs that are never sent webhooks, in addition to DeniedHosts
+
+	SitemapConcurrency int // Maximum number of sitemap URLs analyzed in parallel
+	SitemapMaxURLs     int // Maximum number of URLs analyzed from a single sitemap
+
+	RateLimitRPS   float64 // Sustained requests per second allowed per client, 0 disables rate limiting
+	RateLimitBurst int     // Requests a client may make in a burst above the sustained rate
+	MaxInFlight    int     // Maximum concurrent analysis requests across all clients, 0 disables the cap
+
+	APIKeysFile string // Path to a JSON file of hashed API keys
+	APIKeys     string // JSON array of hashed API keys, merged with the file; no keys disables authentication
+
+	CORS CORSConfig
+
+	TracingExporter    string  // Where spans are exported ("none", "stdout" or "otlp")
+	OTLPEndpoint       string  // OTLP/HTTP collector URL, e.g. http://otel-collector:4318
+	TracingSampleRatio float64 // Fraction of new traces sampled; incoming sampled parents are always kept
+
+	ShutdownDrainDelay int // Seconds readiness reports unavailable before the server stops accepting requests
+
+	ResultStore    string // Where analysis results are kept ("file" or "memory")
+	ResultStoreDir string // Directory of the file result store
+
+	SnapshotStore    string // Where raw fetched pages are kept ("file", "memory" or "none")
+	SnapshotStoreDir string // Directory of the file snapshot store
+
+	MonitorsFile       string // JSON file monitors are saved to, empty keeps them in memory
+	MonitorMinInterval int    // Shortest interval a monitor may be scheduled at, in seconds
+
+	WebhookMaxAttempts int // Deliveries are retried with exponential backoff up to this many attempts
+	WebhookTimeout     int // Timeout of a single webhook delivery attempt, in seconds
+}
+
+type CORSConfig struct {
+	AllowedOrigins   []string // Exact origins, or patterns with a single "*" such as "https://*.example.com"
+	AllowedMethods   []string
+	AllowedHeaders   []string
+	AllowCredentials bool
+	MaxAge           int // Seconds browsers may cache a preflight response
 }
 
 func GetConfig() *Config {
 	return &Config{
-		LogLevel: "debug",
-		CacheTTL: 60,
+		ListenAddr:     getEnv("PEEKALO_LISTEN_ADDR", ":8080"),
+		LogLevel:       "debug",
+		CacheTTL:       getEnvInt("PEEKALO_CACHE_TTL", 60),
+		UserAgent:      getEnv("PEEKALO_USER_AGENT", "Peekalo/1.0"),
+		RobotsPolicy:   getEnv("PEEKALO_ROBOTS_POLICY", "warn"),
+		RobotsCacheTTL: 3600,
+
+		TrackingParams: getEnvList("PEEKALO_TRACKING_PARAMS", urlnorm.DefaultTrackingParams),
+
+		AuthKeywords:     DefaultAuthKeywords,
+		AuthKeywordsFile: getEnv("PEEKALO_AUTH_KEYWORDS_FILE", ""),
+
+		AllowedHosts: getEnvList("PEEKALO_ALLOWED_HOSTS", nil),
+		DeniedHosts:  getEnvList("PEEKALO_DENIED_HOSTS", nil),
+
+		WebhookDeniedHosts: getEnvList("PEEKALO_WEBHOOK_DENIED_HOSTS", webhook.DefaultDeniedHosts),
+
+		SitemapConcurrency: 4,
+		SitemapMaxURLs:     500,
+
+		RateLimitRPS:   1,
+		RateLimitBurst: 5,
+		MaxInFlight:    20,
+
+		APIKeysFile: getEnv("PEEKALO_API_KEYS_FILE", ""),
+		APIKeys:     getEnv("PEEKALO_API_KEYS", ""),
+
+		CORS: CORSConfig{
+			// only the bundled client is allowed by default
+			AllowedOrigins:   getEnvList("PEEKALO_CORS_ALLOWED_ORIGINS", []string{"http://localhost:5000"}),
+			AllowedMethods:   getEnvList("PEEKALO_CORS_ALLOWED_METHODS", []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"}),
+			AllowedHeaders:   getEnvList("PEEKALO_CORS_ALLOWED_HEADERS", []string{"Accept", "Authorization", "Content-Type", "X-API-Key", "X-Request-ID", "traceparent", "tracestate"}),
+			AllowCredentials: getEnvBool("PEEKALO_CORS_ALLOW_CREDENTIALS", false),
+			MaxAge:           getEnvInt("PEEKALO_CORS_MAX_AGE", 300), // Maximum value not ignored by any of major browsers
+		},
+
+		TracingExporter:    getEnv("PEEKALO_TRACING_EXPORTER", "none"),
+		OTLPEndpoint:       getEnv("PEEKALO_OTLP_ENDPOINT", ""),
+		TracingSampleRatio: getEnvFloat("PEEKALO_TRACING_SAMPLE_RATIO", 1),
+
+		ShutdownDrainDelay: getEnvInt("PEEKALO_SHUTDOWN_DRAIN_DELAY", 5),
+
+		ResultStore:    getEnv("PEEKALO_RESULT_STORE", "file"),
+		ResultStoreDir: getEnv("PEEKALO_RESULT_STORE_DIR", "data/results"),
+
+		SnapshotStore:    getEnv("PEEKALO_SNAPSHOT_STORE", "file"),
+		SnapshotStoreDir: getEnv("PEEKALO_SNAPSHOT_STORE_DIR", "data/snapshots"),
+
+		MonitorsFile:       getEnv("PEEKALO_MONITORS_FILE", "data/monitors.json"),
+		MonitorMinInterval: getEnvInt("PEEKALO_MONITOR_MIN_INTERVAL", 60),
+
+		WebhookMaxAttempts: getEnvInt("PEEKALO_WEBHOOK_MAX_ATTEMPTS", 5),
+		WebhookTimeout:     getEnvInt("PEEKALO_WEBHOOK_TIMEOUT", 10),
 	}
 }
+
+// Validate reports configuration mistakes that should stop the server from starting
+func (c *Config) Validate() error {
+	var errs []error
+	if err := c.CORS.validate(); err != nil {
+		errs = append(errs, err)
+	}
+	switch c.TracingExporter {
+	case "", "none", "stdout", "otlp":
+	default:
+		errs = append(errs, fmt.Errorf("tracing: unknown exporter %q", c.TracingExporter))
+	}
+	switch c.ResultStore {
+	case "file", "memory":
+	default:
+		errs = append(errs, fmt.Errorf("storage: unknown result store %q", c.ResultStore))
+	}
+	switch c.SnapshotStore {
+	case "", "none", "file", "memory":
+	default:
+		errs = append(errs, fmt.Errorf("storage: unknown snapshot store %q", c.SnapshotStore))
+	}
+	if _, err := hostpolicy.New(c.AllowedHosts, c.DeniedHosts); err != nil {
+		errs = append(errs, fmt.Errorf("hosts: %v", err))
+	}
+	if _, err := c.WebhookHosts(); err != nil {
+		errs = append(errs, fmt.Errorf("webhook hosts: %v", err))
+	}
+	if c.TracingSampleRatio < 0 || c.TracingSampleRatio > 1 {
+		errs = append(errs, errors.New("tracing: sample ratio must be between 0 and 1"))
+	}
+	return errors.Join(errs...)
+}
+
+func (c *CORSConfig) validate() error {
+	var errs []error
+	if len(c.AllowedOrigins) == 0 {
+		errs = append(errs, errors.New("cors: at least one allowed origin is required"))
+	}
+	for _, origin := range c.AllowedOrigins {
+		if origin == "*" {
+			// browsers reject credentialed responses for a wildcard origin
+			if c.AllowCredentials {
+				errs = append(errs, errors.New("cors: wildcard origin \"*\" cannot be combined with allow credentials"))
+			}
+			continue
+		}
+		if strings.Count(origin, "*") > 1 {
+			errs = append(errs, fmt.Errorf("cors: origin %q may contain at most one wildcard", origin))
+			continue
+		}
+		u, err := url.Parse(strings.Replace(origin, "*", "wildcard", 1))
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || (u.Path != "" && u.Path != "/") {
+			errs = append(errs, fmt.Errorf("cors: origin %q must be a scheme and host such as https://example.com", origin))
+			continue
+		}
+		if c.AllowCredentials && strings.Contains(origin, "*") {
+			errs = append(errs, fmt.Errorf("cors: wildcard origin %q cannot be combined with allow credentials", origin))
+		}
+	}
+	for _, method := range c.AllowedMethods {
+		switch strings.ToUpper(method) {
+		case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete, http.MethodOptions:
+		default:
+			errs = append(errs, fmt.Errorf("cors: unknown method %q", method))
+		}
+	}
+	if c.MaxAge < 0 {
+		errs = append(errs, errors.New("cors: max age cannot be negative"))
+	}
+	return errors.Join(errs...)
+}
+
+// getEnv returns the value of the environment variable key, or fallback when it is unset or empty
+func getEnv(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
+
+// getEnvList splits a comma separated environment variable, or returns fallback when it is unset or empty
+func getEnvList(key string, fallback []string) []string {
+	v := os.Getenv(key)
+	if v == "" {
+		return fallback
+	}
+	var list []string
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+func getEnvBool(key string, fallback bool) bool {
+	if b, err := strconv.ParseBool(os.Getenv(key)); err == nil {
+		return b
+	}
+	return fallback
+}
+
+func getEnvFloat(key string, fallback float64) float64 {
+	if f, err := strconv.ParseFloat(os.Getenv(key), 64); err == nil {
+		return f
+	}
+	return fallback
+}
+
+func getEnvInt(key string, fallback int) int {
+	if i, err := strconv.Atoi(os.Getenv(key)); err == nil {
+		return i
+	}
+	return fallback
+}
+
+// WebhookHosts returns the policy webhook targets are checked against. The allow list of analysis
+// targets does not apply, since receivers are usually on other hosts than the analyzed sites.
+func (c *Config) WebhookHosts() (*hostpolicy.Policy, error) {
+	denied := append(append([]string{}, c.DeniedHosts...), c.WebhookDeniedHosts...)
+	return hostpolicy.New(nil, denied)
+}
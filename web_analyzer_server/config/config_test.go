@@ -0,0 +1,79 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetConfig_DefaultsAreValid(t *testing.T) {
+	cfg := GetConfig()
+	assert.NoError(t, cfg.Validate())
+	assert.Equal(t, []string{"http://localhost:5000"}, cfg.CORS.AllowedOrigins)
+}
+
+func TestGetConfig_CORSFromEnv(t *testing.T) {
+	t.Setenv("PEEKALO_CORS_ALLOWED_ORIGINS", "https://peekalo.example.com, https://*.example.org")
+	t.Setenv("PEEKALO_CORS_ALLOW_CREDENTIALS", "true")
+
+	cfg := GetConfig()
+	assert.Equal(t, []string{"https://peekalo.example.com", "https://*.example.org"}, cfg.CORS.AllowedOrigins)
+	assert.True(t, cfg.CORS.AllowCredentials)
+	assert.Error(t, cfg.Validate(), "wildcard subdomain with credentials is rejected")
+}
+
+func TestGetConfig_HostsFromEnv(t *testing.T) {
+	t.Setenv("PEEKALO_ALLOWED_HOSTS", "example.com, *.example.com,10.0.0.0/8")
+	t.Setenv("PEEKALO_DENIED_HOSTS", "admin.example.com")
+
+	cfg := GetConfig()
+	assert.Equal(t, []string{"example.com", "*.example.com", "10.0.0.0/8"}, cfg.AllowedHosts)
+	assert.Equal(t, []string{"admin.example.com"}, cfg.DeniedHosts)
+	assert.NoError(t, cfg.Validate())
+
+	cfg.DeniedHosts = []string{"10.0.0.0/40"}
+	assert.ErrorContains(t, cfg.Validate(), "hosts: invalid CIDR")
+}
+
+func TestCORSConfig_Validate(t *testing.T) {
+	valid := CORSConfig{
+		AllowedOrigins: []string{"https://peekalo.example.com", "https://*.example.com", "http://localhost:5000"},
+		AllowedMethods: []string{"GET", "post"},
+		MaxAge:         300,
+	}
+	assert.NoError(t, valid.validate())
+
+	tests := map[string]CORSConfig{
+		"no origins":                {},
+		"wildcard with credentials": {AllowedOrigins: []string{"*"}, AllowCredentials: true},
+		"origin with path":          {AllowedOrigins: []string{"https://example.com/app"}},
+		"origin without scheme":     {AllowedOrigins: []string{"example.com"}},
+		"two wildcards":             {AllowedOrigins: []string{"https://*.*.example.com"}},
+		"unknown method":            {AllowedOrigins: []string{"https://example.com"}, AllowedMethods: []string{"FETCH"}},
+		"negative max age":          {AllowedOrigins: []string{"https://example.com"}, MaxAge: -1},
+	}
+	for name, cors := range tests {
+		assert.Error(t, cors.validate(), name)
+	}
+
+	wildcard := CORSConfig{AllowedOrigins: []string{"*"}}
+	assert.NoError(t, wildcard.validate(), "wildcard without credentials is allowed")
+}
+
+func TestLoadAuthKeywords(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keywords.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"SV": {"login": ["logga in"]}, "de": {"login": ["einloggen"]}}`), 0o600))
+
+	keywords, err := LoadAuthKeywords(path)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"logga in"}, keywords["sv"].Login)
+	assert.Equal(t, []string{"einloggen"}, keywords["de"].Login, "a configured language replaces its defaults")
+	assert.Equal(t, DefaultAuthKeywords["en"], keywords["en"])
+
+	require.NoError(t, os.WriteFile(path, []byte(`{"sv": ["logga in"]}`), 0o600))
+	_, err = LoadAuthKeywords(path)
+	assert.ErrorContains(t, err, "failed to parse auth keywords file")
+}
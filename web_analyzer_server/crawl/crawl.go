@@ -0,0 +1,133 @@
+package crawl
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/sashithaf16/peekalo/analyzer"
+	"github.com/sashithaf16/peekalo/urlnorm"
+)
+
+// PageAnalyzer analyzes a single page
+type PageAnalyzer interface {
+	AnalyzeURL(ctx context.Context, pageURL string) (analyzer.PageInfo, error)
+}
+
+// Options bounds a crawl. A non-positive MaxPages or Concurrency, or a negative MaxDepth, falls back to the defaults below.
+type Options struct {
+	MaxPages    int // pages analyzed in total, including the start page
+	MaxDepth    int // link hops followed from the start page
+	Concurrency int // pages analyzed in parallel
+	// Normalizer canonicalizes the start URL and discovered links so that spellings of the same
+	// page are crawled once; without one only fragments are dropped
+	Normalizer *urlnorm.Normalizer
+}
+
+const (
+	defaultMaxPages    = 50
+	defaultMaxDepth    = 2
+	defaultConcurrency = 4
+)
+
+// Page is one crawled page. Info is nil when the analysis failed.
+type Page struct {
+	URL   string             `json:"url"`
+	Depth int                `json:"depth"`
+	Info  *analyzer.PageInfo `json:"page_info,omitempty"`
+	Error string             `json:"error,omitempty"`
+}
+
+// Crawl analyzes startURL and follows its internal links breadth-first, staying on the start host.
+// Pages are returned in crawl order: by depth, then in the order they were discovered.
+func Crawl(ctx context.Context, pa PageAnalyzer, startURL string, opts Options) ([]Page, error) {
+	start, err := url.Parse(startURL)
+	if err != nil || (start.Scheme != "http" && start.Scheme != "https") || start.Host == "" {
+		return nil, fmt.Errorf("invalid start URL: %s", startURL)
+	}
+	if opts.MaxPages <= 0 {
+		opts.MaxPages = defaultMaxPages
+	}
+	if opts.MaxDepth < 0 {
+		opts.MaxDepth = defaultMaxDepth
+	}
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = defaultConcurrency
+	}
+
+	start = canonical(start, opts.Normalizer)
+	seen := map[string]bool{start.String(): true}
+	level := []string{start.String()}
+	var pages []Page
+
+	for depth := 0; len(level) > 0 && ctx.Err() == nil; depth++ {
+		if remaining := opts.MaxPages - len(pages); len(level) > remaining {
+			level = level[:remaining]
+		}
+		results := analyzeLevel(ctx, pa, level, depth, opts.Concurrency)
+		pages = append(pages, results...)
+		if depth >= opts.MaxDepth || len(pages) >= opts.MaxPages {
+			break
+		}
+
+		var next []string
+		for _, p := range results {
+			if p.Info == nil {
+				continue
+			}
+			for _, link := range p.Info.Links.InternalLinks {
+				u, err := url.Parse(link)
+				if err != nil {
+					continue
+				}
+				u = canonical(u, opts.Normalizer)
+				link = u.String()
+				if !strings.EqualFold(u.Host, start.Host) || seen[link] {
+					continue
+				}
+				seen[link] = true
+				next = append(next, link)
+			}
+		}
+		level = next
+	}
+	return pages, ctx.Err()
+}
+
+// canonical returns the form of u used to recognize pages already crawled
+func canonical(u *url.URL, norm *urlnorm.Normalizer) *url.URL {
+	if norm != nil {
+		c, _ := norm.NormalizeURL(u)
+		return c
+	}
+	c := *u
+	c.Fragment = ""
+	return &c
+}
+
+// analyzeLevel analyzes urls with at most concurrency analyses in flight, keeping their order
+func analyzeLevel(ctx context.Context, pa PageAnalyzer, urls []string, depth, concurrency int) []Page {
+	pages := make([]Page, len(urls))
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i, u := range urls {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, u string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			page := Page{URL: u, Depth: depth}
+			info, err := pa.AnalyzeURL(ctx, u)
+			if err != nil {
+				page.Error = err.Error()
+			} else {
+				page.Info = &info
+			}
+			pages[i] = page
+		}(i, u)
+	}
+	wg.Wait()
+	return pages
+}
@@ -0,0 +1,79 @@
+package crawl
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sashithaf16/peekalo/analyzer"
+	"github.com/sashithaf16/peekalo/urlnorm"
+)
+
+// fakeSite serves link lists per URL; unknown URLs fail
+type fakeSite struct {
+	mu    sync.Mutex
+	links map[string][]string
+	calls []string
+}
+
+func (f *fakeSite) AnalyzeURL(ctx context.Context, pageURL string) (analyzer.PageInfo, error) {
+	f.mu.Lock()
+	f.calls = append(f.calls, pageURL)
+	f.mu.Unlock()
+	links, ok := f.links[pageURL]
+	if !ok {
+		return analyzer.PageInfo{}, errors.New("not found")
+	}
+	return analyzer.PageInfo{StatusCode: 200, Links: analyzer.LinkStats{InternalLinks: links}}, nil
+}
+
+func TestCrawl(t *testing.T) {
+	site := &fakeSite{links: map[string][]string{
+		"https://example.com":   {"https://example.com/a", "https://example.com/b", "https://other.example.com/x"},
+		"https://example.com/a": {"https://example.com", "https://example.com/c"},
+		"https://example.com/b": {"https://example.com/a", "https://example.com/d"},
+		"https://example.com/c": {"https://example.com/e"},
+	}}
+
+	pages, err := Crawl(context.Background(), site, "https://example.com#top", Options{MaxDepth: 2, Concurrency: 2})
+	require.NoError(t, err)
+
+	var urls []string
+	for _, p := range pages {
+		urls = append(urls, p.URL)
+	}
+	assert.Equal(t, []string{
+		"https://example.com",
+		"https://example.com/a",
+		"https://example.com/b",
+		"https://example.com/c",
+		"https://example.com/d",
+	}, urls)
+	assert.Equal(t, 2, pages[4].Depth)
+	assert.Equal(t, "not found", pages[4].Error)
+	assert.Nil(t, pages[4].Info)
+	assert.NotContains(t, site.calls, "https://other.example.com/x")
+
+	pages, err = Crawl(context.Background(), site, "https://example.com", Options{MaxPages: 2, MaxDepth: 5})
+	require.NoError(t, err)
+	assert.Len(t, pages, 2)
+
+	_, err = Crawl(context.Background(), site, "example.com", Options{})
+	assert.Error(t, err)
+}
+
+func TestCrawlNormalizesLinks(t *testing.T) {
+	site := &fakeSite{links: map[string][]string{
+		"https://example.com/":  {"https://EXAMPLE.com/a?utm_source=nav", "https://example.com:443/a", "https://example.com/b/../a#top"},
+		"https://example.com/a": {"https://example.com/"},
+	}}
+
+	pages, err := Crawl(context.Background(), site, "HTTPS://Example.com?utm_campaign=x", Options{MaxDepth: 3, Normalizer: urlnorm.New(urlnorm.DefaultTrackingParams)})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"https://example.com/", "https://example.com/a"}, site.calls)
+	assert.Len(t, pages, 2)
+}
@@ -0,0 +1,138 @@
+package diff
+
+import (
+	"sort"
+	"time"
+
+	"github.com/sashithaf16/peekalo/storage"
+)
+
+type StringChange struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+type IntChange struct {
+	From  int `json:"from"`
+	To    int `json:"to"`
+	Delta int `json:"delta"`
+}
+
+// Report lists what changed between two analyses. Fields that did not change are omitted.
+type Report struct {
+	URL      string    `json:"url"`
+	FromID   string    `json:"from_id"`
+	ToID     string    `json:"to_id"`
+	FromTime time.Time `json:"from_time"`
+	ToTime   time.Time `json:"to_time"`
+	Changed  bool      `json:"changed"`
+
+	StatusCode   *IntChange           `json:"status_code,omitempty"`
+	HTMLVersion  *StringChange        `json:"html_version,omitempty"`
+	Title        *StringChange        `json:"title,omitempty"`
+	Canonical    *StringChange        `json:"canonical,omitempty"`
+	Headings     map[string]IntChange `json:"headings,omitempty"`    // keyed by heading level
+	LinkCounts   map[string]IntChange `json:"link_counts,omitempty"` // keyed by internal, external and inaccessible
+	LinksAdded   []string             `json:"links_added,omitempty"`
+	LinksRemoved []string             `json:"links_removed,omitempty"`
+	LoginForm    string               `json:"login_form,omitempty"` // "appeared" or "disappeared"
+}
+
+// Compare reports the differences from an older analysis to a newer one
+func Compare(from, to *storage.Result) Report {
+	a, b := from.PageInfo, to.PageInfo
+	report := Report{
+		URL:      to.URL,
+		FromID:   from.ID,
+		ToID:     to.ID,
+		FromTime: from.CreatedAt,
+		ToTime:   to.CreatedAt,
+	}
+
+	report.StatusCode = intChange(a.StatusCode, b.StatusCode)
+	report.HTMLVersion = stringChange(a.HTMLVersion, b.HTMLVersion)
+	report.Title = stringChange(a.Title, b.Title)
+	report.Canonical = stringChange(a.Canonical, b.Canonical)
+
+	report.Headings = make(map[string]IntChange)
+	for level := range union(a.Headings, b.Headings) {
+		if c := intChange(a.Headings[level], b.Headings[level]); c != nil {
+			report.Headings[level] = *c
+		}
+	}
+
+	report.LinkCounts = make(map[string]IntChange)
+	for name, counts := range map[string][2]int{
+		"internal":     {a.Links.Internal, b.Links.Internal},
+		"external":     {a.Links.External, b.Links.External},
+		"inaccessible": {a.Links.Inaccessible, b.Links.Inaccessible},
+	} {
+		if c := intChange(counts[0], counts[1]); c != nil {
+			report.LinkCounts[name] = *c
+		}
+	}
+
+	fromLinks := linkSet(a.Links.InternalLinks, a.Links.ExternalLinks)
+	toLinks := linkSet(b.Links.InternalLinks, b.Links.ExternalLinks)
+	report.LinksAdded = missingFrom(toLinks, fromLinks)
+	report.LinksRemoved = missingFrom(fromLinks, toLinks)
+
+	switch {
+	case !a.HasLogin && b.HasLogin:
+		report.LoginForm = "appeared"
+	case a.HasLogin && !b.HasLogin:
+		report.LoginForm = "disappeared"
+	}
+
+	report.Changed = report.StatusCode != nil || report.HTMLVersion != nil || report.Title != nil ||
+		report.Canonical != nil || len(report.Headings) > 0 || len(report.LinkCounts) > 0 ||
+		len(report.LinksAdded) > 0 || len(report.LinksRemoved) > 0 || report.LoginForm != ""
+	return report
+}
+
+func stringChange(from, to string) *StringChange {
+	if from == to {
+		return nil
+	}
+	return &StringChange{From: from, To: to}
+}
+
+func intChange(from, to int) *IntChange {
+	if from == to {
+		return nil
+	}
+	return &IntChange{From: from, To: to, Delta: to - from}
+}
+
+func union(a, b map[string]int) map[string]bool {
+	keys := make(map[string]bool, len(a)+len(b))
+	for k := range a {
+		keys[k] = true
+	}
+	for k := range b {
+		keys[k] = true
+	}
+	return keys
+}
+
+func linkSet(lists ...[]string) map[string]bool {
+	set := make(map[string]bool)
+	for _, list := range lists {
+		for _, link := range list {
+			set[link] = true
+		}
+	}
+	return set
+}
+
+// missingFrom returns the sorted members of a that are not in b
+func missingFrom(a, b map[string]bool) []string {
+	var missing []string
+	for link := range a {
+		if !b[link] {
+			missing = append(missing, link)
+		}
+	}
+	sort.Strings(missing)
+	return missing
+}
@@ -0,0 +1,77 @@
+package diff
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sashithaf16/peekalo/analyzer"
+	"github.com/sashithaf16/peekalo/storage"
+)
+
+func TestCompare(t *testing.T) {
+	from := &storage.Result{
+		ID:        "a",
+		URL:       "https://example.com",
+		CreatedAt: time.Date(2025, 6, 1, 10, 0, 0, 0, time.UTC),
+		PageInfo: analyzer.PageInfo{
+			StatusCode:  200,
+			HTMLVersion: "HTML 5",
+			Title:       "Spring Sale",
+			Headings:    map[string]int{"h1": 1, "h2": 3},
+			Links: analyzer.LinkStats{
+				Internal:      2,
+				External:      1,
+				InternalLinks: []string{"https://example.com/sale", "https://example.com/login"},
+				ExternalLinks: []string{"https://partner.com"},
+			},
+			HasLogin: true,
+		},
+	}
+	to := &storage.Result{
+		ID:        "b",
+		URL:       "https://example.com",
+		CreatedAt: time.Date(2025, 6, 2, 10, 0, 0, 0, time.UTC),
+		PageInfo: analyzer.PageInfo{
+			StatusCode:  200,
+			HTMLVersion: "HTML 5",
+			Title:       "Summer Sale",
+			Headings:    map[string]int{"h1": 2, "h2": 3, "h3": 1},
+			Links: analyzer.LinkStats{
+				Internal:      2,
+				External:      1,
+				InternalLinks: []string{"https://example.com/sale", "https://example.com/summer"},
+				ExternalLinks: []string{"https://partner.com"},
+			},
+			HasLogin: false,
+		},
+	}
+
+	report := Compare(from, to)
+
+	assert.True(t, report.Changed)
+	assert.Equal(t, "a", report.FromID)
+	assert.Equal(t, "b", report.ToID)
+	assert.Equal(t, &StringChange{From: "Spring Sale", To: "Summer Sale"}, report.Title)
+	assert.Nil(t, report.HTMLVersion)
+	assert.Nil(t, report.StatusCode)
+	assert.Equal(t, map[string]IntChange{
+		"h1": {From: 1, To: 2, Delta: 1},
+		"h3": {From: 0, To: 1, Delta: 1},
+	}, report.Headings)
+	assert.Empty(t, report.LinkCounts)
+	assert.Equal(t, []string{"https://example.com/summer"}, report.LinksAdded)
+	assert.Equal(t, []string{"https://example.com/login"}, report.LinksRemoved)
+	assert.Equal(t, "disappeared", report.LoginForm)
+}
+
+func TestCompare_Unchanged(t *testing.T) {
+	r := &storage.Result{ID: "a", PageInfo: analyzer.PageInfo{Title: "Same", Headings: map[string]int{"h1": 1}}}
+
+	report := Compare(r, r)
+
+	assert.False(t, report.Changed)
+	assert.Nil(t, report.Title)
+	assert.Empty(t, report.Headings)
+}
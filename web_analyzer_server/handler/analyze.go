@@ -1,44 +1,143 @@
 package handler
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
+	"time"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/sashithaf16/peekalo/analyzer"
+	"github.com/sashithaf16/peekalo/auth"
+	"github.com/sashithaf16/peekalo/coalesce"
 	"github.com/sashithaf16/peekalo/config"
+	"github.com/sashithaf16/peekalo/hostpolicy"
 	"github.com/sashithaf16/peekalo/logger"
 	"github.com/sashithaf16/peekalo/metrics"
+	"github.com/sashithaf16/peekalo/ratelimit"
+	"github.com/sashithaf16/peekalo/robots"
+	"github.com/sashithaf16/peekalo/snapshot"
+	"github.com/sashithaf16/peekalo/storage"
+	"github.com/sashithaf16/peekalo/urlnorm"
+	"github.com/sashithaf16/peekalo/webhook"
 )
 
 var validate = validator.New()
 
 type UrlAnalyzeRequest struct {
 	URL string `json:"url" validate:"required,url"`
+	WebhookRequest
+}
+
+// WebhookRequest asks for the outcome of an analysis to be POSTed, signed with the secret, to a URL
+type WebhookRequest struct {
+	WebhookURL    string `json:"webhook_url,omitempty" validate:"omitempty,url"`
+	WebhookSecret string `json:"webhook_secret,omitempty" validate:"required_with=WebhookURL"`
+}
+
+func (wr WebhookRequest) target() webhook.Target {
+	return webhook.Target{URL: wr.WebhookURL, Secret: wr.WebhookSecret}
+}
+
+// AnalysisEvent is the webhook payload data sent when an analysis finishes
+type AnalysisEvent struct {
+	URL      string      `json:"url"`
+	ResultID string      `json:"result_id,omitempty"`
+	Data     interface{} `json:"data,omitempty"` // page info or sitemap report
+	Error    string      `json:"error,omitempty"`
 }
 
 type AnalyzeURLHandlerParams struct {
 	cfg        *config.Config
 	logger     logger.Logger
 	httpClient analyzer.HttpClientInterface
+	robots     *robots.Checker
+	store      storage.Store
+	webhooks   *webhook.Dispatcher
+	snapshots  snapshot.Store
+	norm       *urlnorm.Normalizer
+	hosts      *hostpolicy.Policy
+	inFlight   coalesce.Group[analysisOutcome]
+	slots      *ratelimit.InFlight // in-flight slots taken by sitemap fan-outs
+	keys       *auth.Store         // charged for the pages of sitemap analyses
+}
+
+// analysisOutcome is shared by identical analyze requests made while it was in flight
+type analysisOutcome struct {
+	info     analyzer.PageInfo
+	resultID string
+}
+
+// Option configures optional collaborators of the analyze handler
+type Option func(*AnalyzeURLHandlerParams)
+
+// WithResultStore persists every successful analysis in store
+func WithResultStore(store storage.Store) Option {
+	return func(a *AnalyzeURLHandlerParams) {
+		a.store = store
+	}
+}
+
+// WithWebhooks lets callers ask for analysis outcomes to be delivered by d
+func WithWebhooks(d *webhook.Dispatcher) Option {
+	return func(a *AnalyzeURLHandlerParams) {
+		a.webhooks = d
+	}
 }
 
-func NewAnalyzeUrlHandler(cfg *config.Config, logger logger.Logger, httpClient analyzer.HttpClientInterface) *AnalyzeURLHandlerParams {
-	return &AnalyzeURLHandlerParams{
+func NewAnalyzeUrlHandler(cfg *config.Config, logger logger.Logger, httpClient analyzer.HttpClientInterface, opts ...Option) *AnalyzeURLHandlerParams {
+	h := &AnalyzeURLHandlerParams{
 		cfg:        cfg,
 		logger:     logger,
 		httpClient: httpClient,
+		norm:       urlnorm.New(cfg.TrackingParams),
 	}
+	// the checker is shared across requests so robots.txt is cached per host
+	if robots.Policy(cfg.RobotsPolicy).Enabled() {
+		h.robots = robots.NewChecker(logger, httpClient, cfg.UserAgent, time.Duration(cfg.RobotsCacheTTL)*time.Second)
+	}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
+}
+
+// analyzerOptions configures analyzers for API requests, which reuse analyses younger than the cache TTL
+func (a *AnalyzeURLHandlerParams) analyzerOptions() []analyzer.Option {
+	return a.analyzerOptionsWithTTL(time.Duration(a.cfg.CacheTTL) * time.Second)
+}
+
+func (a *AnalyzeURLHandlerParams) analyzerOptionsWithTTL(cacheTTL time.Duration) []analyzer.Option {
+	var opts []analyzer.Option
+	if a.robots != nil {
+		opts = append(opts, analyzer.WithRobotsChecker(a.robots))
+	}
+	if a.snapshots != nil {
+		opts = append(opts, analyzer.WithSnapshotStore(a.snapshots))
+	}
+	if a.store != nil {
+		opts = append(opts, analyzer.WithPreviousResults(fetchedResults{store: a.store, norm: a.norm, logger: a.logger}, cacheTTL))
+	}
+	return opts
+}
+
+// PageAnalyzer returns an analyzer sharing the handler's robots.txt cache and snapshot store, for analyses made
+// outside a request such as monitor runs. It ignores the cache TTL and always fetches, revalidating with the
+// validators of the previous analysis, so a run never reports a page it did not check.
+func (a *AnalyzeURLHandlerParams) PageAnalyzer() *analyzer.Analyzer {
+	return analyzer.NewAnalyzer(a.logger, a.cfg, a.httpClient, a.analyzerOptionsWithTTL(0)...)
 }
 
 func (a *AnalyzeURLHandlerParams) AnalyzeURLHandler(w http.ResponseWriter, r *http.Request) {
 
-	a.logger.Info().Msg("Received request to analyze URL")
+	log := logger.FromContext(r.Context(), a.logger)
+	log.Info().Msg("Received request to analyze URL")
 
 	var req UrlAnalyzeRequest
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		a.logger.Error().Err(err).Msg("Failed to decode request body")
+		log.Error().Err(err).Msg("Failed to decode request body")
 		metrics.RequestInvalidCount.Inc()
 		a.respondJSON(w, http.StatusBadRequest, APIResponse{Success: false, Error: "Invalid request payload"})
 		return
@@ -46,35 +145,190 @@ func (a *AnalyzeURLHandlerParams) AnalyzeURLHandler(w http.ResponseWriter, r *ht
 
 	err := validate.Struct(req)
 	if err != nil {
-		a.logger.Error().Err(err).Msg("Validation failed for request")
+		log.Error().Err(err).Msg("Validation failed for request")
 		metrics.RequestInvalidCount.Inc()
 		a.respondJSON(w, http.StatusBadRequest, APIResponse{Success: false, Error: "Validation failed: " + err.Error()})
 		return
 	}
+	if !a.checkWebhook(w, r, req.WebhookRequest) {
+		return
+	}
+	if !a.allowTarget(w, r, req.URL) {
+		return
+	}
 
 	metrics.RequestReceivedSuccessCount.Inc()
 
-	analyzer := analyzer.NewAnalyzer(a.logger, a.cfg, a.httpClient)
+	an := analyzer.NewAnalyzer(a.logger, a.cfg, a.httpClient, a.analyzerOptions()...)
 
-	pageInfo, err := analyzer.AnalyzeURL(r.Context(), req.URL) // context from the request is propagated to the analyzer function
+	// identical requests share one fetch and one stored result; context from the request is propagated to the analyzer function
+	outcome, shared, err := a.inFlight.Do(r.Context(), canonicalURL(a.norm, req.URL), func(ctx context.Context) (analysisOutcome, error) {
+		info, err := an.AnalyzeURL(ctx, req.URL)
+		if err != nil {
+			return analysisOutcome{}, err
+		}
+		return analysisOutcome{info: info, resultID: a.saveResult(ctx, req.URL, "analyze", info)}, nil
+	})
+	if shared {
+		log.Info().Msgf("Joined analysis already in flight: %s", req.URL)
+		metrics.AnalysisCoalescedCount.Inc()
+	}
+	pageInfo, resultID := outcome.info, outcome.resultID
+	if errors.Is(err, analyzer.ErrDisallowedByRobots) {
+		log.Info().Msgf("Analysis blocked by robots.txt: %s", req.URL)
+		metrics.RequestAnalyzerFailureCount.Inc()
+		deliveryID := a.notify(r.Context(), req.WebhookRequest, webhook.EventAnalysisFailed, AnalysisEvent{URL: req.URL, Error: err.Error()})
+		a.respondJSON(w, http.StatusForbidden, APIResponse{Success: false, Error: "Failed to analyze URL: " + err.Error(), DeliveryID: deliveryID})
+		return
+	}
+	if errors.Is(err, hostpolicy.ErrNotAllowed) {
+		log.Warn().Err(err).Msgf("Analysis redirected to a refused host: %s", req.URL)
+		metrics.TargetNotAllowedCount.Inc()
+		metrics.RequestAnalyzerFailureCount.Inc()
+		resp := targetNotAllowed(err)
+		resp.DeliveryID = a.notify(r.Context(), req.WebhookRequest, webhook.EventAnalysisFailed, AnalysisEvent{URL: req.URL, Error: err.Error()})
+		a.respondJSON(w, http.StatusForbidden, resp)
+		return
+	}
 	if err != nil {
-		a.logger.Error().Err(err).Msg("Failed to analyze URL")
+		log.Error().Err(err).Msg("Failed to analyze URL")
 		metrics.RequestAnalyzerFailureCount.Inc()
-		a.respondJSON(w, http.StatusInternalServerError, APIResponse{Success: false, Error: "Failed to analyze URL: " + err.Error()})
+		deliveryID := a.notify(r.Context(), req.WebhookRequest, webhook.EventAnalysisFailed, AnalysisEvent{URL: req.URL, Error: err.Error()})
+		a.respondJSON(w, http.StatusInternalServerError, APIResponse{Success: false, Error: "Failed to analyze URL: " + err.Error(), DeliveryID: deliveryID})
 		return
 	}
-	a.logger.Info().Msgf("Successfully analyzed URL: %s", req.URL)
+	log.Info().Msgf("Successfully analyzed URL: %s", req.URL)
 	metrics.RequestAnalyzerSuccessCount.Inc()
-	a.respondJSON(w, http.StatusOK, APIResponse{Success: true, Data: pageInfo})
+	deliveryID := a.notify(r.Context(), req.WebhookRequest, webhook.EventAnalysisCompleted, AnalysisEvent{URL: req.URL, ResultID: resultID, Data: pageInfo})
+	a.respondJSON(w, http.StatusOK, APIResponse{Success: true, Data: pageInfo, ResultID: resultID, DeliveryID: deliveryID})
+}
+
+// canonicalURL returns the normalized form of pageURL, under which results are stored and
+// identical analyses are shared. URLs that cannot be parsed are used as given.
+func canonicalURL(norm *urlnorm.Normalizer, pageURL string) string {
+	canonical, err := norm.Normalize(pageURL)
+	if err != nil {
+		return pageURL
+	}
+	return canonical
+}
+
+// checkWebhook validates a requested webhook, writing the error response when it cannot be delivered.
+// Targets refused by the webhook host policy are forbidden like refused analysis targets.
+func (a *AnalyzeURLHandlerParams) checkWebhook(w http.ResponseWriter, r *http.Request, wr WebhookRequest) bool {
+	if wr.WebhookURL == "" {
+		return true
+	}
+	if a.webhooks == nil {
+		metrics.RequestInvalidCount.Inc()
+		a.respondJSON(w, http.StatusBadRequest, APIResponse{Success: false, Error: "Webhooks are not enabled"})
+		return false
+	}
+	err := a.webhooks.CheckTarget(wr.target())
+	if errors.Is(err, hostpolicy.ErrNotAllowed) {
+		logger.FromContext(r.Context(), a.logger).Warn().Err(err).Msgf("Refused webhook target: %s", wr.WebhookURL)
+		metrics.TargetNotAllowedCount.Inc()
+		a.respondJSON(w, http.StatusForbidden, targetNotAllowed(err))
+		return false
+	}
+	if err != nil {
+		metrics.RequestInvalidCount.Inc()
+		a.respondJSON(w, http.StatusBadRequest, APIResponse{Success: false, Error: "Validation failed: " + err.Error()})
+		return false
+	}
+	return true
+}
+
+// notify queues a webhook when the request asked for one and returns the delivery ID
+func (a *AnalyzeURLHandlerParams) notify(ctx context.Context, wr WebhookRequest, event string, data AnalysisEvent) string {
+	if wr.WebhookURL == "" || a.webhooks == nil {
+		return ""
+	}
+	id, err := a.webhooks.Send(wr.target(), event, data)
+	if err != nil {
+		logger.FromContext(ctx, a.logger).Error().Err(err).Msgf("Failed to queue webhook for URL: %s", data.URL)
+		return ""
+	}
+	return id
+}
+
+// saveResult stores a successful analysis under the canonical URL when a result store is configured
+// and returns its ID. A storage failure is logged but does not fail the analysis.
+func (a *AnalyzeURLHandlerParams) saveResult(ctx context.Context, pageURL, source string, info analyzer.PageInfo) string {
+	if a.store == nil {
+		return ""
+	}
+	result := &storage.Result{
+		URL:      canonicalURL(a.norm, pageURL),
+		Options:  map[string]string{"source": source, "robots_policy": a.cfg.RobotsPolicy},
+		PageInfo: info,
+	}
+	if err := a.store.Save(ctx, result); err != nil {
+		logger.FromContext(ctx, a.logger).Error().Err(err).Msgf("Failed to store analysis result for URL: %s", pageURL)
+		return ""
+	}
+	return result.ID
+}
+
+// previousResultsWindow bounds the stored results searched for the latest fetched analyses of a URL
+const previousResultsWindow = 10
+
+// fetchedResults looks up previous analyses in the result store. Results that reused a cached analysis
+// are skipped so the cache TTL counts from the last real fetch.
+type fetchedResults struct {
+	store  storage.Store
+	norm   *urlnorm.Normalizer
+	logger logger.Logger
+}
+
+func (f fetchedResults) LatestFetched(ctx context.Context, pageURL string) (analyzer.PageInfo, time.Time, bool) {
+	results, err := f.store.ListByURL(ctx, canonicalURL(f.norm, pageURL), previousResultsWindow)
+	if err != nil {
+		logger.FromContext(ctx, f.logger).Error().Err(err).Msgf("Failed to look up previous results for URL: %s", pageURL)
+		return analyzer.PageInfo{}, time.Time{}, false
+	}
+	for _, r := range results {
+		if r.Fetched() && !r.PageInfo.Cached {
+			return r.PageInfo, r.CreatedAt, true
+		}
+	}
+	return analyzer.PageInfo{}, time.Time{}, false
+}
+
+// recordingAnalyzer stores every successful analysis made through it
+type recordingAnalyzer struct {
+	handler *AnalyzeURLHandlerParams
+	an      *analyzer.Analyzer
+	source  string
+}
+
+func (r recordingAnalyzer) AnalyzeURL(ctx context.Context, pageURL string) (analyzer.PageInfo, error) {
+	info, err := r.an.AnalyzeURL(ctx, pageURL)
+	if err == nil {
+		r.handler.saveResult(ctx, pageURL, r.source, info)
+	}
+	return info, err
 }
 
 type APIResponse struct {
-	Success bool        `json:"success"`
-	Data    interface{} `json:"data,omitempty"`
-	Error   string      `json:"error,omitempty"`
+	Success    bool        `json:"success"`
+	Data       interface{} `json:"data,omitempty"`
+	Error      string      `json:"error,omitempty"`
+	RequestID  string      `json:"request_id,omitempty"`
+	Code       string      `json:"code,omitempty"`        // machine-readable reason of an error, such as CodeTargetNotAllowed
+	ResultID   string      `json:"result_id,omitempty"`   // ID of the stored analysis, when results are persisted
+	DeliveryID string      `json:"delivery_id,omitempty"` // ID of the queued webhook delivery, when one was requested
 }
 
 func (a *AnalyzeURLHandlerParams) respondJSON(w http.ResponseWriter, statusCode int, resp APIResponse) {
+	writeJSON(w, statusCode, resp)
+}
+
+func writeJSON(w http.ResponseWriter, statusCode int, resp APIResponse) {
+	// RequestLogger sets the header before any handler runs
+	if resp.RequestID == "" {
+		resp.RequestID = w.Header().Get(RequestIDHeader)
+	}
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(statusCode)
 	json.NewEncoder(w).Encode(resp)
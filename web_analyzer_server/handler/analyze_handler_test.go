@@ -2,6 +2,7 @@ package handler
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"io"
@@ -9,14 +10,19 @@ import (
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 
 	"github.com/sashithaf16/peekalo/config"
+	"github.com/sashithaf16/peekalo/hostpolicy"
 
 	mocks "github.com/sashithaf16/peekalo/_mocks"
 	"github.com/sashithaf16/peekalo/logger"
+	"github.com/sashithaf16/peekalo/storage"
+	"github.com/sashithaf16/peekalo/urlnorm"
 )
 
 // Helper to create a HTTP response for mock
@@ -134,3 +140,101 @@ func TestAnalyzeURLHandler_WithMockHTTPClient(t *testing.T) {
 		mockHTTPClient.AssertExpectations(t)
 	})
 }
+
+func TestAnalyzeURLHandler_ConditionalFetch(t *testing.T) {
+	cfg := &config.Config{LogLevel: "debug", CacheTTL: 60}
+	log := logger.CreateLogger(cfg.LogLevel)
+	store := storage.NewMemoryStore()
+	mockHTTPClient := new(mocks.MockHTTPClient)
+	h := NewAnalyzeUrlHandler(cfg, log, mockHTTPClient, WithResultStore(store))
+
+	analyze := func() map[string]interface{} {
+		w := httptest.NewRecorder()
+		h.AnalyzeURLHandler(w, httptest.NewRequest(http.MethodPost, "/analyze", bytes.NewBufferString(`{"url":"https://example.com"}`)))
+		require.Equal(t, http.StatusOK, w.Code)
+		var apiResp APIResponse
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&apiResp))
+		return apiResp.Data.(map[string]interface{})
+	}
+
+	first := newHTTPResponse(`<html><head><title>Versioned</title></head></html>`, 200)
+	first.Header.Set("ETag", `"abc"`)
+	mockHTTPClient.On("Do", mock.AnythingOfType("*http.Request")).Return(first, nil).Once()
+	assert.Equal(t, `"abc"`, analyze()["etag"])
+
+	// within CacheTTL the stored analysis is reused without a request
+	assert.Equal(t, true, analyze()["cached"])
+	mockHTTPClient.AssertNumberOfCalls(t, "Do", 1)
+
+	// once the cached analysis is older than the TTL the page is revalidated
+	results, err := store.ListByURL(context.Background(), "https://example.com/", 0)
+	require.NoError(t, err)
+	for _, r := range results {
+		r.CreatedAt = r.CreatedAt.Add(-time.Hour)
+		require.NoError(t, store.Save(context.Background(), &r))
+	}
+	mockHTTPClient.On("Do", mock.MatchedBy(func(req *http.Request) bool {
+		return req.Header.Get("If-None-Match") == `"abc"`
+	})).Return(newHTTPResponse("", http.StatusNotModified), nil).Once()
+	data := analyze()
+	assert.Equal(t, true, data["not_modified"])
+	assert.Equal(t, "Versioned", data["title"])
+	mockHTTPClient.AssertExpectations(t)
+
+	// monitor runs ignore the TTL and revalidate although the last analysis was just stored
+	mockHTTPClient.On("Do", mock.MatchedBy(func(req *http.Request) bool {
+		return req.Header.Get("If-None-Match") == `"abc"`
+	})).Return(newHTTPResponse("", http.StatusNotModified), nil).Once()
+	info, err := h.PageAnalyzer().AnalyzeURL(context.Background(), "https://example.com")
+	require.NoError(t, err)
+	assert.True(t, info.NotModified)
+	assert.False(t, info.Cached)
+	mockHTTPClient.AssertNumberOfCalls(t, "Do", 3)
+}
+
+func TestCanonicalURL(t *testing.T) {
+	norm := urlnorm.New(urlnorm.DefaultTrackingParams)
+	assert.Equal(t, "https://example.com/", canonicalURL(norm, "HTTPS://Example.COM:443#top"))
+	assert.Equal(t, "http://example.com:8080/a?b=1", canonicalURL(norm, "http://example.com:8080/a?b=1&utm_source=news"))
+	assert.NotEqual(t, canonicalURL(norm, "https://example.com/a"), canonicalURL(norm, "https://example.com/b"))
+	assert.Equal(t, "http://[::1", canonicalURL(norm, "http://[::1"))
+}
+
+func TestAnalyzeURLHandler_HostPolicy(t *testing.T) {
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<html><head><title>Elsewhere</title></head></html>`))
+	}))
+	defer target.Close()
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, strings.Replace(target.URL, "127.0.0.1", "localhost", 1), http.StatusFound)
+	}))
+	defer origin.Close()
+
+	cfg := &config.Config{LogLevel: "debug"}
+	hosts, err := hostpolicy.New([]string{"127.0.0.1"}, nil)
+	require.NoError(t, err)
+	h := NewAnalyzeUrlHandler(cfg, logger.CreateLogger(cfg.LogLevel), hosts.Client(&http.Client{}), WithHostPolicy(hosts))
+
+	analyze := func(pageURL string) (int, APIResponse) {
+		w := httptest.NewRecorder()
+		h.AnalyzeURLHandler(w, httptest.NewRequest(http.MethodPost, "/analyze", bytes.NewBufferString(`{"url":"`+pageURL+`"}`)))
+		var apiResp APIResponse
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&apiResp))
+		return w.Code, apiResp
+	}
+
+	code, resp := analyze("https://example.org/")
+	assert.Equal(t, http.StatusForbidden, code)
+	assert.Equal(t, CodeTargetNotAllowed, resp.Code)
+	assert.Contains(t, resp.Error, "example.org is not on the allow list")
+
+	// a redirect hop leaving the allow list is refused as well
+	code, resp = analyze(origin.URL)
+	assert.Equal(t, http.StatusForbidden, code)
+	assert.Equal(t, CodeTargetNotAllowed, resp.Code)
+	assert.Contains(t, resp.Error, "localhost is not on the allow list")
+
+	code, resp = analyze(target.URL)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "Elsewhere", resp.Data.(map[string]interface{})["title"])
+}
@@ -0,0 +1,74 @@
+package handler
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/sashithaf16/peekalo/analyzer"
+	"github.com/sashithaf16/peekalo/archive"
+	"github.com/sashithaf16/peekalo/logger"
+	"github.com/sashithaf16/peekalo/metrics"
+)
+
+// maxArchiveSize bounds uploaded HAR and WARC files
+const maxArchiveSize = 50 << 20
+
+type ArchiveAnalyzeResponse struct {
+	Format archive.Format      `json:"format"`
+	Pages  []ArchivePageResult `json:"pages"`
+}
+
+type ArchivePageResult struct {
+	archive.Page
+	ResultID string `json:"result_id,omitempty"`
+}
+
+// AnalyzeArchiveHandler analyzes every HTML response captured in an uploaded HAR or WARC file,
+// optionally gzip-compressed, without fetching anything. Successful analyses are stored with
+// the archive format as their source.
+func (a *AnalyzeURLHandlerParams) AnalyzeArchiveHandler(w http.ResponseWriter, r *http.Request) {
+
+	log := logger.FromContext(r.Context(), a.logger)
+	log.Info().Msg("Received request to analyze archive")
+
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxArchiveSize))
+	if err != nil {
+		metrics.RequestInvalidCount.Inc()
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			a.respondJSON(w, http.StatusRequestEntityTooLarge, APIResponse{Success: false, Error: "Archive is too large"})
+			return
+		}
+		log.Error().Err(err).Msg("Failed to read request body")
+		a.respondJSON(w, http.StatusBadRequest, APIResponse{Success: false, Error: "Invalid request payload"})
+		return
+	}
+	records, format, err := archive.Read(bytes.NewReader(data))
+	if err != nil {
+		metrics.RequestInvalidCount.Inc()
+		log.Error().Err(err).Msg("Failed to read archive")
+		a.respondJSON(w, http.StatusBadRequest, APIResponse{Success: false, Error: "Invalid archive: " + err.Error()})
+		return
+	}
+
+	metrics.RequestReceivedSuccessCount.Inc()
+
+	an := analyzer.NewAnalyzer(a.logger, a.cfg, a.httpClient)
+	pages := archive.Analyze(r.Context(), an, records, a.cfg.SitemapConcurrency)
+
+	resp := ArchiveAnalyzeResponse{Format: format, Pages: make([]ArchivePageResult, 0, len(pages))}
+	for _, p := range pages {
+		result := ArchivePageResult{Page: p}
+		if p.Info != nil {
+			metrics.RequestAnalyzerSuccessCount.Inc()
+			result.ResultID = a.saveResult(r.Context(), p.URL, string(format), *p.Info)
+		} else {
+			metrics.RequestAnalyzerFailureCount.Inc()
+		}
+		resp.Pages = append(resp.Pages, result)
+	}
+	log.Info().Msgf("Analyzed %d pages from %s archive", len(pages), format)
+	a.respondJSON(w, http.StatusOK, APIResponse{Success: true, Data: resp})
+}
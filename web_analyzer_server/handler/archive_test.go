@@ -0,0 +1,63 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	mocks "github.com/sashithaf16/peekalo/_mocks"
+	"github.com/sashithaf16/peekalo/config"
+	"github.com/sashithaf16/peekalo/logger"
+	"github.com/sashithaf16/peekalo/storage"
+)
+
+func TestAnalyzeArchiveHandler(t *testing.T) {
+	cfg := &config.Config{LogLevel: "debug", RobotsPolicy: "enforce", SitemapConcurrency: 2}
+	log := logger.CreateLogger(cfg.LogLevel)
+	store := storage.NewMemoryStore()
+	mockHTTPClient := new(mocks.MockHTTPClient)
+	h := NewAnalyzeUrlHandler(cfg, log, mockHTTPClient, WithResultStore(store))
+
+	har := `{"log": {"entries": [
+		{"request": {"url": "https://example.com/"}, "response": {"status": 200, "headers": [],
+		 "content": {"mimeType": "text/html", "text": "<title>Home</title>"}}},
+		{"request": {"url": "https://example.com/gone"}, "response": {"status": 404, "headers": [],
+		 "content": {"mimeType": "text/html"}}}
+	]}}`
+
+	serve := func(body string) (*httptest.ResponseRecorder, APIResponse) {
+		w := httptest.NewRecorder()
+		h.AnalyzeArchiveHandler(w, httptest.NewRequest(http.MethodPost, "/analyze/archive", strings.NewReader(body)))
+		var apiResp APIResponse
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&apiResp))
+		return w, apiResp
+	}
+
+	w, resp := serve(har)
+	require.Equal(t, http.StatusOK, w.Code)
+	raw, _ := json.Marshal(resp.Data)
+	var data ArchiveAnalyzeResponse
+	require.NoError(t, json.Unmarshal(raw, &data))
+	assert.Equal(t, "har", string(data.Format))
+	require.Len(t, data.Pages, 2)
+	assert.Equal(t, "Home", data.Pages[0].Info.Title)
+	require.NotEmpty(t, data.Pages[0].ResultID)
+	assert.Empty(t, data.Pages[1].ResultID)
+	assert.Equal(t, "response body was not captured", data.Pages[1].Error)
+
+	stored, err := store.Get(context.Background(), data.Pages[0].ResultID)
+	require.NoError(t, err)
+	assert.Equal(t, "har", stored.Options["source"])
+
+	w, _ = serve("<html></html>")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	mockHTTPClient.AssertNotCalled(t, "Do", mock.Anything)
+}
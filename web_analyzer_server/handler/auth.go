@@ -0,0 +1,113 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sashithaf16/peekalo/auth"
+	"github.com/sashithaf16/peekalo/logger"
+	"github.com/sashithaf16/peekalo/metrics"
+	"github.com/sashithaf16/peekalo/ratelimit"
+)
+
+// apiKeyFromRequest reads the key from "Authorization: Bearer <key>" or the X-API-Key header
+func apiKeyFromRequest(r *http.Request) string {
+	if h := r.Header.Get("Authorization"); h != "" {
+		if scheme, token, ok := strings.Cut(h, " "); ok && strings.EqualFold(scheme, "Bearer") {
+			return strings.TrimSpace(token)
+		}
+	}
+	return strings.TrimSpace(r.Header.Get("X-API-Key"))
+}
+
+// ClientKey keys clients by their authenticated API key, falling back to the remote address
+func ClientKey(r *http.Request) string {
+	if k, ok := auth.KeyFromContext(r.Context()); ok {
+		return "key:" + k.ID
+	}
+	return ClientIP(r)
+}
+
+// Authenticate rejects requests without a valid API key with 401 and stores the key in the request context
+func Authenticate(log logger.Logger, store *auth.Store) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			reqLog := logger.FromContext(r.Context(), log)
+			key, ok := store.Authenticate(apiKeyFromRequest(r))
+			if !ok {
+				reqLog.Warn().Str("client", ClientIP(r)).Msg("Rejected request with missing or invalid API key")
+				metrics.RequestRejectedCount.WithLabelValues("unauthorized").Inc()
+				w.Header().Set("WWW-Authenticate", `Bearer realm="peekalo"`)
+				writeJSON(w, http.StatusUnauthorized, APIResponse{Success: false, Error: "Missing or invalid API key"})
+				return
+			}
+			// every later log line of the request, including the access log, carries the key id
+			logger.AddStr(reqLog, "key_id", key.ID)
+			reqLog.Debug().Msgf("Authenticated request: %s %s", r.Method, r.URL.Path)
+			metrics.APIKeyRequestCount.WithLabelValues(key.ID).Inc()
+			next.ServeHTTP(w, r.WithContext(auth.WithKey(r.Context(), key)))
+		})
+	}
+}
+
+// RequireScope rejects authenticated requests whose key does not grant scope with 403
+func RequireScope(log logger.Logger, scope auth.Scope) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			reqLog := logger.FromContext(r.Context(), log)
+			key, ok := auth.KeyFromContext(r.Context())
+			if !ok || !key.HasScope(scope) {
+				reqLog.Warn().Msgf("API key lacks scope %q", scope)
+				metrics.RequestRejectedCount.WithLabelValues("forbidden").Inc()
+				writeJSON(w, http.StatusForbidden, APIResponse{Success: false, Error: "API key does not grant the " + string(scope) + " scope"})
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// KeyLimits applies the per-key rate limit and daily quota of the authenticated key with 429
+func KeyLimits(log logger.Logger, store *auth.Store) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			reqLog := logger.FromContext(r.Context(), log)
+			key, ok := auth.KeyFromContext(r.Context())
+			if !ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if allowed, wait := store.AllowRate(key); !allowed {
+				reqLog.Warn().Msg("Rate limit exceeded for API key")
+				metrics.RequestRejectedCount.WithLabelValues("key_rate_limited").Inc()
+				w.Header().Set("Retry-After", strconv.Itoa(ratelimit.RetryAfterSeconds(wait)))
+				writeJSON(w, http.StatusTooManyRequests, APIResponse{Success: false, Error: "Rate limit exceeded"})
+				return
+			}
+			if allowed, resetAt := store.ConsumeQuota(key); !allowed {
+				reqLog.Warn().Msg("Daily quota exhausted for API key")
+				metrics.RequestRejectedCount.WithLabelValues("quota_exceeded").Inc()
+				w.Header().Set("Retry-After", strconv.Itoa(ratelimit.RetryAfterSeconds(time.Until(resetAt))))
+				writeJSON(w, http.StatusTooManyRequests, APIResponse{Success: false, Error: "Daily quota exceeded"})
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+type AdminHandlerParams struct {
+	logger logger.Logger
+	store  *auth.Store
+}
+
+func NewAdminHandler(logger logger.Logger, store *auth.Store) *AdminHandlerParams {
+	return &AdminHandlerParams{logger: logger, store: store}
+}
+
+// KeyUsageHandler lists every API key with its scopes and today's quota usage
+func (a *AdminHandlerParams) KeyUsageHandler(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, APIResponse{Success: true, Data: a.store.Usage()})
+}
@@ -0,0 +1,39 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sashithaf16/peekalo/auth"
+	"github.com/sashithaf16/peekalo/logger"
+)
+
+func TestAuthMiddleware(t *testing.T) {
+	log := logger.CreateLogger("debug")
+	store := auth.NewStore([]auth.Key{
+		{ID: "ci", Hash: auth.HashKey("ci-secret"), Scopes: []auth.Scope{auth.ScopeAnalyze}, DailyQuota: 1},
+	})
+
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) })
+	analyze := Authenticate(log, store)(RequireScope(log, auth.ScopeAnalyze)(KeyLimits(log, store)(ok)))
+	admin := Authenticate(log, store)(RequireScope(log, auth.ScopeAdmin)(ok))
+
+	serve := func(h http.Handler, header, value string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/analyze", nil)
+		if header != "" {
+			req.Header.Set(header, value)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		return w
+	}
+
+	assert.Equal(t, http.StatusUnauthorized, serve(analyze, "", "").Code)
+	assert.Equal(t, http.StatusUnauthorized, serve(analyze, "X-API-Key", "wrong").Code)
+	assert.Equal(t, http.StatusOK, serve(analyze, "Authorization", "Bearer ci-secret").Code)
+	assert.Equal(t, http.StatusTooManyRequests, serve(analyze, "X-API-Key", "ci-secret").Code, "daily quota of 1 is used up")
+	assert.Equal(t, http.StatusForbidden, serve(admin, "X-API-Key", "ci-secret").Code)
+}
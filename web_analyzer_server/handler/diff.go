@@ -0,0 +1,62 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/sashithaf16/peekalo/diff"
+	"github.com/sashithaf16/peekalo/logger"
+	"github.com/sashithaf16/peekalo/storage"
+)
+
+// DiffHandler compares two stored analyses, given either as from and to result IDs or
+// as a url whose latest fetched analysis is compared with the previous one
+func (h *ResultsHandlerParams) DiffHandler(w http.ResponseWriter, r *http.Request) {
+	log := logger.FromContext(r.Context(), h.logger)
+	query := r.URL.Query()
+
+	var from, to *storage.Result
+	var err error
+	switch {
+	case query.Get("from") != "" && query.Get("to") != "":
+		if from, err = h.store.Get(r.Context(), query.Get("from")); err == nil {
+			to, err = h.store.Get(r.Context(), query.Get("to"))
+		}
+	case query.Get("url") != "":
+		var results []storage.Result
+		results, err = h.store.ListByURL(r.Context(), canonicalURL(h.norm, query.Get("url")), previousResultsWindow)
+		// documents submitted by clients are not versions of the page
+		var fetched []storage.Result
+		for _, result := range results {
+			if result.Fetched() {
+				fetched = append(fetched, result)
+			}
+		}
+		if err == nil && len(fetched) < 2 {
+			writeJSON(w, http.StatusNotFound, APIResponse{Success: false, Error: "At least two stored results are needed to diff a URL"})
+			return
+		}
+		if err == nil {
+			to, from = &fetched[0], &fetched[1]
+		}
+	default:
+		writeJSON(w, http.StatusBadRequest, APIResponse{Success: false, Error: "Validation failed: provide from and to result ids, or a url"})
+		return
+	}
+
+	if errors.Is(err, storage.ErrNotFound) {
+		writeJSON(w, http.StatusNotFound, APIResponse{Success: false, Error: "Result not found"})
+		return
+	}
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to load results to diff")
+		writeJSON(w, http.StatusInternalServerError, APIResponse{Success: false, Error: "Failed to load results"})
+		return
+	}
+	// results stored before URLs were normalized may be spelled differently
+	if canonicalURL(h.norm, from.URL) != canonicalURL(h.norm, to.URL) {
+		writeJSON(w, http.StatusBadRequest, APIResponse{Success: false, Error: "Validation failed: from and to are analyses of different URLs"})
+		return
+	}
+	writeJSON(w, http.StatusOK, APIResponse{Success: true, Data: diff.Compare(from, to)})
+}
@@ -0,0 +1,43 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/sashithaf16/peekalo/health"
+	"github.com/sashithaf16/peekalo/logger"
+)
+
+type HealthHandlerParams struct {
+	logger logger.Logger
+	health *health.Health
+}
+
+func NewHealthHandler(logger logger.Logger, health *health.Health) *HealthHandlerParams {
+	return &HealthHandlerParams{logger: logger, health: health}
+}
+
+// LivenessHandler answers 200 as long as the process can serve HTTP
+func (h *HealthHandlerParams) LivenessHandler(w http.ResponseWriter, r *http.Request) {
+	writeHealth(w, http.StatusOK, h.health.Liveness())
+}
+
+// ReadinessHandler answers 503 while draining or when a component is unhealthy, so load
+// balancers stop routing new requests to the instance
+func (h *HealthHandlerParams) ReadinessHandler(w http.ResponseWriter, r *http.Request) {
+	report := h.health.Readiness(r.Context())
+	status := http.StatusOK
+	if report.Status != health.StatusOK {
+		logger.FromContext(r.Context(), h.logger).Warn().Interface("components", report.Components).Msg("Readiness check failed")
+		status = http.StatusServiceUnavailable
+	}
+	writeHealth(w, status, report)
+}
+
+// health reports are written bare rather than wrapped in APIResponse, as probes expect
+func writeHealth(w http.ResponseWriter, statusCode int, report health.Report) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(report)
+}
@@ -0,0 +1,37 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/sashithaf16/peekalo/hostpolicy"
+	"github.com/sashithaf16/peekalo/logger"
+	"github.com/sashithaf16/peekalo/metrics"
+)
+
+// CodeTargetNotAllowed is the error code of requests for hosts outside the configured allow and deny lists
+const CodeTargetNotAllowed = "target_not_allowed"
+
+// WithHostPolicy refuses analyses of URLs whose host the policy does not permit. Redirects are
+// checked by the HTTP client, which should come from the same policy's Client method.
+func WithHostPolicy(p *hostpolicy.Policy) Option {
+	return func(a *AnalyzeURLHandlerParams) {
+		a.hosts = p
+	}
+}
+
+// allowTarget checks the host of pageURL before anything is fetched, writing the error response when it is refused
+func (a *AnalyzeURLHandlerParams) allowTarget(w http.ResponseWriter, r *http.Request, pageURL string) bool {
+	err := a.hosts.CheckURL(pageURL)
+	if err == nil {
+		return true
+	}
+	logger.FromContext(r.Context(), a.logger).Warn().Err(err).Msgf("Refused analysis target: %s", pageURL)
+	metrics.TargetNotAllowedCount.Inc()
+	a.respondJSON(w, http.StatusForbidden, targetNotAllowed(err))
+	return false
+}
+
+// targetNotAllowed is the response for an error wrapping hostpolicy.ErrNotAllowed
+func targetNotAllowed(err error) APIResponse {
+	return APIResponse{Success: false, Error: err.Error(), Code: CodeTargetNotAllowed}
+}
@@ -0,0 +1,90 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/sashithaf16/peekalo/analyzer"
+	"github.com/sashithaf16/peekalo/logger"
+	"github.com/sashithaf16/peekalo/metrics"
+)
+
+// maxHTMLSize bounds documents submitted for analysis
+const maxHTMLSize = 5 << 20
+
+type HTMLAnalyzeRequest struct {
+	HTML    string `json:"html" validate:"required"`
+	BaseURL string `json:"base_url,omitempty" validate:"omitempty,url"`
+}
+
+// AnalyzeHTMLHandler analyzes markup sent by the client instead of fetching a page. It accepts
+// a text/html body with an optional base_url query parameter, or a JSON HTMLAnalyzeRequest.
+// Results are stored under the base URL when one is given.
+func (a *AnalyzeURLHandlerParams) AnalyzeHTMLHandler(w http.ResponseWriter, r *http.Request) {
+
+	log := logger.FromContext(r.Context(), a.logger)
+	log.Info().Msg("Received request to analyze HTML")
+
+	var req HTMLAnalyzeRequest
+	body := http.MaxBytesReader(w, r.Body, maxHTMLSize)
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch mediaType {
+	case "text/html":
+		data, err := io.ReadAll(body)
+		if err != nil {
+			a.rejectHTML(w, log, err)
+			return
+		}
+		req = HTMLAnalyzeRequest{HTML: string(data), BaseURL: r.URL.Query().Get("base_url")}
+	case "application/json", "":
+		if err := json.NewDecoder(body).Decode(&req); err != nil {
+			a.rejectHTML(w, log, err)
+			return
+		}
+	default:
+		metrics.RequestInvalidCount.Inc()
+		a.respondJSON(w, http.StatusUnsupportedMediaType, APIResponse{Success: false, Error: "Content-Type must be text/html or application/json"})
+		return
+	}
+
+	if err := validate.Struct(req); err != nil {
+		log.Error().Err(err).Msg("Validation failed for request")
+		metrics.RequestInvalidCount.Inc()
+		a.respondJSON(w, http.StatusBadRequest, APIResponse{Success: false, Error: "Validation failed: " + err.Error()})
+		return
+	}
+
+	metrics.RequestReceivedSuccessCount.Inc()
+
+	an := analyzer.NewAnalyzer(a.logger, a.cfg, a.httpClient)
+	pageInfo, err := an.AnalyzeDocument(r.Context(), strings.NewReader(req.HTML), req.BaseURL)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to analyze HTML")
+		metrics.RequestAnalyzerFailureCount.Inc()
+		a.respondJSON(w, http.StatusInternalServerError, APIResponse{Success: false, Error: "Failed to analyze HTML: " + err.Error()})
+		return
+	}
+	metrics.RequestAnalyzerSuccessCount.Inc()
+
+	var resultID string
+	if req.BaseURL != "" {
+		resultID = a.saveResult(r.Context(), req.BaseURL, "html", pageInfo)
+	}
+	a.respondJSON(w, http.StatusOK, APIResponse{Success: true, Data: pageInfo, ResultID: resultID})
+}
+
+func (a *AnalyzeURLHandlerParams) rejectHTML(w http.ResponseWriter, log *logger.Logger, err error) {
+	metrics.RequestInvalidCount.Inc()
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		a.respondJSON(w, http.StatusRequestEntityTooLarge, APIResponse{Success: false, Error: "HTML document is too large"})
+		return
+	}
+	log.Error().Err(err).Msg("Failed to read request body")
+	a.respondJSON(w, http.StatusBadRequest, APIResponse{Success: false, Error: "Invalid request payload"})
+}
@@ -0,0 +1,66 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	mocks "github.com/sashithaf16/peekalo/_mocks"
+	"github.com/sashithaf16/peekalo/config"
+	"github.com/sashithaf16/peekalo/logger"
+	"github.com/sashithaf16/peekalo/storage"
+)
+
+func TestAnalyzeHTMLHandler(t *testing.T) {
+	cfg := &config.Config{LogLevel: "debug", RobotsPolicy: "enforce"}
+	log := logger.CreateLogger(cfg.LogLevel)
+	store := storage.NewMemoryStore()
+	mockHTTPClient := new(mocks.MockHTTPClient)
+	h := NewAnalyzeUrlHandler(cfg, log, mockHTTPClient, WithResultStore(store))
+
+	doc := `<html><head><title>SSO Page</title></head><body><a href="/account">Account</a><form><input type="password"></form></body></html>`
+
+	serve := func(contentType, target, body string) (*httptest.ResponseRecorder, APIResponse) {
+		req := httptest.NewRequest(http.MethodPost, target, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", contentType)
+		w := httptest.NewRecorder()
+		h.AnalyzeHTMLHandler(w, req)
+		var apiResp APIResponse
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&apiResp))
+		return w, apiResp
+	}
+
+	w, resp := serve("text/html; charset=utf-8", "/analyze/html?base_url=https://intranet.example.com/home", doc)
+	require.Equal(t, http.StatusOK, w.Code)
+	data := resp.Data.(map[string]interface{})
+	assert.Equal(t, "SSO Page", data["title"])
+	assert.Equal(t, true, data["has_login"])
+	assert.Equal(t, float64(1), data["link_stats"].(map[string]interface{})["internal"])
+	require.NotEmpty(t, resp.ResultID)
+	stored, err := store.Get(context.Background(), resp.ResultID)
+	require.NoError(t, err)
+	assert.Equal(t, "html", stored.Options["source"])
+
+	body, _ := json.Marshal(HTMLAnalyzeRequest{HTML: doc})
+	w, resp = serve("application/json", "/analyze/html", string(body))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, resp.ResultID, "documents without a base URL are not stored")
+
+	w, _ = serve("application/json", "/analyze/html", `{"html":""}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w, _ = serve("text/plain", "/analyze/html", doc)
+	assert.Equal(t, http.StatusUnsupportedMediaType, w.Code)
+	w, _ = serve("text/html", "/analyze/html", strings.Repeat("a", maxHTMLSize+1))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+
+	// robots.txt and the page itself are never fetched
+	mockHTTPClient.AssertNotCalled(t, "Do", mock.Anything)
+}
@@ -0,0 +1,144 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/sashithaf16/peekalo/hostpolicy"
+	"github.com/sashithaf16/peekalo/logger"
+	"github.com/sashithaf16/peekalo/monitor"
+	"github.com/sashithaf16/peekalo/webhook"
+)
+
+type MonitorRequest struct {
+	URL        string             `json:"url" validate:"required,url"`
+	Interval   string             `json:"interval,omitempty"`
+	Cron       string             `json:"cron,omitempty"`
+	Conditions monitor.Conditions `json:"conditions"`
+	WebhookRequest
+}
+
+type MonitorsHandlerParams struct {
+	logger   logger.Logger
+	monitors *monitor.Manager
+}
+
+func NewMonitorsHandler(logger logger.Logger, monitors *monitor.Manager) *MonitorsHandlerParams {
+	return &MonitorsHandlerParams{logger: logger, monitors: monitors}
+}
+
+// CreateMonitorHandler registers a monitor and schedules it
+func (h *MonitorsHandlerParams) CreateMonitorHandler(w http.ResponseWriter, r *http.Request) {
+	req, ok := h.decodeMonitor(w, r)
+	if !ok {
+		return
+	}
+	mon, err := h.monitors.Create(req)
+	if err != nil {
+		h.respondError(w, r, err, "Failed to create monitor")
+		return
+	}
+	logger.FromContext(r.Context(), h.logger).Info().Msgf("Created monitor %s for URL: %s", mon.ID, mon.URL)
+	writeJSON(w, http.StatusCreated, APIResponse{Success: true, Data: redact(mon)})
+}
+
+// ListMonitorsHandler returns all monitors with their last run
+func (h *MonitorsHandlerParams) ListMonitorsHandler(w http.ResponseWriter, r *http.Request) {
+	monitors := h.monitors.List()
+	for i := range monitors {
+		monitors[i] = redact(monitors[i])
+	}
+	writeJSON(w, http.StatusOK, APIResponse{Success: true, Data: monitors})
+}
+
+// GetMonitorHandler returns one monitor by ID
+func (h *MonitorsHandlerParams) GetMonitorHandler(w http.ResponseWriter, r *http.Request) {
+	mon, err := h.monitors.Get(chi.URLParam(r, "id"))
+	if err != nil {
+		h.respondError(w, r, err, "Failed to read monitor")
+		return
+	}
+	writeJSON(w, http.StatusOK, APIResponse{Success: true, Data: redact(mon)})
+}
+
+// UpdateMonitorHandler replaces the URL, schedule and conditions of a monitor
+func (h *MonitorsHandlerParams) UpdateMonitorHandler(w http.ResponseWriter, r *http.Request) {
+	req, ok := h.decodeMonitor(w, r)
+	if !ok {
+		return
+	}
+	mon, err := h.monitors.Update(chi.URLParam(r, "id"), req)
+	if err != nil {
+		h.respondError(w, r, err, "Failed to update monitor")
+		return
+	}
+	writeJSON(w, http.StatusOK, APIResponse{Success: true, Data: redact(mon)})
+}
+
+// DeleteMonitorHandler stops and removes a monitor. Its stored results are kept.
+func (h *MonitorsHandlerParams) DeleteMonitorHandler(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if err := h.monitors.Delete(id); err != nil {
+		h.respondError(w, r, err, "Failed to delete monitor")
+		return
+	}
+	logger.FromContext(r.Context(), h.logger).Info().Msgf("Deleted monitor %s", id)
+	writeJSON(w, http.StatusOK, APIResponse{Success: true})
+}
+
+// RunMonitorHandler runs a monitor immediately and returns the run
+func (h *MonitorsHandlerParams) RunMonitorHandler(w http.ResponseWriter, r *http.Request) {
+	run, err := h.monitors.Run(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		h.respondError(w, r, err, "Failed to run monitor")
+		return
+	}
+	writeJSON(w, http.StatusOK, APIResponse{Success: true, Data: run, ResultID: run.ResultID})
+}
+
+func (h *MonitorsHandlerParams) decodeMonitor(w http.ResponseWriter, r *http.Request) (monitor.Monitor, bool) {
+	log := logger.FromContext(r.Context(), h.logger)
+
+	var req MonitorRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Error().Err(err).Msg("Failed to decode request body")
+		writeJSON(w, http.StatusBadRequest, APIResponse{Success: false, Error: "Invalid request payload"})
+		return monitor.Monitor{}, false
+	}
+	if err := validate.Struct(req); err != nil {
+		log.Error().Err(err).Msg("Validation failed for request")
+		writeJSON(w, http.StatusBadRequest, APIResponse{Success: false, Error: "Validation failed: " + err.Error()})
+		return monitor.Monitor{}, false
+	}
+	mon := monitor.Monitor{URL: req.URL, Interval: req.Interval, Cron: req.Cron, Conditions: req.Conditions}
+	if req.WebhookURL != "" {
+		target := req.target()
+		mon.Webhook = &target
+	}
+	return mon, true
+}
+
+// redact hides the webhook secret of a monitor returned to callers
+func redact(mon monitor.Monitor) monitor.Monitor {
+	if mon.Webhook != nil {
+		mon.Webhook = &webhook.Target{URL: mon.Webhook.URL}
+	}
+	return mon
+}
+
+func (h *MonitorsHandlerParams) respondError(w http.ResponseWriter, r *http.Request, err error, msg string) {
+	switch {
+	case errors.Is(err, monitor.ErrNotFound):
+		writeJSON(w, http.StatusNotFound, APIResponse{Success: false, Error: "Monitor not found"})
+	case errors.Is(err, hostpolicy.ErrNotAllowed):
+		writeJSON(w, http.StatusForbidden, targetNotAllowed(err))
+	case errors.Is(err, monitor.ErrInvalid):
+		writeJSON(w, http.StatusBadRequest, APIResponse{Success: false, Error: "Validation failed: " + err.Error()})
+	default:
+		logger.FromContext(r.Context(), h.logger).Error().Err(err).Msg(msg)
+		writeJSON(w, http.StatusInternalServerError, APIResponse{Success: false, Error: msg})
+	}
+}
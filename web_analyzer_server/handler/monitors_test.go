@@ -0,0 +1,79 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	mocks "github.com/sashithaf16/peekalo/_mocks"
+	"github.com/sashithaf16/peekalo/config"
+	"github.com/sashithaf16/peekalo/logger"
+	"github.com/sashithaf16/peekalo/monitor"
+	"github.com/sashithaf16/peekalo/storage"
+)
+
+func TestMonitorsHandlers(t *testing.T) {
+	cfg := &config.Config{LogLevel: "debug"}
+	log := logger.CreateLogger(cfg.LogLevel)
+	store := storage.NewMemoryStore()
+
+	mockHTTPClient := new(mocks.MockHTTPClient)
+	mockHTTPClient.On("Do", mock.AnythingOfType("*http.Request")).Return(
+		newHTTPResponse(`<html><head><title>Monitored</title></head></html>`, 200), nil,
+	).Once()
+
+	pa := NewAnalyzeUrlHandler(cfg, log, mockHTTPClient).PageAnalyzer()
+	monitors, err := monitor.NewManager(log, pa, store, "", time.Minute)
+	require.NoError(t, err)
+
+	h := NewMonitorsHandler(log, monitors)
+	r := chi.NewRouter()
+	r.Get("/monitors", h.ListMonitorsHandler)
+	r.Post("/monitors", h.CreateMonitorHandler)
+	r.Get("/monitors/{id}", h.GetMonitorHandler)
+	r.Put("/monitors/{id}", h.UpdateMonitorHandler)
+	r.Delete("/monitors/{id}", h.DeleteMonitorHandler)
+	r.Post("/monitors/{id}/run", h.RunMonitorHandler)
+
+	serve := func(method, target string, body string) (*httptest.ResponseRecorder, APIResponse) {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(method, target, bytes.NewBufferString(body)))
+		var apiResp APIResponse
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&apiResp))
+		return w, apiResp
+	}
+
+	w, created := serve(http.MethodPost, "/monitors", `{"url":"https://example.com","interval":"15m","conditions":{"page_broken":true}}`)
+	require.Equal(t, http.StatusCreated, w.Code)
+	id := created.Data.(map[string]interface{})["id"].(string)
+
+	w, _ = serve(http.MethodPost, "/monitors", `{"url":"https://example.com","interval":"1s"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w, _ = serve(http.MethodPost, "/monitors", `{"interval":"15m"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w, run := serve(http.MethodPost, "/monitors/"+id+"/run", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NotEmpty(t, run.ResultID)
+
+	w, updated := serve(http.MethodPut, "/monitors/"+id, `{"url":"https://example.com","cron":"0 9 * * 1"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "0 9 * * 1", updated.Data.(map[string]interface{})["cron"])
+
+	w, list := serve(http.MethodGet, "/monitors", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Len(t, list.Data, 1)
+
+	w, _ = serve(http.MethodDelete, "/monitors/"+id, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	w, _ = serve(http.MethodGet, "/monitors/"+id, "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
@@ -0,0 +1,81 @@
+package handler
+
+import (
+	"net"
+	"net/http"
+	"strconv"
+
+	"github.com/sashithaf16/peekalo/auth"
+	"github.com/sashithaf16/peekalo/logger"
+	"github.com/sashithaf16/peekalo/metrics"
+	"github.com/sashithaf16/peekalo/ratelimit"
+)
+
+// ClientKeyFunc identifies the client a request is rate limited as
+type ClientKeyFunc func(r *http.Request) string
+
+// ClientIP keys clients by the remote address of the connection
+func ClientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// RateLimit rejects requests with 429 once the client's token bucket is empty
+func RateLimit(log logger.Logger, limiter *ratelimit.Limiter, clientKey ClientKeyFunc) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			reqLog := logger.FromContext(r.Context(), log)
+			client := clientKey(r)
+			if ok, wait := limiter.Allow(client); !ok {
+				reqLog.Warn().Msgf("Rate limit exceeded for client: %s", client)
+				metrics.RequestRejectedCount.WithLabelValues("rate_limited").Inc()
+				w.Header().Set("Retry-After", strconv.Itoa(ratelimit.RetryAfterSeconds(wait)))
+				writeJSON(w, http.StatusTooManyRequests, APIResponse{Success: false, Error: "Rate limit exceeded"})
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// ConcurrencyLimit rejects requests with 503 while every in-flight slot is taken
+func ConcurrencyLimit(log logger.Logger, inFlight *ratelimit.InFlight) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			reqLog := logger.FromContext(r.Context(), log)
+			if !inFlight.TryAcquire() {
+				reqLog.Warn().Msgf("Server saturated with %d in-flight requests", inFlight.Cap())
+				metrics.RequestRejectedCount.WithLabelValues("saturated").Inc()
+				w.Header().Set("Retry-After", "1")
+				writeJSON(w, http.StatusServiceUnavailable, APIResponse{Success: false, Error: "Server is busy, retry later"})
+				return
+			}
+			metrics.InFlightRequests.Inc()
+			defer func() {
+				metrics.InFlightRequests.Dec()
+				inFlight.Release()
+			}()
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// AnalysisLimits returns the middleware of the analysis endpoints in the order it has to run.
+// The global rate and concurrency limits come first, so a request they reject with 429 or 503
+// is not charged to the key's daily quota. A nil store, limiter or inFlight skips that limit.
+func AnalysisLimits(log logger.Logger, store *auth.Store, limiter *ratelimit.Limiter, inFlight *ratelimit.InFlight) []func(http.Handler) http.Handler {
+	var mw []func(http.Handler) http.Handler
+	if limiter != nil {
+		mw = append(mw, RateLimit(log, limiter, ClientKey))
+	}
+	if inFlight != nil {
+		mw = append(mw, ConcurrencyLimit(log, inFlight))
+	}
+	if store != nil {
+		mw = append(mw, KeyLimits(log, store))
+	}
+	return mw
+}
@@ -0,0 +1,95 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sashithaf16/peekalo/auth"
+	"github.com/sashithaf16/peekalo/logger"
+	"github.com/sashithaf16/peekalo/ratelimit"
+)
+
+func TestRateLimit(t *testing.T) {
+	log := logger.CreateLogger("debug")
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) })
+	h := RateLimit(log, ratelimit.NewLimiter(0.5, 1), ClientIP)(ok)
+
+	req := httptest.NewRequest(http.MethodPost, "/analyze", nil)
+	req.RemoteAddr = "192.0.2.1:1234"
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "2", w.Header().Get("Retry-After"))
+
+	req.RemoteAddr = "192.0.2.2:1234"
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestConcurrencyLimit(t *testing.T) {
+	log := logger.CreateLogger("debug")
+	inFlight := ratelimit.NewInFlight(1)
+
+	var inner *httptest.ResponseRecorder
+	var h http.Handler
+	h = ConcurrencyLimit(log, inFlight)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// a second request arriving while this one runs is rejected
+		inner = httptest.NewRecorder()
+		h.ServeHTTP(inner, r)
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/analyze", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, http.StatusServiceUnavailable, inner.Code)
+	assert.Equal(t, "1", inner.Header().Get("Retry-After"))
+	assert.Equal(t, 0, inFlight.Len())
+}
+
+func TestAnalysisLimits_RejectedRequestsKeepQuota(t *testing.T) {
+	log := logger.CreateLogger("debug")
+	store := auth.NewStore([]auth.Key{
+		{ID: "ci", Hash: auth.HashKey("ci-secret"), Scopes: []auth.Scope{auth.ScopeAnalyze}, DailyQuota: 5},
+	})
+	inFlight := ratelimit.NewInFlight(1)
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) })
+	var h http.Handler = ok
+	mw := AnalysisLimits(log, store, ratelimit.NewLimiter(0.5, 2), inFlight)
+	for i := len(mw) - 1; i >= 0; i-- {
+		h = mw[i](h)
+	}
+	h = Authenticate(log, store)(h)
+
+	serve := func() int {
+		req := httptest.NewRequest(http.MethodPost, "/analyze", nil)
+		req.Header.Set("X-API-Key", "ci-secret")
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		return w.Code
+	}
+	used := func() int { return store.Usage()[0].Used }
+
+	// every slot is taken, so the request is turned away before the quota is charged
+	inFlight.TryAcquire()
+	assert.Equal(t, http.StatusServiceUnavailable, serve())
+	assert.Equal(t, 0, used())
+	inFlight.Release()
+
+	assert.Equal(t, http.StatusOK, serve())
+	assert.Equal(t, 1, used())
+
+	// the rejected and the admitted request used up the burst of 2
+	assert.Equal(t, http.StatusTooManyRequests, serve())
+	assert.Equal(t, 1, used())
+}
@@ -0,0 +1,64 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sashithaf16/peekalo/logger"
+)
+
+func TestRequestLogger(t *testing.T) {
+	var buf bytes.Buffer
+	base := zerolog.New(&buf)
+
+	var seenID string
+	h := RequestLogger(base)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seenID = RequestIDFromContext(r.Context())
+		logger.FromContext(r.Context(), zerolog.Nop()).Info().Msg("inside handler")
+		writeJSON(w, http.StatusTeapot, APIResponse{Success: false, Error: "teapot"})
+	}))
+
+	t.Run("honours incoming request id", func(t *testing.T) {
+		buf.Reset()
+		req := httptest.NewRequest(http.MethodPost, "/analyze", nil)
+		req.Header.Set(RequestIDHeader, "abc-123")
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+
+		assert.Equal(t, "abc-123", seenID)
+		assert.Equal(t, "abc-123", w.Header().Get(RequestIDHeader))
+
+		var apiResp APIResponse
+		assert.NoError(t, json.NewDecoder(w.Body).Decode(&apiResp))
+		assert.Equal(t, "abc-123", apiResp.RequestID)
+
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		assert.Len(t, lines, 2)
+		for _, line := range lines {
+			var entry map[string]interface{}
+			assert.NoError(t, json.Unmarshal([]byte(line), &entry))
+			assert.Equal(t, "abc-123", entry["request_id"])
+		}
+		var access map[string]interface{}
+		assert.NoError(t, json.Unmarshal([]byte(lines[1]), &access))
+		assert.Equal(t, float64(http.StatusTeapot), access["status"])
+		assert.Equal(t, "/analyze", access["path"])
+	})
+
+	t.Run("generates id for missing or malformed header", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/analyze", nil)
+		req.Header.Set(RequestIDHeader, "bad id with spaces")
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+
+		assert.Len(t, seenID, 32)
+		assert.Equal(t, seenID, w.Header().Get(RequestIDHeader))
+	})
+}
@@ -0,0 +1,76 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/sashithaf16/peekalo/logger"
+	"github.com/sashithaf16/peekalo/storage"
+	"github.com/sashithaf16/peekalo/urlnorm"
+)
+
+const (
+	defaultResultsLimit = 20
+	maxResultsLimit     = 100
+)
+
+type ResultsHandlerParams struct {
+	logger logger.Logger
+	store  storage.Store
+	norm   *urlnorm.Normalizer
+}
+
+func NewResultsHandler(logger logger.Logger, store storage.Store, norm *urlnorm.Normalizer) *ResultsHandlerParams {
+	return &ResultsHandlerParams{logger: logger, store: store, norm: norm}
+}
+
+// ListResultsHandler returns the stored analyses of the url query parameter, newest first.
+// Any spelling of the URL finds the results stored under its canonical form.
+func (h *ResultsHandlerParams) ListResultsHandler(w http.ResponseWriter, r *http.Request) {
+	log := logger.FromContext(r.Context(), h.logger)
+
+	pageURL := r.URL.Query().Get("url")
+	if err := validate.Var(pageURL, "required,url"); err != nil {
+		writeJSON(w, http.StatusBadRequest, APIResponse{Success: false, Error: "Validation failed: url query parameter must be a valid URL"})
+		return
+	}
+
+	limit := defaultResultsLimit
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxResultsLimit {
+			writeJSON(w, http.StatusBadRequest, APIResponse{Success: false, Error: "Validation failed: limit must be between 1 and " + strconv.Itoa(maxResultsLimit)})
+			return
+		}
+		limit = n
+	}
+
+	results, err := h.store.ListByURL(r.Context(), canonicalURL(h.norm, pageURL), limit)
+	if err != nil {
+		log.Error().Err(err).Msgf("Failed to list results for URL: %s", pageURL)
+		writeJSON(w, http.StatusInternalServerError, APIResponse{Success: false, Error: "Failed to list results"})
+		return
+	}
+	writeJSON(w, http.StatusOK, APIResponse{Success: true, Data: results})
+}
+
+// GetResultHandler returns one stored analysis by ID
+func (h *ResultsHandlerParams) GetResultHandler(w http.ResponseWriter, r *http.Request) {
+	log := logger.FromContext(r.Context(), h.logger)
+
+	id := chi.URLParam(r, "id")
+	result, err := h.store.Get(r.Context(), id)
+	if errors.Is(err, storage.ErrNotFound) {
+		writeJSON(w, http.StatusNotFound, APIResponse{Success: false, Error: "Result not found"})
+		return
+	}
+	if err != nil {
+		log.Error().Err(err).Msgf("Failed to read result: %s", id)
+		writeJSON(w, http.StatusInternalServerError, APIResponse{Success: false, Error: "Failed to read result"})
+		return
+	}
+	writeJSON(w, http.StatusOK, APIResponse{Success: true, Data: result})
+}
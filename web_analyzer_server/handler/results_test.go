@@ -0,0 +1,117 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	mocks "github.com/sashithaf16/peekalo/_mocks"
+	"github.com/sashithaf16/peekalo/analyzer"
+	"github.com/sashithaf16/peekalo/config"
+	"github.com/sashithaf16/peekalo/logger"
+	"github.com/sashithaf16/peekalo/storage"
+	"github.com/sashithaf16/peekalo/urlnorm"
+)
+
+func TestResultsHandlers(t *testing.T) {
+	cfg := &config.Config{LogLevel: "debug"}
+	log := logger.CreateLogger(cfg.LogLevel)
+	store := storage.NewMemoryStore()
+
+	mockHTTPClient := new(mocks.MockHTTPClient)
+	mockHTTPClient.On("Do", mock.AnythingOfType("*http.Request")).Return(
+		newHTTPResponse(`<html><head><title>Stored Page</title></head></html>`, 200), nil,
+	).Once()
+
+	r := chi.NewRouter()
+	r.Post("/analyze", NewAnalyzeUrlHandler(cfg, log, mockHTTPClient, WithResultStore(store)).AnalyzeURLHandler)
+	resultsHandler := NewResultsHandler(log, store, urlnorm.New(cfg.TrackingParams))
+	r.Get("/results", resultsHandler.ListResultsHandler)
+	r.Get("/results/{id}", resultsHandler.GetResultHandler)
+
+	serve := func(method, target string, body string) (*httptest.ResponseRecorder, APIResponse) {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(method, target, bytes.NewBufferString(body)))
+		var apiResp APIResponse
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&apiResp))
+		return w, apiResp
+	}
+
+	w, analyzed := serve(http.MethodPost, "/analyze", `{"url":"https://example.com"}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NotEmpty(t, analyzed.ResultID)
+
+	w, got := serve(http.MethodGet, "/results/"+analyzed.ResultID, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "Stored Page", got.Data.(map[string]interface{})["page_info"].(map[string]interface{})["title"])
+
+	w, list := serve(http.MethodGet, "/results?url=https://example.com", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Len(t, list.Data, 1)
+
+	// results are stored under the canonical URL, so other spellings find them
+	w, list = serve(http.MethodGet, "/results?url=HTTPS://EXAMPLE.com:443/%23top", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Len(t, list.Data, 1)
+
+	w, _ = serve(http.MethodGet, "/results/000000000000000000000000", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w, _ = serve(http.MethodGet, "/results?url=not-a-url", "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestDiffHandler(t *testing.T) {
+	ctx := context.Background()
+	log := logger.CreateLogger("debug")
+	store := storage.NewMemoryStore()
+	base := time.Date(2025, 6, 1, 10, 0, 0, 0, time.UTC)
+	save := func(source, title string, at time.Duration) *storage.Result {
+		r := &storage.Result{
+			URL:       "https://example.com/",
+			CreatedAt: base.Add(at),
+			Options:   map[string]string{"source": source},
+			PageInfo:  analyzer.PageInfo{StatusCode: 200, Title: title},
+		}
+		require.NoError(t, store.Save(ctx, r))
+		return r
+	}
+	older := save("analyze", "Home", 0)
+	newer := save("monitor", "Home", time.Minute)
+	// a document submitted for the same URL is newer but is not a version of the page
+	save("html", "Draft", 2*time.Minute)
+
+	h := NewResultsHandler(log, store, urlnorm.New(nil))
+	w := httptest.NewRecorder()
+	h.DiffHandler(w, httptest.NewRequest(http.MethodGet, "/diff?url=https://example.com", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	var apiResp APIResponse
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&apiResp))
+	report := apiResp.Data.(map[string]interface{})
+	assert.Equal(t, older.ID, report["from_id"])
+	assert.Equal(t, newer.ID, report["to_id"])
+	assert.Equal(t, false, report["changed"])
+
+	// results of different URLs cannot be compared, whatever their spelling
+	legacy := &storage.Result{URL: "HTTPS://Example.com:443", Options: map[string]string{"source": "analyze"}}
+	other := &storage.Result{URL: "https://example.org/", Options: map[string]string{"source": "analyze"}}
+	require.NoError(t, store.Save(ctx, legacy))
+	require.NoError(t, store.Save(ctx, other))
+	w = httptest.NewRecorder()
+	h.DiffHandler(w, httptest.NewRequest(http.MethodGet, "/diff?from="+legacy.ID+"&to="+newer.ID, nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = httptest.NewRecorder()
+	h.DiffHandler(w, httptest.NewRequest(http.MethodGet, "/diff?from="+older.ID+"&to="+other.ID, nil))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&apiResp))
+	assert.Contains(t, apiResp.Error, "different URLs")
+}
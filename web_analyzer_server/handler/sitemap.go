@@ -0,0 +1,148 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/sashithaf16/peekalo/analyzer"
+	"github.com/sashithaf16/peekalo/auth"
+	"github.com/sashithaf16/peekalo/hostpolicy"
+	"github.com/sashithaf16/peekalo/logger"
+	"github.com/sashithaf16/peekalo/metrics"
+	"github.com/sashithaf16/peekalo/ratelimit"
+	"github.com/sashithaf16/peekalo/sitemap"
+	"github.com/sashithaf16/peekalo/webhook"
+)
+
+// WithInFlight makes sitemap analyses hold one slot of inFlight per page analyzed at once,
+// on top of the slot the request itself holds
+func WithInFlight(inFlight *ratelimit.InFlight) Option {
+	return func(a *AnalyzeURLHandlerParams) {
+		a.slots = inFlight
+	}
+}
+
+// WithKeyQuotas charges every page of a sitemap analysis to the daily quota of the caller's key
+func WithKeyQuotas(store *auth.Store) Option {
+	return func(a *AnalyzeURLHandlerParams) {
+		a.keys = store
+	}
+}
+
+type SitemapAnalyzeRequest struct {
+	URL         string `json:"url" validate:"required,url"`
+	Concurrency int    `json:"concurrency" validate:"omitempty,min=1"`
+	MaxURLs     int    `json:"max_urls" validate:"omitempty,min=1"`
+	WebhookRequest
+}
+
+func (a *AnalyzeURLHandlerParams) AnalyzeSitemapHandler(w http.ResponseWriter, r *http.Request) {
+
+	log := logger.FromContext(r.Context(), a.logger)
+	log.Info().Msg("Received request to analyze sitemap")
+
+	var req SitemapAnalyzeRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Error().Err(err).Msg("Failed to decode request body")
+		metrics.RequestInvalidCount.Inc()
+		a.respondJSON(w, http.StatusBadRequest, APIResponse{Success: false, Error: "Invalid request payload"})
+		return
+	}
+
+	err := validate.Struct(req)
+	if err != nil {
+		log.Error().Err(err).Msg("Validation failed for request")
+		metrics.RequestInvalidCount.Inc()
+		a.respondJSON(w, http.StatusBadRequest, APIResponse{Success: false, Error: "Validation failed: " + err.Error()})
+		return
+	}
+	if !a.checkWebhook(w, r, req.WebhookRequest) {
+		return
+	}
+	if !a.allowTarget(w, r, req.URL) {
+		return
+	}
+
+	metrics.RequestReceivedSuccessCount.Inc()
+
+	// callers may lower the configured limits but never raise them
+	concurrency := a.cfg.SitemapConcurrency
+	if req.Concurrency > 0 && (concurrency <= 0 || req.Concurrency < concurrency) {
+		concurrency = req.Concurrency
+	}
+	maxURLs := a.cfg.SitemapMaxURLs
+	if req.MaxURLs > 0 && (maxURLs <= 0 || req.MaxURLs < maxURLs) {
+		maxURLs = req.MaxURLs
+	}
+
+	fetcher := sitemap.NewFetcher(a.logger, a.httpClient, a.cfg.UserAgent)
+	urls, err := fetcher.Fetch(r.Context(), req.URL, maxURLs)
+	if errors.Is(err, hostpolicy.ErrNotAllowed) {
+		log.Warn().Err(err).Msgf("Sitemap redirected to a refused host: %s", req.URL)
+		metrics.TargetNotAllowedCount.Inc()
+		metrics.RequestAnalyzerFailureCount.Inc()
+		resp := targetNotAllowed(err)
+		resp.DeliveryID = a.notify(r.Context(), req.WebhookRequest, webhook.EventAnalysisFailed, AnalysisEvent{URL: req.URL, Error: err.Error()})
+		a.respondJSON(w, http.StatusForbidden, resp)
+		return
+	}
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to fetch sitemap")
+		metrics.RequestAnalyzerFailureCount.Inc()
+		deliveryID := a.notify(r.Context(), req.WebhookRequest, webhook.EventAnalysisFailed, AnalysisEvent{URL: req.URL, Error: err.Error()})
+		a.respondJSON(w, http.StatusBadGateway, APIResponse{Success: false, Error: "Failed to fetch sitemap: " + err.Error(), DeliveryID: deliveryID})
+		return
+	}
+	// the request itself was charged once, every further page is charged here
+	if a.keys != nil {
+		if key, ok := auth.KeyFromContext(r.Context()); ok {
+			if allowed, resetAt := a.keys.ConsumeQuotaN(key, len(urls)-1); !allowed {
+				log.Warn().Msgf("Daily quota does not cover %d URLs from sitemap: %s", len(urls), req.URL)
+				metrics.RequestRejectedCount.WithLabelValues("quota_exceeded").Inc()
+				msg := fmt.Sprintf("Daily quota does not cover the %d URLs of the sitemap", len(urls))
+				deliveryID := a.notify(r.Context(), req.WebhookRequest, webhook.EventAnalysisFailed, AnalysisEvent{URL: req.URL, Error: msg})
+				w.Header().Set("Retry-After", strconv.Itoa(ratelimit.RetryAfterSeconds(time.Until(resetAt))))
+				a.respondJSON(w, http.StatusTooManyRequests, APIResponse{Success: false, Error: msg, DeliveryID: deliveryID})
+				return
+			}
+		}
+	}
+	concurrency, release := a.reserveSlots(concurrency, len(urls))
+	defer release()
+	log.Info().Msgf("Analyzing %d URLs from sitemap: %s with concurrency %d", len(urls), req.URL, concurrency)
+
+	an := analyzer.NewAnalyzer(a.logger, a.cfg, a.httpClient, a.analyzerOptions()...)
+	report := sitemap.Coverage(r.Context(), recordingAnalyzer{handler: a, an: an, source: "sitemap"}, a.norm, req.URL, urls, concurrency)
+
+	metrics.RequestAnalyzerSuccessCount.Inc()
+	deliveryID := a.notify(r.Context(), req.WebhookRequest, webhook.EventAnalysisCompleted, AnalysisEvent{URL: req.URL, Data: report})
+	a.respondJSON(w, http.StatusOK, APIResponse{Success: true, Data: report, DeliveryID: deliveryID})
+}
+
+// reserveSlots takes the in-flight slots for a fan-out of up to concurrency page analyses over
+// urls pages. The request's own slot covers the first analysis; when the others are not all
+// free, the fan-out is narrowed to the slots that were. release returns the extra slots.
+func (a *AnalyzeURLHandlerParams) reserveSlots(concurrency, urls int) (int, func()) {
+	if a.slots == nil {
+		return concurrency, func() {}
+	}
+	if concurrency > urls {
+		concurrency = urls
+	}
+	extra := 0
+	for extra < concurrency-1 && a.slots.TryAcquire() {
+		extra++
+	}
+	metrics.InFlightRequests.Add(float64(extra))
+	return extra + 1, func() {
+		for i := 0; i < extra; i++ {
+			a.slots.Release()
+		}
+		metrics.InFlightRequests.Sub(float64(extra))
+	}
+}
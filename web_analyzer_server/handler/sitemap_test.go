@@ -0,0 +1,115 @@
+package handler
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sashithaf16/peekalo/auth"
+	"github.com/sashithaf16/peekalo/config"
+	"github.com/sashithaf16/peekalo/logger"
+	"github.com/sashithaf16/peekalo/ratelimit"
+)
+
+// newSitemapSite serves a sitemap of pages and records how many of them were fetched at once
+func newSitemapSite(t *testing.T, pages int, onPage func()) (*httptest.Server, *atomic.Int32) {
+	var fetched atomic.Int32
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/sitemap.xml":
+			var urls strings.Builder
+			for i := 0; i < pages; i++ {
+				fmt.Fprintf(&urls, "<url><loc>%s/page-%d</loc></url>", srv.URL, i)
+			}
+			fmt.Fprintf(w, `<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">%s</urlset>`, urls.String())
+		case strings.HasPrefix(r.URL.Path, "/page-"):
+			fetched.Add(1)
+			if onPage != nil {
+				onPage()
+			}
+			fmt.Fprint(w, "<html><head><title>Page</title></head><body></body></html>")
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &fetched
+}
+
+func TestAnalyzeSitemapHandler_TakesSlotPerPage(t *testing.T) {
+	cfg := &config.Config{LogLevel: "debug", SitemapConcurrency: 5}
+	log := logger.CreateLogger(cfg.LogLevel)
+	inFlight := ratelimit.NewInFlight(3)
+
+	var mu sync.Mutex
+	running, maxRunning, maxSlots := 0, 0, 0
+	srv, fetched := newSitemapSite(t, 6, func() {
+		mu.Lock()
+		running++
+		maxRunning = max(maxRunning, running)
+		maxSlots = max(maxSlots, inFlight.Len())
+		mu.Unlock()
+		time.Sleep(20 * time.Millisecond)
+		mu.Lock()
+		running--
+		mu.Unlock()
+	})
+	h := NewAnalyzeUrlHandler(cfg, log, http.DefaultClient, WithInFlight(inFlight))
+
+	// the slot ConcurrencyLimit takes for the request itself
+	require.True(t, inFlight.TryAcquire())
+	w := httptest.NewRecorder()
+	h.AnalyzeSitemapHandler(w, httptest.NewRequest(http.MethodPost, "/sitemap/analyze",
+		bytes.NewBufferString(`{"url":"`+srv.URL+`/sitemap.xml"}`)))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	assert.Equal(t, int32(6), fetched.Load())
+	assert.Equal(t, 3, maxSlots, "the fan-out takes every free slot")
+	assert.LessOrEqual(t, maxRunning, 3, "no more pages run at once than slots are held")
+	assert.Equal(t, 1, inFlight.Len(), "extra slots are released")
+}
+
+func TestAnalyzeSitemapHandler_ChargesKeyQuotaPerPage(t *testing.T) {
+	cfg := &config.Config{LogLevel: "debug", SitemapConcurrency: 2}
+	log := logger.CreateLogger(cfg.LogLevel)
+	store := auth.NewStore([]auth.Key{
+		{ID: "ci", Hash: auth.HashKey("ci-secret"), Scopes: []auth.Scope{auth.ScopeAnalyze}, DailyQuota: 5},
+	})
+	key, ok := store.Authenticate("ci-secret")
+	require.True(t, ok)
+	h := NewAnalyzeUrlHandler(cfg, log, http.DefaultClient, WithKeyQuotas(store))
+
+	serve := func(srv *httptest.Server) *httptest.ResponseRecorder {
+		// KeyLimits charges the request itself before the handler runs
+		charged, _ := store.ConsumeQuota(key)
+		require.True(t, charged)
+		req := httptest.NewRequest(http.MethodPost, "/sitemap/analyze", bytes.NewBufferString(`{"url":"`+srv.URL+`/sitemap.xml"}`))
+		w := httptest.NewRecorder()
+		h.AnalyzeSitemapHandler(w, req.WithContext(auth.WithKey(req.Context(), key)))
+		return w
+	}
+
+	srv, fetched := newSitemapSite(t, 3, nil)
+	w := serve(srv)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, int32(3), fetched.Load())
+	assert.Equal(t, 3, store.Usage()[0].Used)
+
+	// 2 requests remain, which does not cover a sitemap of 3 pages
+	srv, fetched = newSitemapSite(t, 3, nil)
+	w = serve(srv)
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.NotEmpty(t, w.Header().Get("Retry-After"))
+	assert.Equal(t, int32(0), fetched.Load(), "no page is analyzed")
+	assert.Equal(t, 4, store.Usage()[0].Used, "only the request itself was charged")
+}
@@ -0,0 +1,108 @@
+package handler
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/sashithaf16/peekalo/analyzer"
+	"github.com/sashithaf16/peekalo/logger"
+	"github.com/sashithaf16/peekalo/metrics"
+	"github.com/sashithaf16/peekalo/snapshot"
+)
+
+// WithSnapshotStore keeps the raw body and headers of every fetched page in store
+func WithSnapshotStore(store snapshot.Store) Option {
+	return func(a *AnalyzeURLHandlerParams) {
+		a.snapshots = store
+	}
+}
+
+// GetSnapshotHandler returns the metadata of a stored capture
+func (a *AnalyzeURLHandlerParams) GetSnapshotHandler(w http.ResponseWriter, r *http.Request) {
+	s, ok := a.findSnapshot(w, r)
+	if !ok {
+		return
+	}
+	a.respondJSON(w, http.StatusOK, APIResponse{Success: true, Data: s})
+}
+
+// GetSnapshotBodyHandler returns the raw bytes of a stored snapshot as a download. The body is
+// never served as HTML so captured pages cannot run scripts on the API's origin.
+func (a *AnalyzeURLHandlerParams) GetSnapshotBodyHandler(w http.ResponseWriter, r *http.Request) {
+	s, ok := a.findSnapshot(w, r)
+	if !ok {
+		return
+	}
+	body, err := a.snapshots.Body(r.Context(), s.Hash)
+	if err != nil {
+		logger.FromContext(r.Context(), a.logger).Error().Err(err).Msgf("Failed to read snapshot %s", s.Hash)
+		a.respondJSON(w, http.StatusInternalServerError, APIResponse{Success: false, Error: "Failed to read snapshot"})
+		return
+	}
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Disposition", `attachment; filename="`+s.Hash+`.html"`)
+	w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(http.StatusOK)
+	w.Write(body)
+}
+
+// ReanalyzeSnapshotHandler runs the current analyzers on a stored capture, without fetching
+// anything, and stores the new result under the URL it was captured from
+func (a *AnalyzeURLHandlerParams) ReanalyzeSnapshotHandler(w http.ResponseWriter, r *http.Request) {
+
+	log := logger.FromContext(r.Context(), a.logger)
+	log.Info().Msg("Received request to reanalyze snapshot")
+
+	s, ok := a.findSnapshot(w, r)
+	if !ok {
+		return
+	}
+	body, err := a.snapshots.Body(r.Context(), s.Hash)
+	if err != nil {
+		log.Error().Err(err).Msgf("Failed to read snapshot %s", s.Hash)
+		a.respondJSON(w, http.StatusInternalServerError, APIResponse{Success: false, Error: "Failed to read snapshot"})
+		return
+	}
+
+	metrics.RequestReceivedSuccessCount.Inc()
+
+	an := analyzer.NewAnalyzer(a.logger, a.cfg, a.httpClient)
+	pageInfo, err := an.AnalyzeDocument(r.Context(), bytes.NewReader(body), s.URL)
+	if err != nil {
+		log.Error().Err(err).Msgf("Failed to reanalyze snapshot %s", s.ID)
+		metrics.RequestAnalyzerFailureCount.Inc()
+		a.respondJSON(w, http.StatusInternalServerError, APIResponse{Success: false, Error: "Failed to analyze snapshot: " + err.Error()})
+		return
+	}
+	metrics.RequestAnalyzerSuccessCount.Inc()
+	pageInfo.StatusCode = s.StatusCode
+	pageInfo.Snapshot = s.ID
+
+	resultID := a.saveResult(r.Context(), s.URL, "reanalyze", pageInfo)
+	a.respondJSON(w, http.StatusOK, APIResponse{Success: true, Data: pageInfo, ResultID: resultID})
+}
+
+// findSnapshot looks up the capture named in the path, writing the error response when there is none
+func (a *AnalyzeURLHandlerParams) findSnapshot(w http.ResponseWriter, r *http.Request) (*snapshot.Snapshot, bool) {
+	if a.snapshots == nil {
+		a.respondJSON(w, http.StatusNotFound, APIResponse{Success: false, Error: "Snapshots are not enabled"})
+		return nil, false
+	}
+	id := chi.URLParam(r, "id")
+	s, err := a.snapshots.Get(r.Context(), id)
+	if errors.Is(err, snapshot.ErrNotFound) {
+		a.respondJSON(w, http.StatusNotFound, APIResponse{Success: false, Error: "Snapshot not found"})
+		return nil, false
+	}
+	if err != nil {
+		logger.FromContext(r.Context(), a.logger).Error().Err(err).Msgf("Failed to read snapshot %s", id)
+		a.respondJSON(w, http.StatusInternalServerError, APIResponse{Success: false, Error: "Failed to read snapshot"})
+		return nil, false
+	}
+	return s, true
+}
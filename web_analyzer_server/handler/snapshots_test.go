@@ -0,0 +1,102 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	mocks "github.com/sashithaf16/peekalo/_mocks"
+	"github.com/sashithaf16/peekalo/config"
+	"github.com/sashithaf16/peekalo/logger"
+	"github.com/sashithaf16/peekalo/snapshot"
+	"github.com/sashithaf16/peekalo/storage"
+)
+
+func TestSnapshotHandlers(t *testing.T) {
+	cfg := &config.Config{LogLevel: "debug"}
+	log := logger.CreateLogger(cfg.LogLevel)
+	store := storage.NewMemoryStore()
+	snapshots := snapshot.NewMemoryStore()
+
+	page := `<html><head><title>Captured</title></head><body><a href="/login">Log in</a></body></html>`
+	resp := newHTTPResponse(page, 200)
+	resp.Header.Set("Content-Type", "text/html")
+	resp.Header.Set("Set-Cookie", "session=secret")
+	// the same bytes served by a second URL must not be attributed to the first
+	mirror := newHTTPResponse(page, 200)
+	mockHTTPClient := new(mocks.MockHTTPClient)
+	mockHTTPClient.On("Do", mock.MatchedBy(func(req *http.Request) bool { return req.URL.Host == "example.com" })).Return(resp, nil).Once()
+	mockHTTPClient.On("Do", mock.MatchedBy(func(req *http.Request) bool { return req.URL.Host == "mirror.example.net" })).Return(mirror, nil).Once()
+
+	h := NewAnalyzeUrlHandler(cfg, log, mockHTTPClient, WithResultStore(store), WithSnapshotStore(snapshots))
+	r := chi.NewRouter()
+	r.Post("/analyze", h.AnalyzeURLHandler)
+	r.Get("/snapshots/{id}", h.GetSnapshotHandler)
+	r.Get("/snapshots/{id}/raw", h.GetSnapshotBodyHandler)
+	r.Post("/snapshots/{id}/reanalyze", h.ReanalyzeSnapshotHandler)
+
+	serve := func(method, target, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(method, target, bytes.NewBufferString(body)))
+		return w
+	}
+	decode := func(w *httptest.ResponseRecorder) APIResponse {
+		var apiResp APIResponse
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&apiResp))
+		return apiResp
+	}
+
+	w := serve(http.MethodPost, "/analyze", `{"url":"https://example.com"}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	id := decode(w).Data.(map[string]interface{})["snapshot"].(string)
+
+	w = serve(http.MethodGet, "/snapshots/"+id, "")
+	require.Equal(t, http.StatusOK, w.Code)
+	meta := decode(w).Data.(map[string]interface{})
+	assert.Equal(t, id, meta["id"])
+	assert.Equal(t, snapshot.Hash([]byte(page)), meta["hash"])
+	assert.Equal(t, "https://example.com", meta["url"])
+	assert.NotContains(t, meta["headers"], "Set-Cookie")
+	assert.Equal(t, float64(len(page)), meta["size"])
+
+	w = serve(http.MethodGet, "/snapshots/"+id+"/raw", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, page, w.Body.String())
+	assert.Equal(t, "application/octet-stream", w.Header().Get("Content-Type"))
+
+	w = serve(http.MethodPost, "/snapshots/"+id+"/reanalyze", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	reanalyzed := decode(w)
+	data := reanalyzed.Data.(map[string]interface{})
+	assert.Equal(t, "Captured", data["title"])
+	assert.Equal(t, float64(200), data["status_code"])
+	assert.Equal(t, id, data["snapshot"])
+	stored, err := store.Get(context.Background(), reanalyzed.ResultID)
+	require.NoError(t, err)
+	assert.Equal(t, "reanalyze", stored.Options["source"])
+	assert.Equal(t, "https://example.com/", stored.URL)
+
+	w = serve(http.MethodPost, "/analyze", `{"url":"https://mirror.example.net/page"}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	mirrorID := decode(w).Data.(map[string]interface{})["snapshot"].(string)
+	assert.NotEqual(t, id, mirrorID)
+	w = serve(http.MethodPost, "/snapshots/"+mirrorID+"/reanalyze", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	stored, err = store.Get(context.Background(), decode(w).ResultID)
+	require.NoError(t, err)
+	assert.Equal(t, "https://mirror.example.net/page", stored.URL)
+
+	w = serve(http.MethodPost, "/snapshots/"+snapshot.Hash(nil)+"/reanalyze", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	// reanalysis never fetches the page again
+	mockHTTPClient.AssertNumberOfCalls(t, "Do", 2)
+}
@@ -0,0 +1,44 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/sashithaf16/peekalo/logger"
+	"github.com/sashithaf16/peekalo/webhook"
+)
+
+type WebhooksHandlerParams struct {
+	logger   logger.Logger
+	webhooks *webhook.Dispatcher
+}
+
+func NewWebhooksHandler(logger logger.Logger, webhooks *webhook.Dispatcher) *WebhooksHandlerParams {
+	return &WebhooksHandlerParams{logger: logger, webhooks: webhooks}
+}
+
+// ListDeliveriesHandler returns recent webhook deliveries with their attempts, newest first
+func (h *WebhooksHandlerParams) ListDeliveriesHandler(w http.ResponseWriter, r *http.Request) {
+	limit := defaultResultsLimit
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxResultsLimit {
+			writeJSON(w, http.StatusBadRequest, APIResponse{Success: false, Error: "Validation failed: limit must be between 1 and " + strconv.Itoa(maxResultsLimit)})
+			return
+		}
+		limit = n
+	}
+	writeJSON(w, http.StatusOK, APIResponse{Success: true, Data: h.webhooks.Deliveries(limit)})
+}
+
+// GetDeliveryHandler returns one webhook delivery by ID
+func (h *WebhooksHandlerParams) GetDeliveryHandler(w http.ResponseWriter, r *http.Request) {
+	delivery, ok := h.webhooks.Delivery(chi.URLParam(r, "id"))
+	if !ok {
+		writeJSON(w, http.StatusNotFound, APIResponse{Success: false, Error: "Delivery not found"})
+		return
+	}
+	writeJSON(w, http.StatusOK, APIResponse{Success: true, Data: delivery})
+}
@@ -0,0 +1,105 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	mocks "github.com/sashithaf16/peekalo/_mocks"
+	"github.com/sashithaf16/peekalo/config"
+	"github.com/sashithaf16/peekalo/logger"
+	"github.com/sashithaf16/peekalo/webhook"
+)
+
+func TestAnalyzeWebhook(t *testing.T) {
+	cfg := &config.Config{LogLevel: "debug"}
+	log := logger.CreateLogger(cfg.LogLevel)
+
+	type delivery struct {
+		header http.Header
+		body   []byte
+	}
+	received := make(chan delivery, 1)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received <- delivery{header: r.Header, body: body}
+	}))
+	defer receiver.Close()
+
+	// the mocked client serves the analyzed page, webhooks go to the real receiver
+	mockHTTPClient := new(mocks.MockHTTPClient)
+	mockHTTPClient.On("Do", mock.AnythingOfType("*http.Request")).Return(
+		newHTTPResponse(`<html><head><title>Hooked</title></head></html>`, 200), nil,
+	).Once()
+	webhooks := webhook.NewDispatcher(log, http.DefaultClient, "", 1, time.Second)
+	defer webhooks.Close()
+
+	r := chi.NewRouter()
+	r.Post("/analyze", NewAnalyzeUrlHandler(cfg, log, mockHTTPClient, WithWebhooks(webhooks)).AnalyzeURLHandler)
+	webhooksHandler := NewWebhooksHandler(log, webhooks)
+	r.Get("/webhooks/deliveries/{id}", webhooksHandler.GetDeliveryHandler)
+
+	serve := func(method, target string, body string) (*httptest.ResponseRecorder, APIResponse) {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(method, target, bytes.NewBufferString(body)))
+		var apiResp APIResponse
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&apiResp))
+		return w, apiResp
+	}
+
+	w, _ := serve(http.MethodPost, "/analyze", `{"url":"https://example.com","webhook_url":"`+receiver.URL+`"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code, "a webhook without a secret is rejected")
+
+	w, resp := serve(http.MethodPost, "/analyze", `{"url":"https://example.com","webhook_url":"`+receiver.URL+`","webhook_secret":"s3cret"}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NotEmpty(t, resp.DeliveryID)
+
+	d := <-received
+	assert.Equal(t, webhook.EventAnalysisCompleted, d.header.Get(webhook.EventHeader))
+	assert.NoError(t, webhook.Verify("s3cret", d.header.Get(webhook.SignatureHeader), d.header.Get(webhook.TimestampHeader), d.body, time.Minute, time.Now()))
+	var payload struct {
+		Data AnalysisEvent `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(d.body, &payload))
+	assert.Equal(t, "https://example.com", payload.Data.URL)
+	assert.Equal(t, "Hooked", payload.Data.Data.(map[string]interface{})["title"])
+
+	assert.Eventually(t, func() bool {
+		_, got := serve(http.MethodGet, "/webhooks/deliveries/"+resp.DeliveryID, "")
+		return got.Success && got.Data.(map[string]interface{})["status"] == webhook.StatusDelivered
+	}, time.Second, 5*time.Millisecond)
+
+	w, _ = serve(http.MethodGet, "/webhooks/deliveries/unknown", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestAnalyzeWebhook_DeniedTarget(t *testing.T) {
+	cfg := &config.Config{LogLevel: "debug", WebhookDeniedHosts: webhook.DefaultDeniedHosts}
+	log := logger.CreateLogger(cfg.LogLevel)
+	hosts, err := cfg.WebhookHosts()
+	require.NoError(t, err)
+	webhooks := webhook.NewDispatcher(log, hosts.Client(&http.Client{}), "", 1, time.Second, webhook.WithHostPolicy(hosts))
+	defer webhooks.Close()
+	// the page must not be fetched for a request that is refused
+	mockHTTPClient := new(mocks.MockHTTPClient)
+	h := NewAnalyzeUrlHandler(cfg, log, mockHTTPClient, WithWebhooks(webhooks))
+
+	w := httptest.NewRecorder()
+	h.AnalyzeURLHandler(w, httptest.NewRequest(http.MethodPost, "/analyze",
+		bytes.NewBufferString(`{"url":"https://example.com","webhook_url":"http://169.254.169.254/latest/meta-data/","webhook_secret":"s3cret"}`)))
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	var apiResp APIResponse
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&apiResp))
+	assert.Equal(t, CodeTargetNotAllowed, apiResp.Code)
+	assert.Empty(t, webhooks.Deliveries(0))
+	mockHTTPClient.AssertNotCalled(t, "Do", mock.Anything)
+}
@@ -0,0 +1,144 @@
+package health
+
+import (
+	"context"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Version and Commit are set at build time, e.g.
+// go build -ldflags "-X github.com/sashithaf16/peekalo/health.Version=1.2.0 -X github.com/sashithaf16/peekalo/health.Commit=abc123"
+var (
+	Version = "dev"
+	Commit  = ""
+)
+
+const (
+	StatusOK          = "ok"
+	StatusUnavailable = "unavailable"
+)
+
+// checkTimeout bounds how long a single component check may take
+const checkTimeout = 2 * time.Second
+
+// CheckFunc reports the health of one component; a nil error means healthy
+type CheckFunc func(ctx context.Context) error
+
+type BuildInfo struct {
+	Version   string    `json:"version"`
+	Commit    string    `json:"commit"`
+	GoVersion string    `json:"go_version"`
+	StartTime time.Time `json:"start_time"`
+}
+
+type ComponentStatus struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+type Report struct {
+	Status     string                     `json:"status"`
+	Components map[string]ComponentStatus `json:"components,omitempty"`
+	Build      BuildInfo                  `json:"build"`
+	Uptime     string                     `json:"uptime"`
+}
+
+type component struct {
+	name  string
+	check CheckFunc
+}
+
+// Health tracks process liveness and whether the instance should receive traffic
+type Health struct {
+	build    BuildInfo
+	draining atomic.Bool
+
+	mu         sync.RWMutex
+	components []component
+}
+
+func New() *Health {
+	build := BuildInfo{Version: Version, Commit: Commit, StartTime: time.Now().UTC()}
+	if info, ok := debug.ReadBuildInfo(); ok {
+		build.GoVersion = info.GoVersion
+		// fall back to the VCS revision stamped by the go tool when no commit was injected
+		if build.Commit == "" {
+			for _, s := range info.Settings {
+				if s.Key == "vcs.revision" {
+					build.Commit = s.Value
+				}
+			}
+		}
+	}
+	return &Health{build: build}
+}
+
+// AddComponent registers a dependency that must be healthy for the instance to be ready
+func (h *Health) AddComponent(name string, check CheckFunc) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.components = append(h.components, component{name: name, check: check})
+}
+
+// SetDraining marks the instance as shutting down so readiness fails while in-flight requests finish
+func (h *Health) SetDraining() {
+	h.draining.Store(true)
+}
+
+// Liveness reports that the process is up; it never checks dependencies
+func (h *Health) Liveness() Report {
+	return Report{Status: StatusOK, Build: h.build, Uptime: h.uptime()}
+}
+
+// Readiness checks the shutdown state and every registered component concurrently
+func (h *Health) Readiness(ctx context.Context) Report {
+	h.mu.RLock()
+	components := append([]component(nil), h.components...)
+	h.mu.RUnlock()
+
+	report := Report{
+		Status:     StatusOK,
+		Components: make(map[string]ComponentStatus, len(components)+1),
+		Build:      h.build,
+		Uptime:     h.uptime(),
+	}
+
+	if h.draining.Load() {
+		report.Components["shutdown"] = ComponentStatus{Status: StatusUnavailable, Error: "shutdown in progress"}
+	} else {
+		report.Components["shutdown"] = ComponentStatus{Status: StatusOK}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+	defer cancel()
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, c := range components {
+		wg.Add(1)
+		go func(c component) {
+			defer wg.Done()
+			status := ComponentStatus{Status: StatusOK}
+			if err := c.check(ctx); err != nil {
+				status = ComponentStatus{Status: StatusUnavailable, Error: err.Error()}
+			}
+			mu.Lock()
+			report.Components[c.name] = status
+			mu.Unlock()
+		}(c)
+	}
+	wg.Wait()
+
+	for _, status := range report.Components {
+		if status.Status != StatusOK {
+			report.Status = StatusUnavailable
+		}
+	}
+	return report
+}
+
+func (h *Health) uptime() string {
+	return time.Since(h.build.StartTime).Round(time.Second).String()
+}
@@ -0,0 +1,45 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadiness(t *testing.T) {
+	h := New()
+
+	report := h.Readiness(context.Background())
+	assert.Equal(t, StatusOK, report.Status)
+	assert.Equal(t, StatusOK, report.Components["shutdown"].Status)
+	assert.Equal(t, Version, report.Build.Version)
+	assert.False(t, report.Build.StartTime.IsZero())
+
+	healthy := true
+	h.AddComponent("store", func(ctx context.Context) error {
+		if !healthy {
+			return errors.New("disk full")
+		}
+		return nil
+	})
+
+	assert.Equal(t, StatusOK, h.Readiness(context.Background()).Status)
+
+	healthy = false
+	report = h.Readiness(context.Background())
+	assert.Equal(t, StatusUnavailable, report.Status)
+	assert.Equal(t, ComponentStatus{Status: StatusUnavailable, Error: "disk full"}, report.Components["store"])
+}
+
+func TestReadiness_Draining(t *testing.T) {
+	h := New()
+	h.SetDraining()
+
+	report := h.Readiness(context.Background())
+	assert.Equal(t, StatusUnavailable, report.Status)
+	assert.Equal(t, "shutdown in progress", report.Components["shutdown"].Error)
+
+	assert.Equal(t, StatusOK, h.Liveness().Status, "liveness is unaffected by draining")
+}
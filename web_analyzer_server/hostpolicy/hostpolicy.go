@@ -0,0 +1,208 @@
+package hostpolicy
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+
+	"golang.org/x/net/idna"
+)
+
+// ErrNotAllowed is returned for targets the policy does not permit
+var ErrNotAllowed = errors.New("target host is not allowed")
+
+const (
+	// maxRedirects matches the limit of the default http.Client
+	maxRedirects = 10
+	// the dialer settings of http.DefaultTransport
+	defaultDialTimeout = 30 * time.Second
+	defaultKeepAlive   = 30 * time.Second
+)
+
+// Policy restricts which hosts may be fetched. Rules are exact hosts such as "example.com",
+// wildcards such as "*.example.com" matching every subdomain but not the domain itself, and
+// CIDRs such as "10.0.0.0/8" matching IP address hosts. A host matching a deny rule is refused;
+// when there are allow rules, a host must also match one of them.
+type Policy struct {
+	allow rules
+	deny  rules
+}
+
+type rules struct {
+	hosts    map[string]bool
+	suffixes []string
+	prefixes []netip.Prefix
+}
+
+// New parses the allow and deny rules, reporting every rule that is not a valid host, wildcard or CIDR
+func New(allow, deny []string) (*Policy, error) {
+	var errs []error
+	p := &Policy{allow: parseRules(allow, &errs), deny: parseRules(deny, &errs)}
+	return p, errors.Join(errs...)
+}
+
+func parseRules(patterns []string, errs *[]error) rules {
+	r := rules{hosts: make(map[string]bool)}
+	for _, pattern := range patterns {
+		pattern = strings.TrimSpace(pattern)
+		if pattern == "" {
+			continue
+		}
+		if strings.Contains(pattern, "/") {
+			prefix, err := netip.ParsePrefix(pattern)
+			if err != nil {
+				*errs = append(*errs, fmt.Errorf("invalid CIDR %q", pattern))
+				continue
+			}
+			r.prefixes = append(r.prefixes, prefix.Masked())
+			continue
+		}
+		if addr, err := netip.ParseAddr(pattern); err == nil {
+			r.prefixes = append(r.prefixes, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+			continue
+		}
+		wildcard := strings.HasPrefix(pattern, "*.")
+		host, err := canonicalHost(strings.TrimPrefix(pattern, "*."))
+		if err != nil || host == "" || strings.Contains(host, "*") {
+			*errs = append(*errs, fmt.Errorf("invalid host pattern %q", pattern))
+			continue
+		}
+		if wildcard {
+			r.suffixes = append(r.suffixes, "."+host)
+		} else {
+			r.hosts[host] = true
+		}
+	}
+	return r
+}
+
+// Enabled reports whether the policy has any rules
+func (p *Policy) Enabled() bool {
+	return p != nil && (!p.allow.empty() || !p.deny.empty())
+}
+
+// Check returns an error wrapping ErrNotAllowed when the host of u may not be fetched
+func (p *Policy) Check(u *url.URL) error {
+	if !p.Enabled() {
+		return nil
+	}
+	host, err := canonicalHost(u.Hostname())
+	if err != nil || host == "" {
+		return fmt.Errorf("%w: invalid host %q", ErrNotAllowed, u.Hostname())
+	}
+	if p.deny.match(host) {
+		return fmt.Errorf("%w: %s is denied", ErrNotAllowed, host)
+	}
+	if !p.allow.empty() && !p.allow.match(host) {
+		return fmt.Errorf("%w: %s is not on the allow list", ErrNotAllowed, host)
+	}
+	return nil
+}
+
+// CheckURL parses rawURL and checks its host
+func (p *Policy) CheckURL(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrNotAllowed, err)
+	}
+	return p.Check(u)
+}
+
+// CheckRedirect checks every redirect hop, for use as http.Client.CheckRedirect
+func (p *Policy) CheckRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) >= maxRedirects {
+		return fmt.Errorf("stopped after %d redirects", maxRedirects)
+	}
+	return p.Check(req.URL)
+}
+
+// Client returns a copy of c that refuses requests to hosts the policy does not permit, including
+// every redirect hop. When c uses an *http.Transport, or the default one, connections to addresses
+// matching a deny CIDR are refused as well, so hostnames resolving into a denied range cannot be reached.
+func (p *Policy) Client(c *http.Client) *http.Client {
+	guarded := *c
+	guarded.CheckRedirect = p.CheckRedirect
+	base := c.Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	if t, ok := base.(*http.Transport); ok && len(p.deny.prefixes) > 0 {
+		t = t.Clone()
+		dialer := &net.Dialer{Timeout: defaultDialTimeout, KeepAlive: defaultKeepAlive, Control: p.controlDial}
+		t.DialContext = dialer.DialContext
+		base = t
+	}
+	guarded.Transport = checkedTransport{policy: p, base: base}
+	return &guarded
+}
+
+// checkedTransport checks the host of every request before sending it
+type checkedTransport struct {
+	policy *Policy
+	base   http.RoundTripper
+}
+
+func (t checkedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := t.policy.Check(req.URL); err != nil {
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		return nil, err
+	}
+	return t.base.RoundTrip(req)
+}
+
+// controlDial refuses connections to resolved addresses in a denied range
+func (p *Policy) controlDial(network, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return nil
+	}
+	addr := addrPort.Addr().Unmap()
+	for _, prefix := range p.deny.prefixes {
+		if prefix.Contains(addr) {
+			return fmt.Errorf("%w: %s is denied", ErrNotAllowed, addr)
+		}
+	}
+	return nil
+}
+
+func (r rules) empty() bool {
+	return len(r.hosts) == 0 && len(r.suffixes) == 0 && len(r.prefixes) == 0
+}
+
+func (r rules) match(host string) bool {
+	if addr, err := netip.ParseAddr(host); err == nil {
+		addr = addr.Unmap()
+		for _, prefix := range r.prefixes {
+			if prefix.Contains(addr) {
+				return true
+			}
+		}
+		return false
+	}
+	if r.hosts[host] {
+		return true
+	}
+	for _, suffix := range r.suffixes {
+		if strings.HasSuffix(host, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// canonicalHost lowercases a host, drops a trailing dot and converts internationalized names to punycode
+func canonicalHost(host string) (string, error) {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	if _, err := netip.ParseAddr(host); err == nil {
+		return host, nil
+	}
+	return idna.Lookup.ToASCII(host)
+}
@@ -0,0 +1,99 @@
+package hostpolicy
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPolicyCheck(t *testing.T) {
+	p, err := New(
+		[]string{"example.com", "*.example.com", "10.0.0.0/8", "bücher.example"},
+		[]string{"admin.example.com", "10.1.0.0/16"},
+	)
+	require.NoError(t, err)
+	require.True(t, p.Enabled())
+
+	tests := []struct {
+		url     string
+		allowed bool
+	}{
+		{"https://example.com/", true},
+		{"https://EXAMPLE.com./", true},
+		{"https://www.example.com/a", true},
+		{"https://deep.www.example.com/", true},
+		{"https://admin.example.com/", false},
+		{"https://notexample.com/", false},
+		{"https://example.org/", false},
+		{"http://10.2.3.4:8080/", true},
+		{"http://10.1.2.3/", false},
+		{"http://[::ffff:10.2.3.4]/", true},
+		{"http://192.168.1.1/", false},
+		{"https://xn--bcher-kva.example/", true},
+	}
+	for _, tt := range tests {
+		err := p.CheckURL(tt.url)
+		if tt.allowed {
+			assert.NoError(t, err, tt.url)
+		} else {
+			assert.ErrorIs(t, err, ErrNotAllowed, tt.url)
+		}
+	}
+
+	// without allow rules only the deny rules apply
+	p, err = New(nil, []string{"*.internal", "127.0.0.1"})
+	require.NoError(t, err)
+	assert.NoError(t, p.CheckURL("https://example.org/"))
+	assert.ErrorIs(t, p.CheckURL("http://wiki.internal/"), ErrNotAllowed)
+	assert.ErrorIs(t, p.CheckURL("http://127.0.0.1:9090/"), ErrNotAllowed)
+
+	p, err = New(nil, nil)
+	require.NoError(t, err)
+	assert.False(t, p.Enabled())
+	assert.NoError(t, p.CheckURL("http://127.0.0.1/"))
+}
+
+func TestNewRejectsInvalidRules(t *testing.T) {
+	_, err := New([]string{"10.0.0.0/33", "*"}, []string{"exa mple.com", "a.*.example.com"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `invalid CIDR "10.0.0.0/33"`)
+	assert.Contains(t, err.Error(), `invalid host pattern "*"`)
+	assert.Contains(t, err.Error(), `invalid host pattern "exa mple.com"`)
+	assert.Contains(t, err.Error(), `invalid host pattern "a.*.example.com"`)
+}
+
+func TestClientChecksRedirects(t *testing.T) {
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer target.Close()
+	_, port, _ := net.SplitHostPort(target.Listener.Addr().String())
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "http://localhost:"+port+"/", http.StatusFound)
+	}))
+	defer origin.Close()
+
+	p, err := New([]string{"127.0.0.1"}, nil)
+	require.NoError(t, err)
+	_, err = p.Client(&http.Client{}).Get(origin.URL)
+	assert.ErrorIs(t, err, ErrNotAllowed, "the redirect leaves the allow list")
+	_, err = p.Client(&http.Client{}).Get("http://localhost:" + port + "/")
+	assert.ErrorIs(t, err, ErrNotAllowed, "requests are checked before they are sent")
+
+	p, err = New([]string{"127.0.0.1", "localhost"}, nil)
+	require.NoError(t, err)
+	resp, err := p.Client(&http.Client{}).Get(origin.URL)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// hostnames resolving into a denied range are refused when connecting
+	p, err = New(nil, []string{"127.0.0.0/8"})
+	require.NoError(t, err)
+	_, err = p.Client(&http.Client{}).Get("http://localhost:" + port + "/")
+	assert.ErrorIs(t, err, ErrNotAllowed)
+}
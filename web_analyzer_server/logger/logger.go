@@ -1,6 +1,8 @@
 package logger
 
 import (
+	"context"
+	"io"
 	"os"
 
 	"github.com/rs/zerolog"
@@ -11,13 +13,42 @@ import (
 type Logger = zerolog.Logger
 
 func CreateLogger(lgL string) Logger {
+	return CreateLoggerWithWriter(lgL, os.Stdout)
+}
+
+// CreateLoggerWithWriter creates a logger writing to w, e.g. stderr for command-line output
+func CreateLoggerWithWriter(lgL string, w io.Writer) Logger {
 	zerolog.TimeFieldFormat = zerolog.TimeFormatUnix
 	logLevel, err := zerolog.ParseLevel(lgL)
 	if err != nil {
 		logLevel = zerolog.InfoLevel
 	}
 	zerolog.SetGlobalLevel(logLevel)
-	logger := zerolog.New(os.Stdout).With().Timestamp().Logger()
+	logger := zerolog.New(w).With().Timestamp().Logger()
 	logger.Info().Msg("Logger initialized")
 	return logger
 }
+
+type contextKey struct{}
+
+// WithContext returns a copy of ctx carrying l. The pointer is shared, so fields added
+// later with l.UpdateContext are visible to every holder of the context.
+func WithContext(ctx context.Context, l *Logger) context.Context {
+	return context.WithValue(ctx, contextKey{}, l)
+}
+
+// FromContext returns the request-scoped logger stored in ctx, or fallback when there is none
+func FromContext(ctx context.Context, fallback Logger) *Logger {
+	if l, ok := ctx.Value(contextKey{}).(*Logger); ok {
+		return l
+	}
+	return &fallback
+}
+
+// AddStr adds a string field to every subsequent entry written by l, including by
+// other holders of the same pointer.
+func AddStr(l *Logger, key, value string) {
+	l.UpdateContext(func(c zerolog.Context) zerolog.Context {
+		return c.Str(key, value)
+	})
+}
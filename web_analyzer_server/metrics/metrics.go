@@ -1,6 +1,9 @@
 package metrics
 
-import "github.com/prometheus/client_golang/prometheus"
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+)
 
 var PrometheusRegistry = prometheus.NewRegistry()
 
@@ -28,11 +31,152 @@ var (
 			Name: "request_analyzer_failure_count",
 			Help: "Number of requests failed to analyze",
 		})
+
+	InFlightRequests = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "in_flight_requests",
+			Help: "Number of rate limited requests currently being processed",
+		})
+
+	RequestRejectedCount = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "request_rejected_count",
+			Help: "Number of requests rejected by rate or concurrency limits",
+		}, []string{"reason"})
+
+	APIKeyRequestCount = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "api_key_request_count",
+			Help: "Number of authenticated requests per API key",
+		}, []string{"key_id"})
+
+	AnalysisDuration = prometheus.NewHistogram(
+		prometheus.HistogramOpts{
+			Name:    "analysis_duration_seconds",
+			Help:    "Total time taken to fetch and analyze a page",
+			Buckets: prometheus.ExponentialBuckets(0.05, 2, 10), // 50ms to ~25s
+		})
+
+	FetchDuration = prometheus.NewHistogram(
+		prometheus.HistogramOpts{
+			Name:    "fetch_duration_seconds",
+			Help:    "Time taken to receive the response headers of a page",
+			Buckets: prometheus.ExponentialBuckets(0.05, 2, 10),
+		})
+
+	AnalyzerDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "analyzer_duration_seconds",
+			Help:    "Time taken by each analyzer on a parsed document",
+			Buckets: prometheus.ExponentialBuckets(0.0001, 4, 8), // 100us to ~1.6s
+		}, []string{"analyzer"})
+
+	ResponseBodySize = prometheus.NewHistogram(
+		prometheus.HistogramOpts{
+			Name:    "response_body_size_bytes",
+			Help:    "Size of fetched page bodies",
+			Buckets: prometheus.ExponentialBuckets(1024, 4, 8), // 1KiB to 16MiB
+		})
+
+	AnalysisErrorCount = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "analysis_error_count",
+			Help: "Number of failed analyses by error class",
+		}, []string{"class"})
+
+	FetchStatusCount = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "fetch_status_count",
+			Help: "Number of fetched pages by HTTP status code",
+		}, []string{"code"})
+
+	InFlightAnalyses = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "in_flight_analyses",
+			Help: "Number of page analyses currently running",
+		})
+
+	MonitorUp = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "monitor_up",
+			Help: "1 when the last run of a monitor analyzed the page with a status below 400, 0 otherwise",
+		}, []string{"monitor_id"})
+
+	MonitorAlerting = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "monitor_alerting",
+			Help: "1 when the last run of a monitor raised an alert, 0 otherwise",
+		}, []string{"monitor_id"})
+
+	MonitorLastRunTimestamp = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "monitor_last_run_timestamp_seconds",
+			Help: "Unix time of the last run of a monitor",
+		}, []string{"monitor_id"})
+
+	MonitorInaccessibleLinks = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "monitor_inaccessible_links",
+			Help: "Inaccessible links found by the last successful run of a monitor",
+		}, []string{"monitor_id"})
+
+	MonitorAlertCount = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "monitor_alert_count",
+			Help: "Number of alerts raised by a monitor by condition",
+		}, []string{"monitor_id", "condition"})
+
+	WebhookDeliveryCount = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "webhook_delivery_count",
+			Help: "Number of finished webhook deliveries by status",
+		}, []string{"status"})
+
+	ConditionalFetchCount = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "conditional_fetch_count",
+			Help: "Number of analyses of previously analyzed URLs by outcome: cached, not_modified or modified",
+		}, []string{"outcome"})
+
+	AnalysisCoalescedCount = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "analysis_coalesced_count",
+			Help: "Number of analyze requests that shared an identical analysis already in flight",
+		})
+
+	TargetNotAllowedCount = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "target_not_allowed_count",
+			Help: "Number of analyses refused because the target or a redirect hop is outside the allowed hosts",
+		})
 )
 
 func RegisterMetrics() {
+	// the custom registry does not include the default runtime and process collectors
+	PrometheusRegistry.MustRegister(collectors.NewGoCollector())
+	PrometheusRegistry.MustRegister(collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}))
+
 	PrometheusRegistry.MustRegister(RequestInvalidCount)
 	PrometheusRegistry.MustRegister(RequestReceivedSuccessCount)
 	PrometheusRegistry.MustRegister(RequestAnalyzerSuccessCount)
 	PrometheusRegistry.MustRegister(RequestAnalyzerFailureCount)
+	PrometheusRegistry.MustRegister(InFlightRequests)
+	PrometheusRegistry.MustRegister(RequestRejectedCount)
+	PrometheusRegistry.MustRegister(APIKeyRequestCount)
+	PrometheusRegistry.MustRegister(AnalysisDuration)
+	PrometheusRegistry.MustRegister(FetchDuration)
+	PrometheusRegistry.MustRegister(AnalyzerDuration)
+	PrometheusRegistry.MustRegister(ResponseBodySize)
+	PrometheusRegistry.MustRegister(AnalysisErrorCount)
+	PrometheusRegistry.MustRegister(FetchStatusCount)
+	PrometheusRegistry.MustRegister(InFlightAnalyses)
+	PrometheusRegistry.MustRegister(MonitorUp)
+	PrometheusRegistry.MustRegister(MonitorAlerting)
+	PrometheusRegistry.MustRegister(MonitorLastRunTimestamp)
+	PrometheusRegistry.MustRegister(MonitorInaccessibleLinks)
+	PrometheusRegistry.MustRegister(MonitorAlertCount)
+	PrometheusRegistry.MustRegister(WebhookDeliveryCount)
+	PrometheusRegistry.MustRegister(ConditionalFetchCount)
+	PrometheusRegistry.MustRegister(AnalysisCoalescedCount)
+	PrometheusRegistry.MustRegister(TargetNotAllowedCount)
 }
@@ -0,0 +1,35 @@
+package metrics
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegisterMetrics(t *testing.T) {
+	RegisterMetrics()
+
+	// vectors only export series once a label combination is used
+	AnalyzerDuration.WithLabelValues("headings").Observe(0.001)
+	AnalysisErrorCount.WithLabelValues("fetch").Inc()
+
+	families, err := PrometheusRegistry.Gather()
+	assert.NoError(t, err)
+
+	names := make(map[string]bool, len(families))
+	for _, f := range families {
+		names[f.GetName()] = true
+	}
+	for _, name := range []string{
+		"analysis_duration_seconds",
+		"fetch_duration_seconds",
+		"analyzer_duration_seconds",
+		"response_body_size_bytes",
+		"analysis_error_count",
+		"in_flight_analyses",
+		"go_goroutines",
+		"process_cpu_seconds_total",
+	} {
+		assert.True(t, names[name], "missing metric %s", name)
+	}
+}
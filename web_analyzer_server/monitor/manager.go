@@ -0,0 +1,450 @@
+package monitor
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/sashithaf16/peekalo/analyzer"
+	"github.com/sashithaf16/peekalo/hostpolicy"
+	"github.com/sashithaf16/peekalo/logger"
+	"github.com/sashithaf16/peekalo/metrics"
+	"github.com/sashithaf16/peekalo/storage"
+	"github.com/sashithaf16/peekalo/urlnorm"
+	"github.com/sashithaf16/peekalo/webhook"
+)
+
+// runTimeout bounds a single scheduled analysis
+const runTimeout = 2 * time.Minute
+
+// previousResultsWindow bounds the stored results searched for the previous fetched analysis of a URL
+const previousResultsWindow = 10
+
+// PageAnalyzer analyzes a single page
+type PageAnalyzer interface {
+	AnalyzeURL(ctx context.Context, pageURL string) (analyzer.PageInfo, error)
+}
+
+// Manager keeps the registered monitors, runs each on its own schedule and stores the results.
+// Monitors are saved to a JSON file when a path is given so they survive restarts.
+type Manager struct {
+	logger      logger.Logger
+	analyzer    PageAnalyzer
+	store       storage.Store
+	path        string
+	minInterval time.Duration
+	webhooks    *webhook.Dispatcher
+	norm        *urlnorm.Normalizer
+	hosts       *hostpolicy.Policy
+
+	mu       sync.Mutex
+	monitors map[string]*Monitor
+	cancels  map[string]context.CancelFunc
+	ctx      context.Context // set by Start, nil while monitors are not scheduled
+	stop     context.CancelFunc
+	wg       sync.WaitGroup
+	now      func() time.Time
+}
+
+// Option configures optional collaborators of a Manager
+type Option func(*Manager)
+
+// WithWebhooks delivers alerts of monitors that have a webhook through d
+func WithWebhooks(d *webhook.Dispatcher) Option {
+	return func(m *Manager) {
+		m.webhooks = d
+	}
+}
+
+// WithNormalizer stores and looks up results under the URL's canonical form, matching results
+// stored by the API for other spellings of the same URL
+func WithNormalizer(n *urlnorm.Normalizer) Option {
+	return func(m *Manager) {
+		m.norm = n
+	}
+}
+
+// WithHostPolicy refuses monitors of URLs whose host the policy does not permit
+func WithHostPolicy(p *hostpolicy.Policy) Option {
+	return func(m *Manager) {
+		m.hosts = p
+	}
+}
+
+// NewManager loads the monitors saved at path; an empty path keeps monitors in memory only
+func NewManager(logger logger.Logger, pa PageAnalyzer, store storage.Store, path string, minInterval time.Duration, opts ...Option) (*Manager, error) {
+	m := &Manager{
+		logger:      logger,
+		analyzer:    pa,
+		store:       store,
+		path:        path,
+		minInterval: minInterval,
+		monitors:    make(map[string]*Monitor),
+		cancels:     make(map[string]context.CancelFunc),
+		now:         time.Now,
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	if path == "" {
+		return m, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return m, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read monitors file: %v", err)
+	}
+	var saved []*Monitor
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return nil, fmt.Errorf("failed to decode monitors file: %v", err)
+	}
+	for _, mon := range saved {
+		// saved monitors were valid when created, so the minimum interval is not re-applied
+		if _, err := mon.validate(0); err != nil {
+			return nil, fmt.Errorf("monitor %s: %v", mon.ID, err)
+		}
+		m.monitors[mon.ID] = mon
+	}
+	return m, nil
+}
+
+// Start schedules every monitor until Stop is called
+func (m *Manager) Start(ctx context.Context) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.ctx != nil {
+		return
+	}
+	m.ctx, m.stop = context.WithCancel(ctx)
+	for id := range m.monitors {
+		m.scheduleLocked(id)
+	}
+	m.logger.Info().Msgf("Scheduled %d monitors", len(m.monitors))
+}
+
+// Stop cancels all schedules and waits for running analyses to finish
+func (m *Manager) Stop() {
+	m.mu.Lock()
+	if m.stop != nil {
+		m.stop()
+	}
+	m.ctx = nil
+	m.cancels = make(map[string]context.CancelFunc)
+	m.mu.Unlock()
+	m.wg.Wait()
+}
+
+// Create validates and registers mon, assigning its ID and creation time
+func (m *Manager) Create(mon Monitor) (Monitor, error) {
+	if err := m.validate(&mon); err != nil {
+		return Monitor{}, err
+	}
+	id, err := newID()
+	if err != nil {
+		return Monitor{}, err
+	}
+	mon.ID = id
+	mon.CreatedAt = m.now().UTC()
+	mon.LastRun = nil
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.monitors[id] = &mon
+	if err := m.saveLocked(); err != nil {
+		delete(m.monitors, id)
+		return Monitor{}, err
+	}
+	m.scheduleLocked(id)
+	return mon, nil
+}
+
+// Update replaces the URL, schedule and conditions of a monitor and restarts its schedule
+func (m *Manager) Update(id string, mon Monitor) (Monitor, error) {
+	if err := m.validate(&mon); err != nil {
+		return Monitor{}, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	existing, ok := m.monitors[id]
+	if !ok {
+		return Monitor{}, ErrNotFound
+	}
+	mon.ID = existing.ID
+	mon.CreatedAt = existing.CreatedAt
+	mon.LastRun = existing.LastRun
+	m.monitors[id] = &mon
+	if err := m.saveLocked(); err != nil {
+		m.monitors[id] = existing
+		return Monitor{}, err
+	}
+	m.unscheduleLocked(id)
+	m.scheduleLocked(id)
+	return mon, nil
+}
+
+func (m *Manager) validate(mon *Monitor) error {
+	if _, err := mon.validate(m.minInterval); err != nil {
+		return err
+	}
+	if mon.Webhook != nil && m.webhooks == nil {
+		return fmt.Errorf("%w: webhooks are not enabled", ErrInvalid)
+	}
+	if mon.Webhook != nil {
+		if err := m.webhooks.CheckTarget(*mon.Webhook); err != nil {
+			return err
+		}
+	}
+	// the error wraps hostpolicy.ErrNotAllowed rather than ErrInvalid so it is reported as forbidden
+	return m.hosts.CheckURL(mon.URL)
+}
+
+// Delete removes a monitor and its metrics
+func (m *Manager) Delete(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	existing, ok := m.monitors[id]
+	if !ok {
+		return ErrNotFound
+	}
+	delete(m.monitors, id)
+	if err := m.saveLocked(); err != nil {
+		m.monitors[id] = existing
+		return err
+	}
+	m.unscheduleLocked(id)
+	deleteMetrics(id)
+	return nil
+}
+
+// Get returns a copy of the monitor with id, or ErrNotFound
+func (m *Manager) Get(id string) (Monitor, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	mon, ok := m.monitors[id]
+	if !ok {
+		return Monitor{}, ErrNotFound
+	}
+	return *mon, nil
+}
+
+// List returns all monitors, oldest first
+func (m *Manager) List() []Monitor {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	list := make([]Monitor, 0, len(m.monitors))
+	for _, mon := range m.monitors {
+		list = append(list, *mon)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].CreatedAt.Equal(list[j].CreatedAt) {
+			return list[i].ID < list[j].ID
+		}
+		return list[i].CreatedAt.Before(list[j].CreatedAt)
+	})
+	return list
+}
+
+// Run analyzes the monitored URL now, stores the result and evaluates the monitor's conditions
+func (m *Manager) Run(ctx context.Context, id string) (Run, error) {
+	mon, err := m.Get(id)
+	if err != nil {
+		return Run{}, err
+	}
+	log := logger.FromContext(ctx, m.logger).With().Str("monitor_id", id).Logger()
+
+	ctx, cancel := context.WithTimeout(ctx, runTimeout)
+	defer cancel()
+
+	// the previous result is read before this run's result is stored
+	resultURL := m.resultURL(mon.URL)
+	var prev *analyzer.PageInfo
+	if results, err := m.store.ListByURL(ctx, resultURL, previousResultsWindow); err != nil {
+		log.Warn().Err(err).Msgf("Failed to read previous result for monitored URL: %s", mon.URL)
+	} else {
+		// results of submitted documents would raise alerts for changes the page never had
+		for i := range results {
+			if results[i].Fetched() {
+				prev = &results[i].PageInfo
+				break
+			}
+		}
+	}
+
+	run := Run{Time: m.now().UTC()}
+	info, analyzeErr := m.analyzer.AnalyzeURL(ctx, mon.URL)
+	if analyzeErr != nil {
+		log.Warn().Err(analyzeErr).Msgf("Monitored URL analysis failed: %s", mon.URL)
+		run.Error = analyzeErr.Error()
+	} else {
+		run.StatusCode = info.StatusCode
+		result := &storage.Result{
+			URL:      resultURL,
+			Options:  map[string]string{"source": "monitor", "monitor_id": id},
+			PageInfo: info,
+		}
+		if err := m.store.Save(ctx, result); err != nil {
+			log.Error().Err(err).Msgf("Failed to store monitor result for URL: %s", mon.URL)
+		} else {
+			run.ResultID = result.ID
+		}
+	}
+	run.Alerts = Evaluate(mon.Conditions, prev, info, analyzeErr)
+	for _, alert := range run.Alerts {
+		log.Warn().Str("condition", alert.Condition).Msgf("Monitor alert for %s: %s", mon.URL, alert.Message)
+	}
+
+	m.recordRun(id, run, info, analyzeErr)
+	if len(run.Alerts) > 0 && mon.Webhook != nil && m.webhooks != nil {
+		if _, err := m.webhooks.Send(*mon.Webhook, webhook.EventMonitorAlert, AlertEvent{MonitorID: id, URL: mon.URL, Run: run}); err != nil {
+			log.Error().Err(err).Msg("Failed to queue monitor alert webhook")
+		}
+	}
+	return run, nil
+}
+
+// recordRun updates the monitor's last run and metrics, unless the monitor was deleted meanwhile
+func (m *Manager) recordRun(id string, run Run, info analyzer.PageInfo, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	mon, ok := m.monitors[id]
+	if !ok {
+		return
+	}
+	mon.LastRun = &run
+	if saveErr := m.saveLocked(); saveErr != nil {
+		m.logger.Error().Err(saveErr).Msgf("Failed to save last run of monitor: %s", id)
+	}
+
+	up := 0.0
+	if err == nil && info.StatusCode < 400 {
+		up = 1
+	}
+	alerting := 0.0
+	if len(run.Alerts) > 0 {
+		alerting = 1
+	}
+	metrics.MonitorUp.WithLabelValues(id).Set(up)
+	metrics.MonitorAlerting.WithLabelValues(id).Set(alerting)
+	metrics.MonitorLastRunTimestamp.WithLabelValues(id).Set(float64(run.Time.Unix()))
+	if err == nil {
+		metrics.MonitorInaccessibleLinks.WithLabelValues(id).Set(float64(info.Links.Inaccessible))
+	}
+	for _, alert := range run.Alerts {
+		metrics.MonitorAlertCount.WithLabelValues(id, alert.Condition).Inc()
+	}
+}
+
+func (m *Manager) scheduleLocked(id string) {
+	if m.ctx == nil {
+		return
+	}
+	// validated on create, update and load
+	sched, _ := m.monitors[id].validate(0)
+	ctx, cancel := context.WithCancel(m.ctx)
+	m.cancels[id] = cancel
+	m.wg.Add(1)
+	go m.loop(ctx, id, sched)
+}
+
+// resultURL is the URL results of a monitored page are stored under
+func (m *Manager) resultURL(pageURL string) string {
+	if m.norm == nil {
+		return pageURL
+	}
+	if canonical, err := m.norm.Normalize(pageURL); err == nil {
+		return canonical
+	}
+	return pageURL
+}
+
+func (m *Manager) unscheduleLocked(id string) {
+	if cancel, ok := m.cancels[id]; ok {
+		cancel()
+		delete(m.cancels, id)
+	}
+}
+
+// loop runs a monitor each time its schedule fires. Runs of one monitor never overlap.
+func (m *Manager) loop(ctx context.Context, id string, sched schedule) {
+	defer m.wg.Done()
+	for {
+		next := sched.Next(m.now().UTC())
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		if _, err := m.Run(ctx, id); err != nil {
+			m.logger.Error().Err(err).Msgf("Failed to run monitor: %s", id)
+		}
+	}
+}
+
+// saveLocked writes all monitors to the monitors file
+func (m *Manager) saveLocked() error {
+	if m.path == "" {
+		return nil
+	}
+	list := make([]*Monitor, 0, len(m.monitors))
+	for _, mon := range m.monitors {
+		list = append(list, mon)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	data, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode monitors: %v", err)
+	}
+
+	dir := filepath.Dir(m.path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create monitors directory: %v", err)
+	}
+	// write to a temporary file and rename so a crash never leaves a truncated file
+	tmp, err := os.CreateTemp(dir, ".monitors-*")
+	if err != nil {
+		return fmt.Errorf("failed to create monitors file: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write monitors file: %v", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write monitors file: %v", err)
+	}
+	if err := os.Rename(tmp.Name(), m.path); err != nil {
+		return fmt.Errorf("failed to store monitors file: %v", err)
+	}
+	return nil
+}
+
+func newID() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate monitor id: %v", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func deleteMetrics(id string) {
+	metrics.MonitorUp.DeleteLabelValues(id)
+	metrics.MonitorAlerting.DeleteLabelValues(id)
+	metrics.MonitorLastRunTimestamp.DeleteLabelValues(id)
+	metrics.MonitorInaccessibleLinks.DeleteLabelValues(id)
+	metrics.MonitorAlertCount.DeletePartialMatch(prometheus.Labels{"monitor_id": id})
+}
@@ -0,0 +1,145 @@
+package monitor
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/robfig/cron/v3"
+
+	"github.com/sashithaf16/peekalo/analyzer"
+	"github.com/sashithaf16/peekalo/webhook"
+)
+
+var (
+	ErrNotFound = errors.New("monitor not found")
+	// ErrInvalid is wrapped by every validation error so callers can tell bad input from failures
+	ErrInvalid = errors.New("invalid monitor")
+)
+
+// Condition names reported in alerts and metrics
+const (
+	ConditionPageBroken       = "page_broken"
+	ConditionTitleChanged     = "title_changed"
+	ConditionLoginDisappeared = "login_disappeared"
+	ConditionBrokenLinks      = "broken_links"
+)
+
+// Conditions selects the checks evaluated after every run
+type Conditions struct {
+	PageBroken       bool `json:"page_broken,omitempty"`       // the analysis failed or the page returned a 4xx or 5xx status
+	TitleChanged     bool `json:"title_changed,omitempty"`     // the title differs from the previous stored result of the URL
+	LoginDisappeared bool `json:"login_disappeared,omitempty"` // the previous stored result had a login form and this one does not
+	MaxBrokenLinks   *int `json:"max_broken_links,omitempty"`  // alert when more links than this are inaccessible
+}
+
+// Monitor is a URL analyzed on a schedule, either a fixed interval or a cron expression
+type Monitor struct {
+	ID         string          `json:"id"`
+	URL        string          `json:"url"`
+	Interval   string          `json:"interval,omitempty"` // Go duration such as "15m"
+	Cron       string          `json:"cron,omitempty"`     // standard five field cron expression, evaluated in UTC
+	Conditions Conditions      `json:"conditions"`
+	Webhook    *webhook.Target `json:"webhook,omitempty"` // notified of runs that raise alerts
+	CreatedAt  time.Time       `json:"created_at"`
+	LastRun    *Run            `json:"last_run,omitempty"`
+}
+
+// Run is the outcome of one analysis of a monitored URL
+type Run struct {
+	Time       time.Time `json:"time"`
+	StatusCode int       `json:"status_code,omitempty"`
+	ResultID   string    `json:"result_id,omitempty"` // stored analysis, empty when the analysis failed
+	Error      string    `json:"error,omitempty"`
+	Alerts     []Alert   `json:"alerts,omitempty"`
+}
+
+// AlertEvent is the webhook payload data sent when a run raises alerts
+type AlertEvent struct {
+	MonitorID string `json:"monitor_id"`
+	URL       string `json:"url"`
+	Run       Run    `json:"run"`
+}
+
+// Alert is a condition that held on a run
+type Alert struct {
+	Condition string `json:"condition"`
+	Message   string `json:"message"`
+}
+
+// schedule returns the next activation time after t
+type schedule interface {
+	Next(t time.Time) time.Time
+}
+
+type intervalSchedule time.Duration
+
+func (s intervalSchedule) Next(t time.Time) time.Time {
+	return t.Add(time.Duration(s))
+}
+
+// validate checks m and returns its schedule. Intervals shorter than minInterval are rejected.
+func (m *Monitor) validate(minInterval time.Duration) (schedule, error) {
+	u, err := url.Parse(m.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("%w: url must be an absolute http or https URL", ErrInvalid)
+	}
+	if m.Webhook != nil {
+		if err := m.Webhook.Validate(); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalid, err)
+		}
+	}
+	if m.Conditions.MaxBrokenLinks != nil && *m.Conditions.MaxBrokenLinks < 0 {
+		return nil, fmt.Errorf("%w: max_broken_links must not be negative", ErrInvalid)
+	}
+
+	switch {
+	case m.Interval != "" && m.Cron != "":
+		return nil, fmt.Errorf("%w: set either interval or cron, not both", ErrInvalid)
+	case m.Interval != "":
+		d, err := time.ParseDuration(m.Interval)
+		if err != nil {
+			return nil, fmt.Errorf("%w: interval: %v", ErrInvalid, err)
+		}
+		if d < minInterval {
+			return nil, fmt.Errorf("%w: interval must be at least %s", ErrInvalid, minInterval)
+		}
+		return intervalSchedule(d), nil
+	case m.Cron != "":
+		s, err := cron.ParseStandard(m.Cron)
+		if err != nil {
+			return nil, fmt.Errorf("%w: cron: %v", ErrInvalid, err)
+		}
+		return s, nil
+	default:
+		return nil, fmt.Errorf("%w: interval or cron is required", ErrInvalid)
+	}
+}
+
+// Evaluate returns the alerts raised by conditions for an analysis of the URL.
+// prev is the previous stored result and may be nil; cur is ignored when err is set.
+func Evaluate(conditions Conditions, prev *analyzer.PageInfo, cur analyzer.PageInfo, err error) []Alert {
+	var alerts []Alert
+	if err != nil {
+		// the other conditions need a page to compare
+		if conditions.PageBroken {
+			alerts = append(alerts, Alert{Condition: ConditionPageBroken, Message: "analysis failed: " + err.Error()})
+		}
+		return alerts
+	}
+
+	if conditions.PageBroken && cur.StatusCode >= 400 {
+		alerts = append(alerts, Alert{Condition: ConditionPageBroken, Message: fmt.Sprintf("page returned status %d", cur.StatusCode)})
+	}
+	if conditions.TitleChanged && prev != nil && prev.Title != cur.Title {
+		alerts = append(alerts, Alert{Condition: ConditionTitleChanged, Message: fmt.Sprintf("title changed from %q to %q", prev.Title, cur.Title)})
+	}
+	if conditions.LoginDisappeared && prev != nil && prev.HasLogin && !cur.HasLogin {
+		alerts = append(alerts, Alert{Condition: ConditionLoginDisappeared, Message: "login form is no longer present"})
+	}
+	if max := conditions.MaxBrokenLinks; max != nil && cur.Links.Inaccessible > *max {
+		alerts = append(alerts, Alert{Condition: ConditionBrokenLinks, Message: fmt.Sprintf("%d inaccessible links, more than %d", cur.Links.Inaccessible, *max)})
+	}
+	return alerts
+}
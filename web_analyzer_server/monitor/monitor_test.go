@@ -0,0 +1,237 @@
+package monitor
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sashithaf16/peekalo/analyzer"
+	"github.com/sashithaf16/peekalo/hostpolicy"
+	"github.com/sashithaf16/peekalo/logger"
+	"github.com/sashithaf16/peekalo/storage"
+	"github.com/sashithaf16/peekalo/webhook"
+)
+
+// fakeAnalyzer returns its pages in order, repeating the last one
+type fakeAnalyzer struct {
+	pages []analyzer.PageInfo
+	err   error
+	calls int
+}
+
+func (f *fakeAnalyzer) AnalyzeURL(ctx context.Context, pageURL string) (analyzer.PageInfo, error) {
+	defer func() { f.calls++ }()
+	if f.err != nil {
+		return analyzer.PageInfo{}, f.err
+	}
+	if f.calls < len(f.pages) {
+		return f.pages[f.calls], nil
+	}
+	return f.pages[len(f.pages)-1], nil
+}
+
+func TestValidate(t *testing.T) {
+	for name, tc := range map[string]struct {
+		monitor Monitor
+		valid   bool
+	}{
+		"interval":          {Monitor{URL: "https://example.com", Interval: "5m"}, true},
+		"cron":              {Monitor{URL: "https://example.com", Cron: "*/15 * * * *"}, true},
+		"both":              {Monitor{URL: "https://example.com", Interval: "5m", Cron: "* * * * *"}, false},
+		"neither":           {Monitor{URL: "https://example.com"}, false},
+		"short interval":    {Monitor{URL: "https://example.com", Interval: "10s"}, false},
+		"bad cron":          {Monitor{URL: "https://example.com", Cron: "every minute"}, false},
+		"relative url":      {Monitor{URL: "/page", Interval: "5m"}, false},
+		"negative max link": {Monitor{URL: "https://example.com", Interval: "5m", Conditions: Conditions{MaxBrokenLinks: intPtr(-1)}}, false},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := tc.monitor.validate(time.Minute)
+			if tc.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, ErrInvalid)
+			}
+		})
+	}
+
+	s, err := (&Monitor{URL: "https://example.com", Cron: "0 * * * *"}).validate(time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2025, 6, 1, 11, 0, 0, 0, time.UTC), s.Next(time.Date(2025, 6, 1, 10, 20, 0, 0, time.UTC)))
+}
+
+func TestEvaluate(t *testing.T) {
+	all := Conditions{PageBroken: true, TitleChanged: true, LoginDisappeared: true, MaxBrokenLinks: intPtr(2)}
+	prev := &analyzer.PageInfo{StatusCode: 200, Title: "Home", HasLogin: true}
+
+	alerts := Evaluate(all, prev, analyzer.PageInfo{StatusCode: 200, Title: "Home", HasLogin: true}, nil)
+	assert.Empty(t, alerts)
+
+	alerts = Evaluate(all, prev, analyzer.PageInfo{StatusCode: 503, Title: "Maintenance", Links: analyzer.LinkStats{Inaccessible: 3}}, nil)
+	var conditions []string
+	for _, a := range alerts {
+		conditions = append(conditions, a.Condition)
+	}
+	assert.Equal(t, []string{ConditionPageBroken, ConditionTitleChanged, ConditionLoginDisappeared, ConditionBrokenLinks}, conditions)
+
+	alerts = Evaluate(all, nil, analyzer.PageInfo{}, errors.New("connection refused"))
+	require.Len(t, alerts, 1)
+	assert.Equal(t, ConditionPageBroken, alerts[0].Condition)
+
+	// without a previous result there is nothing to compare against
+	assert.Empty(t, Evaluate(Conditions{TitleChanged: true, LoginDisappeared: true}, nil, analyzer.PageInfo{Title: "New"}, nil))
+}
+
+func TestManager(t *testing.T) {
+	ctx := context.Background()
+	log := logger.CreateLogger("debug")
+	store := storage.NewMemoryStore()
+	path := filepath.Join(t.TempDir(), "monitors.json")
+	pa := &fakeAnalyzer{pages: []analyzer.PageInfo{
+		{StatusCode: 200, Title: "Home"},
+		{StatusCode: 200, Title: "Renamed"},
+	}}
+
+	m, err := NewManager(log, pa, store, path, time.Minute)
+	require.NoError(t, err)
+
+	_, err = m.Create(Monitor{URL: "https://example.com", Interval: "1s"})
+	assert.ErrorIs(t, err, ErrInvalid)
+
+	mon, err := m.Create(Monitor{URL: "https://example.com", Interval: "1h", Conditions: Conditions{TitleChanged: true}})
+	require.NoError(t, err)
+	assert.NotEmpty(t, mon.ID)
+
+	run, err := m.Run(ctx, mon.ID)
+	require.NoError(t, err)
+	assert.NotEmpty(t, run.ResultID)
+	assert.Empty(t, run.Alerts)
+
+	run, err = m.Run(ctx, mon.ID)
+	require.NoError(t, err)
+	require.Len(t, run.Alerts, 1)
+	assert.Equal(t, ConditionTitleChanged, run.Alerts[0].Condition)
+
+	results, err := store.ListByURL(ctx, "https://example.com", 0)
+	require.NoError(t, err)
+	assert.Len(t, results, 2)
+	assert.Equal(t, mon.ID, results[0].Options["monitor_id"])
+
+	updated, err := m.Update(mon.ID, Monitor{URL: "https://example.com", Cron: "0 * * * *"})
+	require.NoError(t, err)
+	assert.Equal(t, mon.CreatedAt, updated.CreatedAt)
+	assert.NotNil(t, updated.LastRun)
+
+	// monitors and their last run survive a restart
+	reloaded, err := NewManager(log, pa, store, path, time.Minute)
+	require.NoError(t, err)
+	got, err := reloaded.Get(mon.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "0 * * * *", got.Cron)
+	assert.Equal(t, run.ResultID, got.LastRun.ResultID)
+
+	require.NoError(t, m.Delete(mon.ID))
+	assert.ErrorIs(t, m.Delete(mon.ID), ErrNotFound)
+	_, err = m.Run(ctx, mon.ID)
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.Empty(t, m.List())
+}
+
+func TestManagerSchedule(t *testing.T) {
+	pa := &fakeAnalyzer{err: errors.New("connection refused")}
+	m, err := NewManager(logger.CreateLogger("debug"), pa, storage.NewMemoryStore(), "", 0)
+	require.NoError(t, err)
+
+	mon, err := m.Create(Monitor{URL: "https://example.com", Interval: "10ms", Conditions: Conditions{PageBroken: true}})
+	require.NoError(t, err)
+
+	m.Start(context.Background())
+	assert.Eventually(t, func() bool {
+		got, _ := m.Get(mon.ID)
+		return got.LastRun != nil
+	}, time.Second, 5*time.Millisecond)
+	m.Stop()
+
+	got, err := m.Get(mon.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "connection refused", got.LastRun.Error)
+	require.Len(t, got.LastRun.Alerts, 1)
+	assert.Equal(t, ConditionPageBroken, got.LastRun.Alerts[0].Condition)
+}
+
+func TestManagerWebhook(t *testing.T) {
+	received := make(chan string, 1)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- r.Header.Get(webhook.EventHeader)
+	}))
+	defer receiver.Close()
+
+	log := logger.CreateLogger("debug")
+	pa := &fakeAnalyzer{pages: []analyzer.PageInfo{{StatusCode: 404}}}
+	target := &webhook.Target{URL: receiver.URL, Secret: "s3cret"}
+
+	withoutWebhooks, err := NewManager(log, pa, storage.NewMemoryStore(), "", 0)
+	require.NoError(t, err)
+	_, err = withoutWebhooks.Create(Monitor{URL: "https://example.com", Interval: "1h", Webhook: target})
+	assert.ErrorIs(t, err, ErrInvalid)
+
+	webhooks := webhook.NewDispatcher(log, http.DefaultClient, "", 1, time.Second)
+	defer webhooks.Close()
+	m, err := NewManager(log, pa, storage.NewMemoryStore(), "", 0, WithWebhooks(webhooks))
+	require.NoError(t, err)
+	mon, err := m.Create(Monitor{URL: "https://example.com", Interval: "1h", Conditions: Conditions{PageBroken: true}, Webhook: target})
+	require.NoError(t, err)
+
+	run, err := m.Run(context.Background(), mon.ID)
+	require.NoError(t, err)
+	require.Len(t, run.Alerts, 1)
+	assert.Equal(t, webhook.EventMonitorAlert, <-received)
+}
+
+func TestManagerHostPolicy(t *testing.T) {
+	hosts, err := hostpolicy.New(nil, []string{"*.internal"})
+	require.NoError(t, err)
+	m, err := NewManager(logger.CreateLogger("debug"), &fakeAnalyzer{}, storage.NewMemoryStore(), "", 0, WithHostPolicy(hosts))
+	require.NoError(t, err)
+
+	_, err = m.Create(Monitor{URL: "https://wiki.internal/", Interval: "1h"})
+	assert.ErrorIs(t, err, hostpolicy.ErrNotAllowed)
+	mon, err := m.Create(Monitor{URL: "https://example.com", Interval: "1h"})
+	require.NoError(t, err)
+	_, err = m.Update(mon.ID, Monitor{URL: "https://wiki.internal/", Interval: "1h"})
+	assert.ErrorIs(t, err, hostpolicy.ErrNotAllowed)
+}
+
+func intPtr(n int) *int {
+	return &n
+}
+
+func TestManagerIgnoresSubmittedDocuments(t *testing.T) {
+	ctx := context.Background()
+	store := storage.NewMemoryStore()
+	pa := &fakeAnalyzer{pages: []analyzer.PageInfo{{StatusCode: 200, Title: "Home"}}}
+	m, err := NewManager(logger.CreateLogger("debug"), pa, store, filepath.Join(t.TempDir(), "monitors.json"), time.Minute)
+	require.NoError(t, err)
+	mon, err := m.Create(Monitor{URL: "https://example.com", Interval: "1h", Conditions: Conditions{TitleChanged: true}})
+	require.NoError(t, err)
+
+	_, err = m.Run(ctx, mon.ID)
+	require.NoError(t, err)
+	// raw HTML analyzed for the same URL must not become the previous result
+	require.NoError(t, store.Save(ctx, &storage.Result{
+		URL:       "https://example.com",
+		CreatedAt: time.Now().Add(time.Minute),
+		Options:   map[string]string{"source": "html"},
+		PageInfo:  analyzer.PageInfo{Title: "Draft"},
+	}))
+
+	run, err := m.Run(ctx, mon.ID)
+	require.NoError(t, err)
+	assert.Empty(t, run.Alerts)
+}
@@ -0,0 +1,115 @@
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// idleTimeout is how long a client bucket is kept after its last request
+const idleTimeout = 10 * time.Minute
+
+type clientBucket struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// Limiter is a token-bucket rate limiter keyed by client (IP address or API key)
+type Limiter struct {
+	rps   rate.Limit
+	burst int
+
+	mu        sync.Mutex
+	clients   map[string]*clientBucket
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+func NewLimiter(rps float64, burst int) *Limiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &Limiter{
+		rps:     rate.Limit(rps),
+		burst:   burst,
+		clients: make(map[string]*clientBucket),
+		now:     time.Now,
+	}
+}
+
+// Allow takes a token from the client's bucket. When the bucket is empty it returns
+// false and how long the client should wait before retrying.
+func (l *Limiter) Allow(client string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+
+	bucket, ok := l.clients[client]
+	if !ok {
+		bucket = &clientBucket{limiter: rate.NewLimiter(l.rps, l.burst)}
+		l.clients[client] = bucket
+	}
+	bucket.lastSeen = now
+
+	reservation := bucket.limiter.ReserveN(now, 1)
+	if delay := reservation.DelayFrom(now); delay > 0 {
+		reservation.CancelAt(now)
+		return false, delay
+	}
+	return true, 0
+}
+
+// sweep drops idle client buckets so the map does not grow without bound. Callers hold l.mu.
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < idleTimeout {
+		return
+	}
+	l.lastSweep = now
+	for client, bucket := range l.clients {
+		if now.Sub(bucket.lastSeen) > idleTimeout {
+			delete(l.clients, client)
+		}
+	}
+}
+
+// InFlight caps the number of concurrently running operations
+type InFlight struct {
+	slots chan struct{}
+}
+
+func NewInFlight(max int) *InFlight {
+	return &InFlight{slots: make(chan struct{}, max)}
+}
+
+// TryAcquire takes a slot without blocking and reports whether one was available
+func (f *InFlight) TryAcquire() bool {
+	select {
+	case f.slots <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+func (f *InFlight) Release() {
+	<-f.slots
+}
+
+// Len returns the number of slots in use
+func (f *InFlight) Len() int {
+	return len(f.slots)
+}
+
+// Cap returns the total number of slots
+func (f *InFlight) Cap() int {
+	return cap(f.slots)
+}
+
+// RetryAfterSeconds rounds a wait duration up to the whole seconds used by the Retry-After header
+func RetryAfterSeconds(d time.Duration) int {
+	return int(math.Max(1, math.Ceil(d.Seconds())))
+}
@@ -0,0 +1,60 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLimiter_PerClientBuckets(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	l := NewLimiter(1, 2)
+	l.now = func() time.Time { return now }
+
+	ok, _ := l.Allow("10.0.0.1")
+	assert.True(t, ok)
+	ok, _ = l.Allow("10.0.0.1")
+	assert.True(t, ok)
+
+	ok, wait := l.Allow("10.0.0.1")
+	assert.False(t, ok, "burst should be exhausted")
+	assert.Equal(t, time.Second, wait)
+
+	ok, _ = l.Allow("10.0.0.2")
+	assert.True(t, ok, "other clients have their own bucket")
+
+	now = now.Add(time.Second)
+	ok, _ = l.Allow("10.0.0.1")
+	assert.True(t, ok, "bucket refills over time")
+}
+
+func TestLimiter_SweepsIdleClients(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	l := NewLimiter(1, 1)
+	l.now = func() time.Time { return now }
+
+	l.Allow("10.0.0.1")
+	now = now.Add(2 * idleTimeout)
+	l.Allow("10.0.0.2")
+
+	assert.NotContains(t, l.clients, "10.0.0.1")
+	assert.Contains(t, l.clients, "10.0.0.2")
+}
+
+func TestInFlight(t *testing.T) {
+	f := NewInFlight(2)
+	assert.True(t, f.TryAcquire())
+	assert.True(t, f.TryAcquire())
+	assert.False(t, f.TryAcquire())
+	assert.Equal(t, 2, f.Len())
+
+	f.Release()
+	assert.True(t, f.TryAcquire())
+}
+
+func TestRetryAfterSeconds(t *testing.T) {
+	assert.Equal(t, 1, RetryAfterSeconds(0))
+	assert.Equal(t, 1, RetryAfterSeconds(300*time.Millisecond))
+	assert.Equal(t, 3, RetryAfterSeconds(2100*time.Millisecond))
+}
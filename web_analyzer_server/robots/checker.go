@@ -0,0 +1,114 @@
+package robots
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/sashithaf16/peekalo/logger"
+)
+
+type HttpClientInterface interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+type cacheEntry struct {
+	rules     *Rules
+	expiresAt time.Time
+}
+
+// Checker fetches robots.txt files and caches the parsed rules per scheme and host
+type Checker struct {
+	logger     logger.Logger
+	httpClient HttpClientInterface
+	userAgent  string
+	ttl        time.Duration
+
+	mu    sync.Mutex
+	cache map[string]cacheEntry
+	now   func() time.Time
+}
+
+func NewChecker(logger logger.Logger, httpClient HttpClientInterface, userAgent string, ttl time.Duration) *Checker {
+	return &Checker{
+		logger:     logger,
+		httpClient: httpClient,
+		userAgent:  userAgent,
+		ttl:        ttl,
+		cache:      make(map[string]cacheEntry),
+		now:        time.Now,
+	}
+}
+
+// UserAgent returns the user agent the checker matches rules against
+func (c *Checker) UserAgent() string {
+	return c.userAgent
+}
+
+// Allowed reports whether the checker's user agent may fetch u
+func (c *Checker) Allowed(ctx context.Context, u *url.URL) (bool, error) {
+	rules, err := c.Rules(ctx, u)
+	if err != nil {
+		return false, err
+	}
+	return rules.Allowed(c.userAgent, u.RequestURI()), nil
+}
+
+// Rules returns the robots.txt rules that apply to u, fetching them if they are not cached.
+// Per RFC 9309 a missing robots.txt (4xx) allows everything and an unreachable one (5xx) disallows everything.
+func (c *Checker) Rules(ctx context.Context, u *url.URL) (*Rules, error) {
+	key := u.Scheme + "://" + u.Host
+
+	c.mu.Lock()
+	entry, ok := c.cache[key]
+	c.mu.Unlock()
+	if ok && c.now().Before(entry.expiresAt) {
+		return entry.rules, nil
+	}
+
+	rules, err := c.fetch(ctx, key+"/robots.txt")
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	c.cache[key] = cacheEntry{rules: rules, expiresAt: c.now().Add(c.ttl)}
+	c.mu.Unlock()
+	return rules, nil
+}
+
+func (c *Checker) fetch(ctx context.Context, robotsURL string) (*Rules, error) {
+	log := logger.FromContext(ctx, c.logger)
+	log.Debug().Msgf("Fetching robots.txt: %s", robotsURL)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", robotsURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create robots.txt request: %v", err)
+	}
+	if c.userAgent != "" {
+		req.Header.Set("User-Agent", c.userAgent)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("failed to fetch robots.txt: %v", err)
+		}
+		log.Warn().Err(err).Msgf("robots.txt unreachable, disallowing all: %s", robotsURL)
+		return DisallowAll(), nil
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return Parse(resp.Body), nil
+	case resp.StatusCode >= 400 && resp.StatusCode < 500:
+		return AllowAll(), nil
+	default:
+		log.Warn().Msgf("robots.txt returned status %d, disallowing all: %s", resp.StatusCode, robotsURL)
+		return DisallowAll(), nil
+	}
+}
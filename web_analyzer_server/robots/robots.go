@@ -0,0 +1,187 @@
+package robots
+
+import (
+	"bufio"
+	"io"
+	"strings"
+)
+
+// Policy controls how the result of a robots.txt check is applied
+type Policy string
+
+const (
+	PolicyEnforce Policy = "enforce" // disallowed URLs are not fetched
+	PolicyWarn    Policy = "warn"    // disallowed URLs are fetched but a warning is logged
+	PolicyIgnore  Policy = "ignore"  // robots.txt is never fetched
+)
+
+// Enabled reports whether robots.txt should be consulted at all under the policy
+func (p Policy) Enabled() bool {
+	return p == PolicyEnforce || p == PolicyWarn
+}
+
+// CommonUserAgents are the crawlers reported on in the analysis result
+var CommonUserAgents = []string{"*", "Googlebot", "Bingbot"}
+
+// maxRobotsSize is the parsing limit recommended by RFC 9309
+const maxRobotsSize = 500 * 1024
+
+type rule struct {
+	allow bool
+	path  string
+}
+
+type group struct {
+	agents []string
+	rules  []rule
+}
+
+// Rules is a parsed robots.txt file
+type Rules struct {
+	groups   []group
+	Sitemaps []string
+}
+
+// AllowAll returns rules that allow every path, used when robots.txt is missing
+func AllowAll() *Rules {
+	return &Rules{}
+}
+
+// DisallowAll returns rules that disallow every path, used when robots.txt is unreachable
+func DisallowAll() *Rules {
+	return &Rules{groups: []group{{agents: []string{"*"}, rules: []rule{{allow: false, path: "/"}}}}}
+}
+
+// Parse reads a robots.txt body. Unknown directives and malformed lines are skipped.
+// reference: https://www.rfc-editor.org/rfc/rfc9309.html
+func Parse(r io.Reader) *Rules {
+	rules := &Rules{}
+	scanner := bufio.NewScanner(io.LimitReader(r, maxRobotsSize))
+
+	var current *group
+	lastWasAgent := false
+
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+
+		switch key {
+		case "user-agent":
+			// consecutive user-agent lines share the same group
+			if current == nil || !lastWasAgent {
+				rules.groups = append(rules.groups, group{})
+				current = &rules.groups[len(rules.groups)-1]
+			}
+			current.agents = append(current.agents, strings.ToLower(value))
+			lastWasAgent = true
+		case "allow", "disallow":
+			lastWasAgent = false
+			if current == nil {
+				continue
+			}
+			// an empty disallow allows everything and carries no rule
+			if value == "" {
+				continue
+			}
+			current.rules = append(current.rules, rule{allow: key == "allow", path: value})
+		case "sitemap":
+			if value != "" {
+				rules.Sitemaps = append(rules.Sitemaps, value)
+			}
+		default:
+			lastWasAgent = false
+		}
+	}
+	return rules
+}
+
+// Allowed reports whether userAgent may fetch path (path plus optional query).
+// The most specific matching group is used, falling back to "*", and the longest
+// matching rule wins with allow preferred on ties.
+func (r *Rules) Allowed(userAgent, path string) bool {
+	if path == "" {
+		path = "/"
+	}
+	g := r.groupFor(userAgent)
+	if g == nil {
+		return true
+	}
+
+	allowed, matchedLen := true, -1
+	for _, rl := range g.rules {
+		if !matchPath(rl.path, path) {
+			continue
+		}
+		if len(rl.path) > matchedLen || (len(rl.path) == matchedLen && rl.allow) {
+			allowed, matchedLen = rl.allow, len(rl.path)
+		}
+	}
+	return allowed
+}
+
+func (r *Rules) groupFor(userAgent string) *group {
+	// only the product token is matched, e.g. "Googlebot" from "Googlebot/2.1"
+	token := strings.ToLower(userAgent)
+	if i := strings.IndexAny(token, "/ "); i >= 0 {
+		token = token[:i]
+	}
+
+	var best, wildcard *group
+	bestLen := 0
+	for i := range r.groups {
+		g := &r.groups[i]
+		for _, agent := range g.agents {
+			if agent == "*" {
+				if wildcard == nil {
+					wildcard = g
+				}
+				continue
+			}
+			if token != "" && strings.Contains(token, agent) && len(agent) > bestLen {
+				best, bestLen = g, len(agent)
+			}
+		}
+	}
+	if best != nil {
+		return best
+	}
+	return wildcard
+}
+
+// matchPath matches a robots.txt path pattern supporting "*" wildcards and a trailing "$" anchor
+func matchPath(pattern, path string) bool {
+	anchored := strings.HasSuffix(pattern, "$")
+	if anchored {
+		pattern = strings.TrimSuffix(pattern, "$")
+	}
+
+	parts := strings.Split(pattern, "*")
+	if !strings.HasPrefix(path, parts[0]) {
+		return false
+	}
+	rest := path[len(parts[0]):]
+	for _, part := range parts[1:] {
+		i := strings.Index(rest, part)
+		if i < 0 {
+			return false
+		}
+		rest = rest[i+len(part):]
+	}
+	if !anchored {
+		return true
+	}
+	// with an anchor the last literal part must end the path
+	last := parts[len(parts)-1]
+	if len(parts) == 1 {
+		return rest == ""
+	}
+	return last == "" || strings.HasSuffix(path, last)
+}
@@ -0,0 +1,101 @@
+package robots
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	mocks "github.com/sashithaf16/peekalo/_mocks"
+	"github.com/sashithaf16/peekalo/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+const robotsTxt = `
+# example robots.txt
+User-agent: *
+Disallow: /private/
+Allow: /private/public
+Disallow: /*.pdf$
+
+User-agent: Googlebot
+User-agent: Bingbot
+Disallow: /no-search/
+
+Sitemap: https://example.com/sitemap.xml
+Sitemap: https://example.com/news-sitemap.xml
+`
+
+func TestParse_Allowed(t *testing.T) {
+	rules := Parse(strings.NewReader(robotsTxt))
+
+	assert.Equal(t, []string{"https://example.com/sitemap.xml", "https://example.com/news-sitemap.xml"}, rules.Sitemaps)
+
+	tests := []struct {
+		agent   string
+		path    string
+		allowed bool
+	}{
+		{"Peekalo/1.0", "/", true},
+		{"Peekalo/1.0", "/private/secret", false},
+		{"Peekalo/1.0", "/private/public/page", true},
+		{"Peekalo/1.0", "/docs/file.pdf", false},
+		{"Peekalo/1.0", "/docs/file.pdf?download=1", true},
+		{"Googlebot/2.1", "/private/secret", true},
+		{"Googlebot/2.1", "/no-search/page", false},
+		{"bingbot", "/no-search/page", false},
+		{"Peekalo/1.0", "/no-search/page", true},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.allowed, rules.Allowed(tt.agent, tt.path), "agent %q path %q", tt.agent, tt.path)
+	}
+}
+
+func TestParse_EmptyDisallowAllowsAll(t *testing.T) {
+	rules := Parse(strings.NewReader("User-agent: *\nDisallow:\n"))
+	assert.True(t, rules.Allowed("Peekalo", "/anything"))
+}
+
+func TestChecker_StatusHandlingAndCache(t *testing.T) {
+	log := logger.CreateLogger("debug")
+
+	tests := []struct {
+		name       string
+		statusCode int
+		body       string
+		allowed    bool
+	}{
+		{"parsed on 200", 200, "User-agent: *\nDisallow: /\n", false},
+		{"allow all on 404", 404, "", true},
+		{"disallow all on 503", 503, "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockClient := new(mocks.MockHTTPClient)
+			mockClient.On("Do", mock.MatchedBy(func(req *http.Request) bool {
+				return req.URL.String() == "https://example.com/robots.txt"
+			})).Return(&http.Response{
+				StatusCode: tt.statusCode,
+				Body:       io.NopCloser(strings.NewReader(tt.body)),
+			}, nil).Once()
+
+			checker := NewChecker(log, mockClient, "Peekalo/1.0", time.Minute)
+			pageURL, _ := url.Parse("https://example.com/page")
+
+			allowed, err := checker.Allowed(context.Background(), pageURL)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.allowed, allowed)
+
+			// second lookup is served from the cache
+			allowed, err = checker.Allowed(context.Background(), pageURL)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.allowed, allowed)
+
+			mockClient.AssertExpectations(t)
+		})
+	}
+}
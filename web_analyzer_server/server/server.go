@@ -0,0 +1,288 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/cors"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/sashithaf16/peekalo/auth"
+	"github.com/sashithaf16/peekalo/config"
+	"github.com/sashithaf16/peekalo/handler"
+	"github.com/sashithaf16/peekalo/health"
+	"github.com/sashithaf16/peekalo/hostpolicy"
+	"github.com/sashithaf16/peekalo/logger"
+	"github.com/sashithaf16/peekalo/metrics"
+	"github.com/sashithaf16/peekalo/monitor"
+	"github.com/sashithaf16/peekalo/ratelimit"
+	"github.com/sashithaf16/peekalo/snapshot"
+	"github.com/sashithaf16/peekalo/storage"
+	"github.com/sashithaf16/peekalo/tracing"
+	"github.com/sashithaf16/peekalo/urlnorm"
+	"github.com/sashithaf16/peekalo/webhook"
+)
+
+// Run starts the HTTP API and blocks until SIGINT or SIGTERM has shut it down gracefully
+func Run(cfg *config.Config, logger logger.Logger) {
+	if err := cfg.Validate(); err != nil {
+		logger.Error().Err(err).Msg("Invalid configuration")
+		panic(err)
+	}
+	if cfg.AuthKeywordsFile != "" {
+		keywords, err := config.LoadAuthKeywords(cfg.AuthKeywordsFile)
+		if err != nil {
+			logger.Error().Err(err).Msg("Failed to load auth keywords")
+			panic(err)
+		}
+		cfg.AuthKeywords = keywords
+	}
+
+	r := chi.NewRouter()
+	r.Use(handler.RequestLogger(logger)) // replaces chi's text logger with a structured access log
+	r.Use(handler.Tracing(logger))
+	r.Use(cors.Handler(getCORSOptions(cfg)))
+	r.Use(middleware.Recoverer)
+
+	metrics.RegisterMetrics()
+
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg, logger)
+	if err != nil {
+		logger.Error().Err(err).Msg("Failed to set up tracing")
+		panic(err)
+	}
+
+	authStore := getAuthStore(cfg, logger)
+	resultStore := getResultStore(cfg, logger)
+	snapshotStore := getSnapshotStore(cfg, logger)
+
+	hc := health.New()
+	var limiter *ratelimit.Limiter
+	if cfg.RateLimitRPS > 0 {
+		limiter = ratelimit.NewLimiter(cfg.RateLimitRPS, cfg.RateLimitBurst)
+	}
+	var inFlight *ratelimit.InFlight
+	if cfg.MaxInFlight > 0 {
+		inFlight = ratelimit.NewInFlight(cfg.MaxInFlight)
+		hc.AddComponent("worker_pool", func(ctx context.Context) error {
+			if inFlight.Len() >= inFlight.Cap() {
+				return fmt.Errorf("all %d analysis slots in use", inFlight.Cap())
+			}
+			return nil
+		})
+	}
+
+	hc.AddComponent("result_store", resultStore.Ping)
+	if snapshotStore != nil {
+		hc.AddComponent("snapshot_store", snapshotStore.Ping)
+	}
+
+	healthHandler := handler.NewHealthHandler(logger, hc)
+	r.Get("/healthz", healthHandler.LivenessHandler)
+	r.Get("/livez", healthHandler.LivenessHandler)
+	r.Get("/readyz", healthHandler.ReadinessHandler)
+	r.Handle("/metrics", promhttp.HandlerFor(metrics.PrometheusRegistry, promhttp.HandlerOpts{}))
+	// the host lists were validated with the rest of the config
+	webhookHosts, _ := cfg.WebhookHosts()
+	webhookClient := http.DefaultClient
+	if webhookHosts.Enabled() {
+		webhookClient = webhookHosts.Client(&http.Client{})
+	}
+	webhooks := webhook.NewDispatcher(logger, webhookClient, cfg.UserAgent, cfg.WebhookMaxAttempts, time.Duration(cfg.WebhookTimeout)*time.Second, webhook.WithHostPolicy(webhookHosts))
+	hosts, _ := hostpolicy.New(cfg.AllowedHosts, cfg.DeniedHosts)
+	fetchClient := http.DefaultClient
+	if hosts.Enabled() {
+		fetchClient = hosts.Client(&http.Client{})
+	}
+	analyzeOpts := []handler.Option{handler.WithResultStore(resultStore), handler.WithWebhooks(webhooks), handler.WithHostPolicy(hosts)}
+	if inFlight != nil {
+		analyzeOpts = append(analyzeOpts, handler.WithInFlight(inFlight))
+	}
+	if authStore != nil {
+		analyzeOpts = append(analyzeOpts, handler.WithKeyQuotas(authStore))
+	}
+	if snapshotStore != nil {
+		analyzeOpts = append(analyzeOpts, handler.WithSnapshotStore(snapshotStore))
+	}
+	analyzeHandler := handler.NewAnalyzeUrlHandler(cfg, logger, fetchClient, analyzeOpts...)
+	// results are stored under canonical URLs, so every reader normalizes the same way
+	norm := urlnorm.New(cfg.TrackingParams)
+	monitors := getMonitorManager(cfg, logger, analyzeHandler.PageAnalyzer(), resultStore, monitor.WithWebhooks(webhooks), monitor.WithNormalizer(norm), monitor.WithHostPolicy(hosts))
+	monitorsHandler := handler.NewMonitorsHandler(logger, monitors)
+	r.Group(func(r chi.Router) {
+		if authStore != nil {
+			r.Use(handler.Authenticate(logger, authStore))
+			r.Use(handler.RequireScope(logger, auth.ScopeAnalyze))
+		}
+		// analysis endpoints fan out to outbound fetches, so they are rate and concurrency limited
+		r.Use(handler.AnalysisLimits(logger, authStore, limiter, inFlight)...)
+		r.Post("/analyze", analyzeHandler.AnalyzeURLHandler)
+		r.Post("/analyze/html", analyzeHandler.AnalyzeHTMLHandler)
+		r.Post("/analyze/archive", analyzeHandler.AnalyzeArchiveHandler)
+		r.Post("/sitemap/analyze", analyzeHandler.AnalyzeSitemapHandler)
+		r.Post("/monitors/{id}/run", monitorsHandler.RunMonitorHandler)
+		r.Post("/snapshots/{id}/reanalyze", analyzeHandler.ReanalyzeSnapshotHandler)
+	})
+	r.Group(func(r chi.Router) {
+		if authStore != nil {
+			r.Use(handler.Authenticate(logger, authStore))
+			r.Use(handler.RequireScope(logger, auth.ScopeAnalyze))
+		}
+		resultsHandler := handler.NewResultsHandler(logger, resultStore, norm)
+		r.Get("/results", resultsHandler.ListResultsHandler)
+		r.Get("/results/{id}", resultsHandler.GetResultHandler)
+		r.Get("/diff", resultsHandler.DiffHandler)
+		r.Get("/snapshots/{id}", analyzeHandler.GetSnapshotHandler)
+		r.Get("/snapshots/{id}/raw", analyzeHandler.GetSnapshotBodyHandler)
+
+		r.Get("/monitors", monitorsHandler.ListMonitorsHandler)
+		r.Get("/monitors/{id}", monitorsHandler.GetMonitorHandler)
+
+		webhooksHandler := handler.NewWebhooksHandler(logger, webhooks)
+		r.Get("/webhooks/deliveries", webhooksHandler.ListDeliveriesHandler)
+		r.Get("/webhooks/deliveries/{id}", webhooksHandler.GetDeliveryHandler)
+	})
+	r.Group(func(r chi.Router) {
+		// monitors keep analyzing on their schedule, so only admin keys may change them and
+		// every change is charged like an analysis request
+		if authStore != nil {
+			r.Use(handler.Authenticate(logger, authStore))
+			r.Use(handler.RequireScope(logger, auth.ScopeAdmin))
+		}
+		r.Use(handler.AnalysisLimits(logger, authStore, limiter, nil)...)
+		r.Post("/monitors", monitorsHandler.CreateMonitorHandler)
+		r.Put("/monitors/{id}", monitorsHandler.UpdateMonitorHandler)
+		r.Delete("/monitors/{id}", monitorsHandler.DeleteMonitorHandler)
+	})
+	if authStore != nil {
+		r.Group(func(r chi.Router) {
+			r.Use(handler.Authenticate(logger, authStore))
+			r.Use(handler.RequireScope(logger, auth.ScopeAdmin))
+			r.Get("/admin/keys", handler.NewAdminHandler(logger, authStore).KeyUsageHandler)
+		})
+	}
+
+	srv := &http.Server{
+		Addr:    cfg.ListenAddr,
+		Handler: r,
+	}
+	logger.Info().Msg("Starting Peekalo server to analyze web pages...")
+
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			logger.Error().Err(err).Msg("Failed to start server")
+			panic(err)
+		}
+	}()
+
+	monitors.Start(context.Background())
+
+	handleShutdown(srv, logger, hc, time.Duration(cfg.ShutdownDrainDelay)*time.Second, monitors, webhooks, shutdownTracing)
+}
+
+func handleShutdown(srv *http.Server, logger logger.Logger, hc *health.Health, drainDelay time.Duration, monitors *monitor.Manager, webhooks *webhook.Dispatcher, shutdownTracing func(context.Context) error) {
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, syscall.SIGINT, syscall.SIGTERM)
+
+	sig := <-stop
+	logger.Info().Msgf("Shutdown signal received: %v", sig)
+
+	// fail readiness first and keep serving for a while, so load balancers stop routing
+	// new requests here before the listener closes
+	hc.SetDraining()
+	logger.Info().Msgf("Draining for %s before shutdown...", drainDelay)
+	time.Sleep(drainDelay)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	logger.Info().Msg("Starting graceful shutdown...")
+	if err := srv.Shutdown(ctx); err != nil {
+		logger.Error().Err(err).Msg("Failed to shutdown server gracefully")
+		panic(err)
+	}
+	monitors.Stop()
+	// pending retries are abandoned, attempts already in flight are allowed to finish
+	webhooks.Close()
+	// flush spans still buffered by the batch exporter
+	if err := shutdownTracing(ctx); err != nil {
+		logger.Error().Err(err).Msg("Failed to flush traces")
+	}
+	logger.Info().Msg("Server shutdown gracefully")
+}
+
+func getResultStore(cfg *config.Config, logger logger.Logger) storage.Store {
+	if cfg.ResultStore == "memory" {
+		logger.Warn().Msg("Using in-memory result store, results are lost on restart")
+		return storage.NewMemoryStore()
+	}
+	store, err := storage.NewFileStore(cfg.ResultStoreDir)
+	if err != nil {
+		logger.Error().Err(err).Msg("Failed to open result store")
+		panic(err)
+	}
+	logger.Info().Msgf("Storing analysis results in %s", cfg.ResultStoreDir)
+	return store
+}
+
+// getSnapshotStore returns nil when snapshots are disabled
+func getSnapshotStore(cfg *config.Config, logger logger.Logger) snapshot.Store {
+	switch cfg.SnapshotStore {
+	case "", "none":
+		return nil
+	case "memory":
+		logger.Warn().Msg("Using in-memory snapshot store, snapshots are lost on restart")
+		return snapshot.NewMemoryStore()
+	}
+	store, err := snapshot.NewFileStore(cfg.SnapshotStoreDir)
+	if err != nil {
+		logger.Error().Err(err).Msg("Failed to open snapshot store")
+		panic(err)
+	}
+	logger.Info().Msgf("Storing page snapshots in %s", cfg.SnapshotStoreDir)
+	return store
+}
+
+func getMonitorManager(cfg *config.Config, logger logger.Logger, pa monitor.PageAnalyzer, store storage.Store, opts ...monitor.Option) *monitor.Manager {
+	monitors, err := monitor.NewManager(logger, pa, store, cfg.MonitorsFile, time.Duration(cfg.MonitorMinInterval)*time.Second, opts...)
+	if err != nil {
+		logger.Error().Err(err).Msg("Failed to load monitors")
+		panic(err)
+	}
+	return monitors
+}
+
+// getCORSOptions maps the validated CORS config onto the chi cors middleware
+// reference: https://go-chi.io/#/pages/middleware?id=cors
+func getCORSOptions(cfg *config.Config) cors.Options {
+	return cors.Options{
+		AllowedOrigins:   cfg.CORS.AllowedOrigins,
+		AllowedMethods:   cfg.CORS.AllowedMethods,
+		AllowedHeaders:   cfg.CORS.AllowedHeaders,
+		ExposedHeaders:   []string{"Link", "Retry-After", handler.RequestIDHeader},
+		AllowCredentials: cfg.CORS.AllowCredentials,
+		MaxAge:           cfg.CORS.MaxAge,
+	}
+}
+
+// getAuthStore loads the configured API keys. It returns nil, leaving the API open, when none are configured.
+func getAuthStore(cfg *config.Config, logger logger.Logger) *auth.Store {
+	keys, err := auth.LoadKeys(cfg.APIKeysFile, cfg.APIKeys)
+	if err != nil {
+		logger.Error().Err(err).Msg("Failed to load API keys")
+		panic(err)
+	}
+	if len(keys) == 0 {
+		logger.Warn().Msg("No API keys configured, API key authentication is disabled")
+		return nil
+	}
+	logger.Info().Msgf("Loaded %d API keys", len(keys))
+	return auth.NewStore(keys)
+}
@@ -0,0 +1,146 @@
+package sitemap
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/sashithaf16/peekalo/analyzer"
+	"github.com/sashithaf16/peekalo/urlnorm"
+)
+
+// PageAnalyzer is satisfied by *analyzer.Analyzer
+type PageAnalyzer interface {
+	AnalyzeURL(ctx context.Context, pageURL string) (analyzer.PageInfo, error)
+}
+
+type Report struct {
+	SitemapURL         string         `json:"sitemap_url"`
+	TotalURLs          int            `json:"total_urls"`
+	Analyzed           int            `json:"analyzed"`
+	NotFound           []string       `json:"not_found"`
+	Redirected         []Redirect     `json:"redirected"`
+	NonCanonical       []NonCanonical `json:"non_canonical"`
+	Failed             []FailedURL    `json:"failed"`
+	MissingFromSitemap []string       `json:"missing_from_sitemap"` // linked internally but not listed
+}
+
+type Redirect struct {
+	URL      string `json:"url"`
+	FinalURL string `json:"final_url"`
+}
+
+type NonCanonical struct {
+	URL       string `json:"url"`
+	Canonical string `json:"canonical"`
+}
+
+type FailedURL struct {
+	URL   string `json:"url"`
+	Error string `json:"error"`
+}
+
+type pageResult struct {
+	url  string
+	info analyzer.PageInfo
+	err  error
+}
+
+// Coverage analyzes every URL with at most concurrency analyses in flight and classifies the results.
+// URLs are compared in their normalized form, the form the analyzer reports internal links in.
+func Coverage(ctx context.Context, pa PageAnalyzer, norm *urlnorm.Normalizer, sitemapURL string, urls []string, concurrency int) Report {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	results := make([]pageResult, len(urls))
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+
+	for i, u := range urls {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			results[i] = pageResult{url: u, err: ctx.Err()}
+			continue
+		}
+		wg.Add(1)
+		go func(i int, u string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			info, err := pa.AnalyzeURL(ctx, u)
+			results[i] = pageResult{url: u, info: info, err: err}
+		}(i, u)
+	}
+	wg.Wait()
+
+	return buildReport(norm, sitemapURL, urls, results)
+}
+
+func buildReport(norm *urlnorm.Normalizer, sitemapURL string, urls []string, results []pageResult) Report {
+	report := Report{
+		SitemapURL:         sitemapURL,
+		TotalURLs:          len(urls),
+		NotFound:           []string{},
+		Redirected:         []Redirect{},
+		NonCanonical:       []NonCanonical{},
+		Failed:             []FailedURL{},
+		MissingFromSitemap: []string{},
+	}
+
+	listed := make(map[string]bool, len(urls))
+	for _, u := range urls {
+		listed[normalize(norm, u)] = true
+	}
+	// pages reached through a redirect are also considered listed
+	for _, r := range results {
+		if r.err == nil && r.info.FinalURL != "" {
+			listed[normalize(norm, r.info.FinalURL)] = true
+		}
+	}
+
+	missing := make(map[string]bool)
+	for _, r := range results {
+		if r.err != nil {
+			report.Failed = append(report.Failed, FailedURL{URL: r.url, Error: r.err.Error()})
+			continue
+		}
+		report.Analyzed++
+
+		if r.info.StatusCode == http.StatusNotFound {
+			report.NotFound = append(report.NotFound, r.url)
+			continue
+		}
+		page := normalize(norm, r.url)
+		if r.info.FinalURL != "" && normalize(norm, r.info.FinalURL) != page {
+			report.Redirected = append(report.Redirected, Redirect{URL: r.url, FinalURL: r.info.FinalURL})
+		}
+		if r.info.Canonical != "" && normalize(norm, r.info.Canonical) != page {
+			report.NonCanonical = append(report.NonCanonical, NonCanonical{URL: r.url, Canonical: r.info.Canonical})
+		}
+		for _, link := range r.info.Links.InternalLinks {
+			if !listed[normalize(norm, link)] {
+				missing[link] = true
+			}
+		}
+	}
+
+	for link := range missing {
+		report.MissingFromSitemap = append(report.MissingFromSitemap, link)
+	}
+	sort.Strings(report.MissingFromSitemap)
+	return report
+}
+
+// normalize returns the normalized form of u, or u without its fragment when it cannot be parsed
+func normalize(norm *urlnorm.Normalizer, u string) string {
+	if normalized, err := norm.Normalize(u); err == nil {
+		return normalized
+	}
+	if i := strings.IndexByte(u, '#'); i >= 0 {
+		return u[:i]
+	}
+	return u
+}
@@ -0,0 +1,165 @@
+package sitemap
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/sashithaf16/peekalo/logger"
+)
+
+// maxSitemapSize is the uncompressed size limit from the sitemaps protocol
+const maxSitemapSize = 50 * 1024 * 1024
+
+// maxIndexDepth bounds how deeply nested sitemap indexes are followed
+const maxIndexDepth = 3
+
+type HttpClientInterface interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// Document is a parsed sitemap, either a <urlset> listing pages or a <sitemapindex> listing sitemaps
+// reference: https://www.sitemaps.org/protocol.html
+type Document struct {
+	URLs     []string
+	Sitemaps []string
+}
+
+type locEntry struct {
+	Loc string `xml:"loc"`
+}
+
+type xmlDocument struct {
+	XMLName  xml.Name
+	URLs     []locEntry `xml:"url"`
+	Sitemaps []locEntry `xml:"sitemap"`
+}
+
+// Parse reads a sitemap or sitemap index. Gzip-compressed input is detected and decompressed.
+func Parse(r io.Reader) (*Document, error) {
+	br := bufio.NewReader(r)
+	var body io.Reader = br
+	if magic, err := br.Peek(2); err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decompress sitemap: %v", err)
+		}
+		defer gz.Close()
+		body = gz
+	}
+
+	var raw xmlDocument
+	if err := xml.NewDecoder(io.LimitReader(body, maxSitemapSize)).Decode(&raw); err != nil {
+		return nil, fmt.Errorf("failed to parse sitemap XML: %v", err)
+	}
+
+	doc := &Document{}
+	switch raw.XMLName.Local {
+	case "urlset":
+		for _, u := range raw.URLs {
+			if loc := strings.TrimSpace(u.Loc); loc != "" {
+				doc.URLs = append(doc.URLs, loc)
+			}
+		}
+	case "sitemapindex":
+		for _, s := range raw.Sitemaps {
+			if loc := strings.TrimSpace(s.Loc); loc != "" {
+				doc.Sitemaps = append(doc.Sitemaps, loc)
+			}
+		}
+	default:
+		return nil, fmt.Errorf("unexpected sitemap root element: %s", raw.XMLName.Local)
+	}
+	return doc, nil
+}
+
+// Fetcher downloads sitemaps and flattens sitemap indexes into a list of page URLs
+type Fetcher struct {
+	logger     logger.Logger
+	httpClient HttpClientInterface
+	userAgent  string
+}
+
+func NewFetcher(logger logger.Logger, httpClient HttpClientInterface, userAgent string) *Fetcher {
+	return &Fetcher{logger: logger, httpClient: httpClient, userAgent: userAgent}
+}
+
+// Fetch returns the unique page URLs listed by sitemapURL, following nested indexes.
+// At most maxURLs are returned; zero means no limit.
+func (f *Fetcher) Fetch(ctx context.Context, sitemapURL string, maxURLs int) ([]string, error) {
+	log := logger.FromContext(ctx, f.logger)
+	var urls []string
+	seen := make(map[string]bool)
+	visited := make(map[string]bool)
+
+	var walk func(string, int) error
+	walk = func(loc string, depth int) error {
+		if visited[loc] {
+			return nil
+		}
+		visited[loc] = true
+
+		doc, err := f.fetchDocument(ctx, loc)
+		if err != nil {
+			return err
+		}
+		for _, u := range doc.URLs {
+			if maxURLs > 0 && len(urls) >= maxURLs {
+				return nil
+			}
+			if !seen[u] {
+				seen[u] = true
+				urls = append(urls, u)
+			}
+		}
+		if depth >= maxIndexDepth {
+			if len(doc.Sitemaps) > 0 {
+				log.Warn().Msgf("sitemap index nesting too deep, skipping children of: %s", loc)
+			}
+			return nil
+		}
+		for _, child := range doc.Sitemaps {
+			if maxURLs > 0 && len(urls) >= maxURLs {
+				return nil
+			}
+			if err := walk(child, depth+1); err != nil {
+				// one broken child sitemap should not discard the rest of the index
+				log.Warn().Err(err).Msgf("failed to read child sitemap: %s", child)
+			}
+		}
+		return nil
+	}
+
+	if err := walk(sitemapURL, 0); err != nil {
+		return nil, err
+	}
+	return urls, nil
+}
+
+func (f *Fetcher) fetchDocument(ctx context.Context, loc string) (*Document, error) {
+	logger.FromContext(ctx, f.logger).Debug().Msgf("Fetching sitemap: %s", loc)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", loc, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create sitemap request: %v", err)
+	}
+	if f.userAgent != "" {
+		req.Header.Set("User-Agent", f.userAgent)
+	}
+
+	resp, err := f.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch sitemap: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("failed to fetch sitemap: %s returned status %d", loc, resp.StatusCode)
+	}
+	return Parse(resp.Body)
+}
@@ -0,0 +1,139 @@
+package sitemap
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	mocks "github.com/sashithaf16/peekalo/_mocks"
+	"github.com/sashithaf16/peekalo/analyzer"
+	"github.com/sashithaf16/peekalo/logger"
+	"github.com/sashithaf16/peekalo/urlnorm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+const urlsetXML = `<?xml version="1.0" encoding="UTF-8"?>
+<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+	<url><loc>https://example.com/</loc></url>
+	<url><loc> https://example.com/about </loc></url>
+</urlset>`
+
+const indexXML = `<?xml version="1.0" encoding="UTF-8"?>
+<sitemapindex xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+	<sitemap><loc>https://example.com/pages.xml.gz</loc></sitemap>
+	<sitemap><loc>https://example.com/broken.xml</loc></sitemap>
+</sitemapindex>`
+
+func gzipped(t *testing.T, s string) []byte {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	_, err := gz.Write([]byte(s))
+	assert.NoError(t, err)
+	assert.NoError(t, gz.Close())
+	return buf.Bytes()
+}
+
+func TestParse(t *testing.T) {
+	doc, err := Parse(strings.NewReader(urlsetXML))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"https://example.com/", "https://example.com/about"}, doc.URLs)
+
+	doc, err = Parse(strings.NewReader(indexXML))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"https://example.com/pages.xml.gz", "https://example.com/broken.xml"}, doc.Sitemaps)
+
+	doc, err = Parse(bytes.NewReader(gzipped(t, urlsetXML)))
+	assert.NoError(t, err)
+	assert.Len(t, doc.URLs, 2)
+
+	_, err = Parse(strings.NewReader("<html></html>"))
+	assert.Error(t, err)
+}
+
+func TestFetcher_FollowsIndex(t *testing.T) {
+	log := logger.CreateLogger("debug")
+	mockClient := new(mocks.MockHTTPClient)
+	respond := func(path string, status int, body []byte) {
+		mockClient.On("Do", mock.MatchedBy(func(req *http.Request) bool { return req.URL.Path == path })).Return(&http.Response{
+			StatusCode: status,
+			Body:       io.NopCloser(bytes.NewReader(body)),
+		}, nil).Once()
+	}
+	respond("/sitemap.xml", 200, []byte(indexXML))
+	respond("/pages.xml.gz", 200, gzipped(t, urlsetXML))
+	respond("/broken.xml", 500, nil)
+
+	urls, err := NewFetcher(log, mockClient, "Peekalo/1.0").Fetch(context.Background(), "https://example.com/sitemap.xml", 0)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"https://example.com/", "https://example.com/about"}, urls)
+	mockClient.AssertExpectations(t)
+}
+
+type fakeAnalyzer map[string]analyzer.PageInfo
+
+func (f fakeAnalyzer) AnalyzeURL(_ context.Context, pageURL string) (analyzer.PageInfo, error) {
+	info, ok := f[pageURL]
+	if !ok {
+		return analyzer.PageInfo{}, errors.New("failed to fetch URL")
+	}
+	return info, nil
+}
+
+func TestCoverage(t *testing.T) {
+	pages := fakeAnalyzer{
+		"https://example.com/": {
+			StatusCode: 200,
+			FinalURL:   "https://example.com/",
+			Links:      analyzer.LinkStats{InternalLinks: []string{"https://example.com/about", "https://example.com/contact"}},
+		},
+		"https://example.com/old":  {StatusCode: 200, FinalURL: "https://example.com/about"},
+		"https://example.com/gone": {StatusCode: 404, FinalURL: "https://example.com/gone"},
+		"https://example.com/print": {
+			StatusCode: 200,
+			FinalURL:   "https://example.com/print",
+			Canonical:  "https://example.com/",
+		},
+	}
+	urls := []string{"https://example.com/", "https://example.com/old", "https://example.com/gone", "https://example.com/print", "https://example.com/down"}
+
+	report := Coverage(context.Background(), pages, urlnorm.New(nil), "https://example.com/sitemap.xml", urls, 2)
+
+	assert.Equal(t, 5, report.TotalURLs)
+	assert.Equal(t, 4, report.Analyzed)
+	assert.Equal(t, []string{"https://example.com/gone"}, report.NotFound)
+	assert.Equal(t, []Redirect{{URL: "https://example.com/old", FinalURL: "https://example.com/about"}}, report.Redirected)
+	assert.Equal(t, []NonCanonical{{URL: "https://example.com/print", Canonical: "https://example.com/"}}, report.NonCanonical)
+	assert.Len(t, report.Failed, 1)
+	assert.Equal(t, []string{"https://example.com/contact"}, report.MissingFromSitemap)
+}
+
+func TestCoverageComparesNormalizedURLs(t *testing.T) {
+	// internal links are reported normalized, while the sitemap lists URLs as written
+	pages := fakeAnalyzer{
+		"https://Example.com:443/": {
+			StatusCode: 200,
+			FinalURL:   "https://example.com/",
+			Canonical:  "https://example.com/#top",
+			Links: analyzer.LinkStats{InternalLinks: []string{
+				"https://example.com/search?page=2&q=a",
+				"https://example.com/pricing",
+			}},
+		},
+		"https://example.com/search?q=a&page=2&utm_source=mail": {StatusCode: 200, FinalURL: "https://example.com/search?q=a&page=2&utm_source=mail"},
+		"https://example.com/pricing#plans":                     {StatusCode: 200, FinalURL: "https://example.com/pricing"},
+	}
+	urls := []string{"https://Example.com:443/", "https://example.com/search?q=a&page=2&utm_source=mail", "https://example.com/pricing#plans"}
+
+	report := Coverage(context.Background(), pages, urlnorm.New(urlnorm.DefaultTrackingParams), "https://example.com/sitemap.xml", urls, 2)
+
+	assert.Equal(t, 3, report.Analyzed)
+	assert.Empty(t, report.MissingFromSitemap)
+	assert.Empty(t, report.Redirected)
+	assert.Empty(t, report.NonCanonical)
+}
@@ -0,0 +1,130 @@
+package snapshot
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// FileStore keeps bodies and captures in a directory sharded by the first two hex digits of
+// their hash or ID: each body as a raw file named by its hash, and each capture as JSON named by
+// its ID. The capture is written after its body, so it only becomes visible once the body is
+// complete. Snapshots of older versions, stored as JSON named by the body hash, read as captures
+// whose ID is that hash.
+type FileStore struct {
+	dir string
+
+	mu  sync.Mutex // serializes writes of the same capture
+	now func() time.Time
+}
+
+// NewFileStore opens dir, creating it if needed
+func NewFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create snapshot directory: %v", err)
+	}
+	return &FileStore{dir: dir, now: time.Now}, nil
+}
+
+func (f *FileStore) Put(ctx context.Context, s *Snapshot, body []byte) error {
+	prepare(s, body, f.now())
+	meta, err := json.Marshal(s)
+	if err != nil {
+		return fmt.Errorf("failed to encode snapshot: %v", err)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, err := os.Stat(f.capturePath(s.ID)); err == nil {
+		return nil
+	}
+	if _, err := os.Stat(f.bodyPath(s.Hash)); os.IsNotExist(err) {
+		if err := os.MkdirAll(filepath.Dir(f.bodyPath(s.Hash)), 0o755); err != nil {
+			return fmt.Errorf("failed to create snapshot directory: %v", err)
+		}
+		if err := writeFile(f.bodyPath(s.Hash), body); err != nil {
+			return err
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(f.capturePath(s.ID)), 0o755); err != nil {
+		return fmt.Errorf("failed to create snapshot directory: %v", err)
+	}
+	return writeFile(f.capturePath(s.ID), meta)
+}
+
+func (f *FileStore) Get(ctx context.Context, id string) (*Snapshot, error) {
+	if !validHash(id) {
+		return nil, ErrNotFound
+	}
+	data, err := os.ReadFile(f.capturePath(id))
+	if os.IsNotExist(err) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read snapshot %s: %v", id, err)
+	}
+	var s Snapshot
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("failed to decode snapshot %s: %v", id, err)
+	}
+	if s.ID == "" {
+		s.ID = id
+	}
+	return &s, nil
+}
+
+func (f *FileStore) Body(ctx context.Context, hash string) ([]byte, error) {
+	if !validHash(hash) {
+		return nil, ErrNotFound
+	}
+	body, err := os.ReadFile(f.bodyPath(hash))
+	if os.IsNotExist(err) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read snapshot body %s: %v", hash, err)
+	}
+	return body, nil
+}
+
+// Ping checks that the directory is still present and writable
+func (f *FileStore) Ping(ctx context.Context) error {
+	tmp, err := os.CreateTemp(f.dir, ".ping-*")
+	if err != nil {
+		return fmt.Errorf("snapshot directory is not writable: %v", err)
+	}
+	tmp.Close()
+	return os.Remove(tmp.Name())
+}
+
+func (f *FileStore) bodyPath(hash string) string {
+	return filepath.Join(f.dir, hash[:2], hash)
+}
+
+func (f *FileStore) capturePath(id string) string {
+	return filepath.Join(f.dir, id[:2], id+".json")
+}
+
+// writeFile writes to a temporary file and renames it so readers never see a partial file
+func writeFile(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".snapshot-*")
+	if err != nil {
+		return fmt.Errorf("failed to create snapshot file: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write snapshot file: %v", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write snapshot file: %v", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to store snapshot file: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package snapshot
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// MemoryStore keeps snapshots in memory. It is meant for tests and ephemeral deployments.
+type MemoryStore struct {
+	mu       sync.RWMutex
+	captures map[string]Snapshot // by ID
+	bodies   map[string][]byte   // by content hash
+	now      func() time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		captures: make(map[string]Snapshot),
+		bodies:   make(map[string][]byte),
+		now:      time.Now,
+	}
+}
+
+func (m *MemoryStore) Put(ctx context.Context, s *Snapshot, body []byte) error {
+	prepare(s, body, m.now())
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, exists := m.captures[s.ID]; exists {
+		return nil
+	}
+	m.captures[s.ID] = *s
+	if _, exists := m.bodies[s.Hash]; !exists {
+		m.bodies[s.Hash] = append([]byte(nil), body...)
+	}
+	return nil
+}
+
+func (m *MemoryStore) Get(ctx context.Context, id string) (*Snapshot, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	s, ok := m.captures[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &s, nil
+}
+
+func (m *MemoryStore) Body(ctx context.Context, hash string) ([]byte, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	body, ok := m.bodies[hash]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return body, nil
+}
+
+func (m *MemoryStore) Ping(ctx context.Context) error {
+	return nil
+}
@@ -0,0 +1,78 @@
+package snapshot
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"time"
+)
+
+var ErrNotFound = errors.New("snapshot not found")
+
+// Snapshot describes one capture: a response body fetched from a URL. Bodies are stored once
+// under their content hash and shared by every capture of the same bytes.
+type Snapshot struct {
+	ID         string      `json:"id"`   // identifies the capture, derived from URL and Hash
+	Hash       string      `json:"hash"` // hex SHA-256 of the body
+	URL        string      `json:"url"`  // final URL the body was fetched from
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"headers"` // response headers without credentials such as Set-Cookie
+	FetchedAt  time.Time   `json:"fetched_at"`
+	Size       int         `json:"size"`
+}
+
+// Store persists captures and the bodies they point to. Captures are immutable: storing the
+// same bytes from the same URL again keeps the metadata of the first capture, while the same
+// bytes from another URL get a capture of their own.
+type Store interface {
+	// Put stores body with the metadata in s, filling in its ID, Hash, Size and FetchedAt
+	Put(ctx context.Context, s *Snapshot, body []byte) error
+	// Get returns the capture with id, or ErrNotFound
+	Get(ctx context.Context, id string) (*Snapshot, error)
+	// Body returns the raw bytes stored under the content hash, or ErrNotFound
+	Body(ctx context.Context, hash string) ([]byte, error)
+	// Ping reports whether the store can currently serve reads and writes
+	Ping(ctx context.Context) error
+}
+
+// credentialHeaders are never stored, so captures cannot leak sessions or tokens
+var credentialHeaders = []string{"Set-Cookie", "Set-Cookie2", "Cookie", "Authorization", "Proxy-Authorization"}
+
+// Hash returns the content hash a body is stored under
+func Hash(body []byte) string {
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:])
+}
+
+// captureID returns the ID of the capture of the body with hash from url
+func captureID(url, hash string) string {
+	return Hash([]byte(url + "\n" + hash))
+}
+
+// prepare fills in the content-derived fields of a snapshot about to be stored and drops
+// credential headers
+func prepare(s *Snapshot, body []byte, now time.Time) {
+	s.Hash = Hash(body)
+	s.ID = captureID(s.URL, s.Hash)
+	s.Size = len(body)
+	if s.FetchedAt.IsZero() {
+		s.FetchedAt = now.UTC()
+	}
+	if s.Header != nil {
+		s.Header = s.Header.Clone()
+		for _, h := range credentialHeaders {
+			s.Header.Del(h)
+		}
+	}
+}
+
+// validHash guards file names built from hashes and IDs against path traversal
+func validHash(hash string) bool {
+	if len(hash) != 2*sha256.Size {
+		return false
+	}
+	_, err := hex.DecodeString(hash)
+	return err == nil
+}
@@ -0,0 +1,95 @@
+package snapshot
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testStore(t *testing.T, store Store) {
+	ctx := context.Background()
+	body := []byte("<html><head><title>Home</title></head></html>")
+
+	header := http.Header{"Content-Type": {"text/html"}, "Set-Cookie": {"session=secret"}, "Authorization": {"Bearer token"}}
+	first := &Snapshot{URL: "https://example.com/", StatusCode: 200, Header: header}
+	require.NoError(t, store.Put(ctx, first, body))
+	assert.Equal(t, Hash(body), first.Hash)
+	assert.NotEmpty(t, first.ID)
+	assert.Equal(t, len(body), first.Size)
+	assert.False(t, first.FetchedAt.IsZero())
+	assert.Equal(t, "session=secret", header.Get("Set-Cookie"), "the caller's headers are left alone")
+
+	// the same bytes fetched again from the same URL keep the first capture
+	again := &Snapshot{URL: "https://example.com/", StatusCode: 203}
+	require.NoError(t, store.Put(ctx, again, body))
+	assert.Equal(t, first.ID, again.ID)
+
+	// the same bytes fetched from another URL share the body but get their own capture
+	other := &Snapshot{URL: "https://example.com/copy", StatusCode: 404}
+	require.NoError(t, store.Put(ctx, other, body))
+	assert.Equal(t, first.Hash, other.Hash)
+	assert.NotEqual(t, first.ID, other.ID)
+
+	got, err := store.Get(ctx, first.ID)
+	require.NoError(t, err)
+	assert.Equal(t, first.ID, got.ID)
+	assert.Equal(t, "https://example.com/", got.URL)
+	assert.Equal(t, 200, got.StatusCode)
+	assert.Equal(t, "text/html", got.Header.Get("Content-Type"))
+	assert.Empty(t, got.Header.Values("Set-Cookie"))
+	assert.Empty(t, got.Header.Values("Authorization"))
+
+	got, err = store.Get(ctx, other.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/copy", got.URL)
+	assert.Equal(t, 404, got.StatusCode)
+
+	raw, err := store.Body(ctx, first.Hash)
+	require.NoError(t, err)
+	assert.Equal(t, body, raw)
+
+	_, err = store.Get(ctx, Hash([]byte("unknown")))
+	assert.ErrorIs(t, err, ErrNotFound)
+	_, err = store.Body(ctx, Hash([]byte("unknown")))
+	assert.ErrorIs(t, err, ErrNotFound)
+	_, err = store.Body(ctx, "../../etc/passwd")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	assert.NoError(t, store.Ping(ctx))
+}
+
+func TestMemoryStore(t *testing.T) {
+	testStore(t, NewMemoryStore())
+}
+
+func TestFileStore(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewFileStore(dir)
+	require.NoError(t, err)
+	testStore(t, store)
+
+	body := []byte("<html><head><title>Home</title></head></html>")
+	reopened, err := NewFileStore(dir)
+	require.NoError(t, err)
+	_, err = reopened.Get(context.Background(), captureID("https://example.com/", Hash(body)))
+	assert.NoError(t, err)
+
+	// snapshots stored before captures existed are found by their body hash
+	legacy := []byte("<html><head><title>Old</title></head></html>")
+	hash := Hash(legacy)
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, hash[:2]), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, hash[:2], hash), legacy, 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, hash[:2], hash+".json"), []byte(`{"hash":"`+hash+`","url":"https://example.com/old","status_code":200}`), 0o644))
+	got, err := reopened.Get(context.Background(), hash)
+	require.NoError(t, err)
+	assert.Equal(t, hash, got.ID)
+	assert.Equal(t, "https://example.com/old", got.URL)
+	raw, err := reopened.Body(context.Background(), got.Hash)
+	require.NoError(t, err)
+	assert.Equal(t, legacy, raw)
+}
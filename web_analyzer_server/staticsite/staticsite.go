@@ -0,0 +1,216 @@
+package staticsite
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/sashithaf16/peekalo/analyzer"
+)
+
+const defaultConcurrency = 4
+
+// DocumentAnalyzer analyzes HTML without fetching it
+type DocumentAnalyzer interface {
+	AnalyzeDocument(ctx context.Context, r io.Reader, baseURL string) (analyzer.PageInfo, error)
+}
+
+// Options configures a site analysis
+type Options struct {
+	BaseURL     string // URL the site root is served at, such as https://example.com/docs/
+	Concurrency int    // files analyzed in parallel
+}
+
+// Page is one HTML file of the site. Info is nil when the analysis failed.
+type Page struct {
+	Path        string             `json:"path"` // slash-separated path relative to the site root
+	URL         string             `json:"url"`
+	Info        *analyzer.PageInfo `json:"page_info,omitempty"`
+	Error       string             `json:"error,omitempty"`
+	BrokenLinks []string           `json:"broken_links,omitempty"` // internal links that match no file
+}
+
+// Summary aggregates the pages of a site
+type Summary struct {
+	Pages                int      `json:"pages"`
+	Failed               int      `json:"failed"`
+	MissingTitles        int      `json:"missing_titles"`
+	BrokenLinks          int      `json:"broken_links"`
+	PagesWithBrokenLinks int      `json:"pages_with_broken_links"`
+	ExternalLinks        int      `json:"external_links"`
+	Orphans              []string `json:"orphans,omitempty"` // pages no other page links to, except the root index
+}
+
+type Report struct {
+	BaseURL string  `json:"base_url"`
+	Pages   []Page  `json:"pages"`
+	Summary Summary `json:"summary"`
+}
+
+// Analyze treats every .html and .htm file in site as a page served under opts.BaseURL and
+// resolves internal links against the files to find broken ones. Nothing is fetched.
+func Analyze(ctx context.Context, an DocumentAnalyzer, site fs.FS, opts Options) (*Report, error) {
+	base, err := url.Parse(opts.BaseURL)
+	if err != nil || (base.Scheme != "http" && base.Scheme != "https") || base.Host == "" {
+		return nil, fmt.Errorf("invalid base URL: %s", opts.BaseURL)
+	}
+	if !strings.HasSuffix(base.Path, "/") {
+		base.Path += "/"
+	}
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = defaultConcurrency
+	}
+
+	var files []string
+	err = fs.WalkDir(site, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() && isHTML(p) {
+			files = append(files, p)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read site directory: %v", err)
+	}
+	sort.Strings(files)
+
+	pages := make([]Page, len(files))
+	sem := make(chan struct{}, opts.Concurrency)
+	var wg sync.WaitGroup
+	for i, file := range files {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, file string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			pages[i] = analyzeFile(ctx, an, site, base, file)
+		}(i, file)
+	}
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	return &Report{BaseURL: base.String(), Pages: pages, Summary: summarize(site, base, pages)}, nil
+}
+
+func analyzeFile(ctx context.Context, an DocumentAnalyzer, site fs.FS, base *url.URL, file string) Page {
+	page := Page{Path: file, URL: pageURL(base, file)}
+	f, err := site.Open(file)
+	if err != nil {
+		page.Error = fmt.Sprintf("failed to open file: %v", err)
+		return page
+	}
+	defer f.Close()
+
+	info, err := an.AnalyzeDocument(ctx, f, page.URL)
+	if err != nil {
+		page.Error = err.Error()
+		return page
+	}
+	page.Info = &info
+	for _, link := range info.Links.InternalLinks {
+		if target, ok := resolve(base, link); ok && !exists(site, target) {
+			page.BrokenLinks = append(page.BrokenLinks, link)
+		}
+	}
+	return page
+}
+
+func summarize(site fs.FS, base *url.URL, pages []Page) Summary {
+	s := Summary{Pages: len(pages)}
+	linked := make(map[string]bool)
+	for _, p := range pages {
+		if p.Info == nil {
+			s.Failed++
+			continue
+		}
+		if strings.TrimSpace(p.Info.Title) == "" {
+			s.MissingTitles++
+		}
+		if len(p.BrokenLinks) > 0 {
+			s.PagesWithBrokenLinks++
+			s.BrokenLinks += len(p.BrokenLinks)
+		}
+		s.ExternalLinks += len(p.Info.Links.ExternalLinks)
+		for _, link := range p.Info.Links.InternalLinks {
+			if target, ok := resolve(base, link); ok {
+				if file, ok := lookup(site, target); ok && file != p.Path {
+					linked[file] = true
+				}
+			}
+		}
+	}
+	for _, p := range pages {
+		if p.Path != "index.html" && !linked[p.Path] {
+			s.Orphans = append(s.Orphans, p.Path)
+		}
+	}
+	return s
+}
+
+// pageURL is the URL a file is served at; index files are served at their directory
+func pageURL(base *url.URL, file string) string {
+	u := *base
+	if dir, name := path.Split(file); name == "index.html" {
+		file = dir
+	}
+	u.Path = base.Path + file
+	return u.String()
+}
+
+// resolve maps an internal link to a slash-separated path relative to the site root.
+// Links outside the base URL's host or path cannot be checked against the files.
+func resolve(base *url.URL, link string) (string, bool) {
+	u, err := url.Parse(link)
+	if err != nil || !strings.EqualFold(u.Host, base.Host) {
+		return "", false
+	}
+	p := u.Path
+	if p == "" {
+		p = "/"
+	}
+	rel, ok := strings.CutPrefix(p, base.Path)
+	if !ok {
+		if p+"/" != base.Path {
+			return "", false
+		}
+		rel = ""
+	}
+	if strings.HasSuffix(p, "/") || rel == "" {
+		return path.Join(rel, "index.html"), true
+	}
+	return path.Clean(rel), true
+}
+
+// lookup finds the file a path is served from, trying the exact file, then "<path>.html" and "<path>/index.html"
+// as static hosts do for pretty URLs
+func lookup(site fs.FS, p string) (string, bool) {
+	for _, candidate := range []string{p, p + ".html", path.Join(p, "index.html")} {
+		if !fs.ValidPath(candidate) {
+			continue
+		}
+		if info, err := fs.Stat(site, candidate); err == nil && !info.IsDir() {
+			return candidate, true
+		}
+	}
+	return "", false
+}
+
+func exists(site fs.FS, p string) bool {
+	_, ok := lookup(site, p)
+	return ok
+}
+
+func isHTML(p string) bool {
+	ext := strings.ToLower(path.Ext(p))
+	return ext == ".html" || ext == ".htm"
+}
@@ -0,0 +1,82 @@
+package staticsite
+
+import (
+	"context"
+	"net/url"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sashithaf16/peekalo/analyzer"
+	"github.com/sashithaf16/peekalo/config"
+	"github.com/sashithaf16/peekalo/logger"
+)
+
+func file(body string) *fstest.MapFile {
+	return &fstest.MapFile{Data: []byte(body)}
+}
+
+func TestAnalyze(t *testing.T) {
+	site := fstest.MapFS{
+		"index.html": file(`<html><head><title>Docs</title></head><body>
+			<a href="guide/">Guide</a><a href="/docs/about">About</a><a href="missing.html">Missing</a>
+			<a href="https://example.org/">Elsewhere</a><a href="/blog/">Outside the site</a></body></html>`),
+		"about.html":       file(`<html><head><title>About</title></head><body><a href="./">Home</a></body></html>`),
+		"guide/index.html": file(`<html><body><a href="../style.css">Styles</a><a href="../gone/">Gone</a></body></html>`),
+		"draft.htm":        file(`<html><head><title>Draft</title></head></html>`),
+		"style.css":        file(`body {}`),
+	}
+	an := analyzer.NewAnalyzer(logger.CreateLogger("error"), &config.Config{}, nil)
+
+	report, err := Analyze(context.Background(), an, site, Options{BaseURL: "https://example.com/docs"})
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/docs/", report.BaseURL)
+
+	require.Len(t, report.Pages, 4)
+	pages := make(map[string]Page)
+	for _, p := range report.Pages {
+		require.NotNil(t, p.Info, p.Path)
+		pages[p.Path] = p
+	}
+	assert.Equal(t, "https://example.com/docs/", pages["index.html"].URL)
+	assert.Equal(t, "https://example.com/docs/guide/", pages["guide/index.html"].URL)
+	assert.Equal(t, []string{"https://example.com/docs/missing.html"}, pages["index.html"].BrokenLinks)
+	assert.Equal(t, []string{"https://example.com/docs/gone/"}, pages["guide/index.html"].BrokenLinks)
+	assert.Empty(t, pages["about.html"].BrokenLinks)
+
+	assert.Equal(t, Summary{
+		Pages:                4,
+		MissingTitles:        1,
+		BrokenLinks:          2,
+		PagesWithBrokenLinks: 2,
+		ExternalLinks:        1,
+		Orphans:              []string{"draft.htm"},
+	}, report.Summary)
+
+	_, err = Analyze(context.Background(), an, site, Options{BaseURL: "/docs"})
+	assert.Error(t, err)
+}
+
+func TestResolve(t *testing.T) {
+	base, _ := url.Parse("https://example.com/docs/")
+	tests := []struct {
+		link string
+		path string
+		ok   bool
+	}{
+		{"https://example.com/docs/", "index.html", true},
+		{"https://example.com/docs", "index.html", true},
+		{"https://example.com/docs/guide/", "guide/index.html", true},
+		{"https://example.com/docs/guide?tab=1", "guide", true},
+		{"https://example.com/docs/a/../b.html", "b.html", true},
+		{"https://example.com/blog/", "", false},
+		{"https://other.example.com/docs/", "", false},
+	}
+	for _, tt := range tests {
+		p, ok := resolve(base, tt.link)
+		assert.Equal(t, tt.ok, ok, tt.link)
+		assert.Equal(t, tt.path, p, tt.link)
+	}
+}
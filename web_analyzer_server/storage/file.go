@@ -0,0 +1,176 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FileStore keeps each result as a JSON file in a directory, with an in-memory index
+// of result IDs per URL built when the store is opened. The index is kept newest first,
+// so listing the latest results of a URL reads only as many files as are returned.
+type FileStore struct {
+	dir string
+
+	mu    sync.RWMutex
+	byURL map[string][]indexEntry
+	urls  map[string]string // URL of each indexed result ID
+	now   func() time.Time
+}
+
+// indexEntry locates one result in the per-URL index
+type indexEntry struct {
+	id        string
+	createdAt time.Time
+}
+
+// newer orders index entries like newestFirst orders results
+func (e indexEntry) newer(o indexEntry) bool {
+	if e.createdAt.Equal(o.createdAt) {
+		return e.id > o.id
+	}
+	return e.createdAt.After(o.createdAt)
+}
+
+// NewFileStore opens dir, creating it if needed, and indexes the results already in it
+func NewFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create result directory: %v", err)
+	}
+	f := &FileStore{dir: dir, byURL: make(map[string][]indexEntry), urls: make(map[string]string), now: time.Now}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read result directory: %v", err)
+	}
+	for _, e := range entries {
+		id, ok := strings.CutSuffix(e.Name(), ".json")
+		if e.IsDir() || !ok || !validID(id) {
+			continue
+		}
+		r, err := f.read(id)
+		if err != nil {
+			return nil, err
+		}
+		f.index(r)
+	}
+	return f, nil
+}
+
+func (f *FileStore) Save(ctx context.Context, r *Result) error {
+	if err := prepare(r, f.now()); err != nil {
+		return err
+	}
+	if !validID(r.ID) {
+		return fmt.Errorf("invalid result id: %s", r.ID)
+	}
+
+	data, err := json.Marshal(r)
+	if err != nil {
+		return fmt.Errorf("failed to encode result: %v", err)
+	}
+	// write to a temporary file and rename so readers never see a partial result
+	tmp, err := os.CreateTemp(f.dir, ".result-*")
+	if err != nil {
+		return fmt.Errorf("failed to create result file: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write result file: %v", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write result file: %v", err)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := os.Rename(tmp.Name(), f.path(r.ID)); err != nil {
+		return fmt.Errorf("failed to store result file: %v", err)
+	}
+	f.index(r)
+	return nil
+}
+
+// index inserts r into the index of its URL in order, replacing the entry of a result saved again
+func (f *FileStore) index(r *Result) {
+	if url, ok := f.urls[r.ID]; ok {
+		entries := f.byURL[url]
+		for i, e := range entries {
+			if e.id == r.ID {
+				f.byURL[url] = append(entries[:i], entries[i+1:]...)
+				break
+			}
+		}
+	}
+	entry := indexEntry{id: r.ID, createdAt: r.CreatedAt}
+	entries := f.byURL[r.URL]
+	i := sort.Search(len(entries), func(i int) bool { return entry.newer(entries[i]) })
+	entries = append(entries, indexEntry{})
+	copy(entries[i+1:], entries[i:])
+	entries[i] = entry
+	f.byURL[r.URL] = entries
+	f.urls[r.ID] = r.URL
+}
+
+func (f *FileStore) Get(ctx context.Context, id string) (*Result, error) {
+	if !validID(id) {
+		return nil, ErrNotFound
+	}
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.read(id)
+}
+
+func (f *FileStore) ListByURL(ctx context.Context, url string, limit int) ([]Result, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	entries := f.byURL[url]
+	if limit > 0 && len(entries) > limit {
+		entries = entries[:limit]
+	}
+	results := make([]Result, 0, len(entries))
+	for _, e := range entries {
+		r, err := f.read(e.id)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, *r)
+	}
+	return results, nil
+}
+
+// Ping checks that the directory is still present and writable
+func (f *FileStore) Ping(ctx context.Context) error {
+	tmp, err := os.CreateTemp(f.dir, ".ping-*")
+	if err != nil {
+		return fmt.Errorf("result directory is not writable: %v", err)
+	}
+	tmp.Close()
+	return os.Remove(tmp.Name())
+}
+
+func (f *FileStore) read(id string) (*Result, error) {
+	data, err := os.ReadFile(f.path(id))
+	if os.IsNotExist(err) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read result %s: %v", id, err)
+	}
+	var r Result
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, fmt.Errorf("failed to decode result %s: %v", id, err)
+	}
+	return &r, nil
+}
+
+func (f *FileStore) path(id string) string {
+	return filepath.Join(f.dir, id+".json")
+}
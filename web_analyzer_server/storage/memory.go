@@ -0,0 +1,75 @@
+package storage
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// MemoryStore keeps results in memory. It is meant for tests and ephemeral deployments.
+type MemoryStore struct {
+	mu      sync.RWMutex
+	results map[string]Result
+	byURL   map[string][]string
+	now     func() time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		results: make(map[string]Result),
+		byURL:   make(map[string][]string),
+		now:     time.Now,
+	}
+}
+
+func (m *MemoryStore) Save(ctx context.Context, r *Result) error {
+	if err := prepare(r, m.now()); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, exists := m.results[r.ID]; !exists {
+		m.byURL[r.URL] = append(m.byURL[r.URL], r.ID)
+	}
+	m.results[r.ID] = *r
+	return nil
+}
+
+func (m *MemoryStore) Get(ctx context.Context, id string) (*Result, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	r, ok := m.results[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &r, nil
+}
+
+func (m *MemoryStore) ListByURL(ctx context.Context, url string, limit int) ([]Result, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	results := make([]Result, 0, len(m.byURL[url]))
+	for _, id := range m.byURL[url] {
+		results = append(results, m.results[id])
+	}
+	return newestFirst(results, limit), nil
+}
+
+func (m *MemoryStore) Ping(ctx context.Context) error {
+	return nil
+}
+
+// newestFirst sorts results by creation time, newest first, and truncates them to limit
+func newestFirst(results []Result, limit int) []Result {
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].CreatedAt.Equal(results[j].CreatedAt) {
+			return results[i].ID > results[j].ID
+		}
+		return results[i].CreatedAt.After(results[j].CreatedAt)
+	})
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+	}
+	return results
+}
@@ -0,0 +1,77 @@
+package storage
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/sashithaf16/peekalo/analyzer"
+)
+
+var ErrNotFound = errors.New("result not found")
+
+// Result is one stored analysis of a URL
+type Result struct {
+	ID        string            `json:"id"`
+	URL       string            `json:"url"`
+	CreatedAt time.Time         `json:"created_at"`
+	Options   map[string]string `json:"options,omitempty"` // how the analysis was requested, e.g. source and robots policy
+	PageInfo  analyzer.PageInfo `json:"page_info"`
+}
+
+// fetchedSources are the sources of results whose analyses fetched the page
+var fetchedSources = map[string]bool{"analyze": true, "sitemap": true, "monitor": true}
+
+// Fetched reports whether the analysis fetched the page itself. Results of raw HTML, archive imports and
+// re-analyzed snapshots describe documents supplied by a client and are not the page's history.
+func (r Result) Fetched() bool {
+	return fetchedSources[r.Options["source"]]
+}
+
+// Store persists analysis results
+type Store interface {
+	// Save stores r, assigning its ID and CreatedAt when they are empty
+	Save(ctx context.Context, r *Result) error
+	// Get returns the result with id, or ErrNotFound
+	Get(ctx context.Context, id string) (*Result, error)
+	// ListByURL returns up to limit results for url, newest first. A limit of 0 returns all.
+	ListByURL(ctx context.Context, url string, limit int) ([]Result, error)
+	// Ping reports whether the store can currently serve reads and writes
+	Ping(ctx context.Context) error
+}
+
+// prepare fills in the ID and creation time of a result about to be saved
+func prepare(r *Result, now time.Time) error {
+	if r.CreatedAt.IsZero() {
+		r.CreatedAt = now.UTC()
+	}
+	if r.ID == "" {
+		id, err := newID(r.CreatedAt)
+		if err != nil {
+			return err
+		}
+		r.ID = id
+	}
+	return nil
+}
+
+// newID returns a random ID that sorts by creation time
+func newID(t time.Time) (string, error) {
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate result id: %v", err)
+	}
+	return fmt.Sprintf("%016x%s", t.UnixNano(), hex.EncodeToString(b)), nil
+}
+
+// validID guards file names built from IDs against path traversal
+func validID(id string) bool {
+	if len(id) != 24 {
+		return false
+	}
+	_, err := hex.DecodeString(id)
+	return err == nil
+}
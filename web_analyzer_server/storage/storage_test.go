@@ -0,0 +1,98 @@
+package storage
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sashithaf16/peekalo/analyzer"
+)
+
+func testStore(t *testing.T, store Store) {
+	ctx := context.Background()
+	base := time.Date(2025, 6, 1, 10, 0, 0, 0, time.UTC)
+
+	for i, title := range []string{"First", "Second", "Third"} {
+		r := &Result{
+			URL:       "https://example.com",
+			CreatedAt: base.Add(time.Duration(i) * time.Minute),
+			Options:   map[string]string{"source": "analyze"},
+			PageInfo:  analyzer.PageInfo{Title: title},
+		}
+		require.NoError(t, store.Save(ctx, r))
+		assert.Len(t, r.ID, 24)
+	}
+	other := &Result{URL: "https://other.example.com", PageInfo: analyzer.PageInfo{Title: "Other"}}
+	require.NoError(t, store.Save(ctx, other))
+	assert.False(t, other.CreatedAt.IsZero())
+
+	got, err := store.Get(ctx, other.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Other", got.PageInfo.Title)
+
+	_, err = store.Get(ctx, "000000000000000000000000")
+	assert.ErrorIs(t, err, ErrNotFound)
+	_, err = store.Get(ctx, "../../etc/passwd")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	results, err := store.ListByURL(ctx, "https://example.com", 2)
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	assert.Equal(t, "Third", results[0].PageInfo.Title)
+	assert.Equal(t, "Second", results[1].PageInfo.Title)
+	assert.Equal(t, "analyze", results[0].Options["source"])
+
+	results, err = store.ListByURL(ctx, "https://unknown.example.com", 0)
+	require.NoError(t, err)
+	assert.Empty(t, results)
+
+	assert.NoError(t, store.Ping(ctx))
+}
+
+func TestMemoryStore(t *testing.T) {
+	testStore(t, NewMemoryStore())
+}
+
+func TestFileStore(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewFileStore(dir)
+	require.NoError(t, err)
+	testStore(t, store)
+
+	// a reopened store rebuilds its index from the files on disk
+	reopened, err := NewFileStore(dir)
+	require.NoError(t, err)
+	results, err := reopened.ListByURL(context.Background(), "https://example.com", 0)
+	require.NoError(t, err)
+	assert.Len(t, results, 3)
+	oldest := results[2]
+
+	// a result saved again with an earlier time moves down the index
+	newest := results[0]
+	newest.CreatedAt = oldest.CreatedAt.Add(-time.Minute)
+	require.NoError(t, reopened.Save(context.Background(), &newest))
+	results, err = reopened.ListByURL(context.Background(), "https://example.com", 0)
+	require.NoError(t, err)
+	require.Len(t, results, 3)
+	assert.Equal(t, []string{"Second", "First", "Third"}, []string{results[0].PageInfo.Title, results[1].PageInfo.Title, results[2].PageInfo.Title})
+
+	// only as many files as requested are read
+	require.NoError(t, os.Remove(filepath.Join(dir, newest.ID+".json")))
+	results, err = reopened.ListByURL(context.Background(), "https://example.com", 2)
+	require.NoError(t, err)
+	assert.Len(t, results, 2)
+}
+
+func TestFileStore_PingFailsWhenDirectoryRemoved(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "results")
+	store, err := NewFileStore(dir)
+	require.NoError(t, err)
+
+	require.NoError(t, os.RemoveAll(dir))
+	assert.Error(t, store.Ping(context.Background()))
+}
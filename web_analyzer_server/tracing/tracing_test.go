@@ -0,0 +1,24 @@
+package tracing
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sashithaf16/peekalo/config"
+	"github.com/sashithaf16/peekalo/logger"
+)
+
+func TestSetup(t *testing.T) {
+	log := logger.CreateLogger("debug")
+
+	for _, exporter := range []string{"", ExporterNone, ExporterStdout} {
+		shutdown, err := Setup(context.Background(), &config.Config{TracingExporter: exporter, TracingSampleRatio: 1}, log)
+		assert.NoError(t, err, exporter)
+		assert.NoError(t, shutdown(context.Background()), exporter)
+	}
+
+	_, err := Setup(context.Background(), &config.Config{TracingExporter: "zipkin"}, log)
+	assert.Error(t, err)
+}
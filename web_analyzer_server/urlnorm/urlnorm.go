@@ -0,0 +1,156 @@
+package urlnorm
+
+import (
+	"net/url"
+	"sort"
+	"strings"
+
+	"golang.org/x/net/idna"
+)
+
+// DefaultTrackingParams are the query parameters stripped unless configured otherwise
+var DefaultTrackingParams = []string{"utm_*", "gclid", "fbclid", "msclkid", "dclid", "yclid", "mc_cid", "mc_eid", "_ga"}
+
+// Normalizer canonicalizes URLs so that spellings of the same page compare equal
+type Normalizer struct {
+	exact    map[string]bool
+	prefixes []string
+}
+
+// New returns a normalizer stripping the given tracking parameters. A name ending in "*"
+// matches every parameter with that prefix; names are matched case-insensitively.
+func New(trackingParams []string) *Normalizer {
+	n := &Normalizer{exact: make(map[string]bool)}
+	for _, p := range trackingParams {
+		p = strings.ToLower(strings.TrimSpace(p))
+		if prefix, ok := strings.CutSuffix(p, "*"); ok {
+			n.prefixes = append(n.prefixes, prefix)
+		} else if p != "" {
+			n.exact[p] = true
+		}
+	}
+	return n
+}
+
+// Normalize returns the canonical form of an absolute URL, or an error when it cannot be parsed
+func (n *Normalizer) Normalize(rawURL string) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", err
+	}
+	canonical, _ := n.NormalizeURL(u)
+	return canonical.String(), nil
+}
+
+// NormalizeURL returns the canonical form of u and whether tracking parameters were removed.
+// It lowercases the scheme and host, converts internationalized hosts to punycode, removes
+// default ports and the fragment, resolves dot segments, and sorts the query parameters.
+func (n *Normalizer) NormalizeURL(u *url.URL) (*url.URL, bool) {
+	c := *u
+	c.Scheme = strings.ToLower(c.Scheme)
+	c.Fragment, c.RawFragment = "", ""
+
+	if c.Host != "" {
+		host, port := strings.ToLower(c.Hostname()), c.Port()
+		if ascii, err := idna.Lookup.ToASCII(host); err == nil {
+			host = ascii
+		}
+		if (c.Scheme == "http" && port == "80") || (c.Scheme == "https" && port == "443") {
+			port = ""
+		}
+		if strings.Contains(host, ":") {
+			host = "[" + host + "]"
+		}
+		if port != "" {
+			host += ":" + port
+		}
+		c.Host = host
+	}
+
+	escaped := removeDotSegments(c.EscapedPath())
+	if escaped == "" && c.Host != "" {
+		escaped = "/"
+	}
+	if p, err := url.PathUnescape(escaped); err == nil {
+		c.Path, c.RawPath = p, escaped
+	}
+
+	var stripped bool
+	c.RawQuery, stripped = n.normalizeQuery(c.RawQuery)
+	c.ForceQuery = false
+	return &c, stripped
+}
+
+// IsTracking reports whether a query parameter is stripped as a tracking parameter
+func (n *Normalizer) IsTracking(name string) bool {
+	name = strings.ToLower(name)
+	if n.exact[name] {
+		return true
+	}
+	for _, prefix := range n.prefixes {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeQuery drops tracking and empty parameters and sorts the rest by name. Values keep their
+// encoding, and repeated parameters keep their relative order since it can be significant.
+func (n *Normalizer) normalizeQuery(rawQuery string) (string, bool) {
+	if rawQuery == "" {
+		return "", false
+	}
+	var params []string
+	stripped := false
+	for _, param := range strings.Split(rawQuery, "&") {
+		if param == "" {
+			continue
+		}
+		name, _, _ := strings.Cut(param, "=")
+		if unescaped, err := url.QueryUnescape(name); err == nil {
+			name = unescaped
+		}
+		if n.IsTracking(name) {
+			stripped = true
+			continue
+		}
+		params = append(params, param)
+	}
+	sort.SliceStable(params, func(i, j int) bool {
+		return paramName(params[i]) < paramName(params[j])
+	})
+	return strings.Join(params, "&"), stripped
+}
+
+func paramName(param string) string {
+	name, _, _ := strings.Cut(param, "=")
+	return name
+}
+
+// removeDotSegments resolves "." and ".." segments of an absolute path as described in RFC 3986 section 5.2.4
+func removeDotSegments(p string) string {
+	if !strings.Contains(p, ".") {
+		return p
+	}
+	segments := strings.Split(p, "/")
+	out := make([]string, 0, len(segments))
+	for i, s := range segments {
+		last := i == len(segments)-1
+		switch s {
+		case ".":
+		case "..":
+			if len(out) > 1 {
+				out = out[:len(out)-1]
+			}
+		default:
+			out = append(out, s)
+			continue
+		}
+		// a trailing dot segment leaves a directory path
+		if last {
+			out = append(out, "")
+		}
+	}
+	return strings.Join(out, "/")
+}
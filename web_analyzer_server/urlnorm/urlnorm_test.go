@@ -0,0 +1,52 @@
+package urlnorm
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalize(t *testing.T) {
+	n := New(DefaultTrackingParams)
+	tests := []struct {
+		in, want string
+	}{
+		{"HTTPS://Example.COM", "https://example.com/"},
+		{"http://example.com:80/a", "http://example.com/a"},
+		{"https://example.com:443/a", "https://example.com/a"},
+		{"https://example.com:8443/a", "https://example.com:8443/a"},
+		{"https://example.com/a/./b/../c", "https://example.com/a/c"},
+		{"https://example.com/a/b/..", "https://example.com/a/"},
+		{"https://example.com/../a", "https://example.com/a"},
+		{"https://example.com/a?b=2&a=1#section", "https://example.com/a?a=1&b=2"},
+		{"https://example.com/?tag=x&tag=a", "https://example.com/?tag=x&tag=a"},
+		{"https://example.com/p?utm_source=news&id=7&UTM_Medium=mail&gclid=abc&fbclid=def", "https://example.com/p?id=7"},
+		{"https://example.com/p?utm_source=news", "https://example.com/p"},
+		{"https://example.com/p?", "https://example.com/p"},
+		{"https://bücher.example/katalog", "https://xn--bcher-kva.example/katalog"},
+		{"https://example.com/a%2Fb/c", "https://example.com/a%2Fb/c"},
+		{"http://[::1]:80/", "http://[::1]/"},
+	}
+	for _, tt := range tests {
+		got, err := n.Normalize(tt.in)
+		require.NoError(t, err, tt.in)
+		assert.Equal(t, tt.want, got, tt.in)
+	}
+
+	_, err := n.Normalize("http://[::1")
+	assert.Error(t, err)
+}
+
+func TestNormalizeURLReportsTracking(t *testing.T) {
+	n := New([]string{"ref", "campaign_*"})
+	u, _ := url.Parse("https://example.com/?ref=home&campaign_id=1&utm_source=x")
+	canonical, stripped := n.NormalizeURL(u)
+	assert.True(t, stripped)
+	assert.Equal(t, "https://example.com/?utm_source=x", canonical.String())
+	assert.Equal(t, "https://example.com/?ref=home&campaign_id=1&utm_source=x", u.String(), "the input is not modified")
+
+	_, stripped = New(nil).NormalizeURL(u)
+	assert.False(t, stripped)
+}
@@ -0,0 +1,286 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/sashithaf16/peekalo/hostpolicy"
+	"github.com/sashithaf16/peekalo/logger"
+	"github.com/sashithaf16/peekalo/metrics"
+)
+
+// maxDeliveries bounds the in-memory delivery log; the oldest deliveries are dropped first
+const maxDeliveries = 500
+
+// Delivery states
+const (
+	StatusPending   = "pending"
+	StatusDelivered = "delivered"
+	StatusFailed    = "failed"
+)
+
+type HttpClientInterface interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// Attempt is one POST of a delivery
+type Attempt struct {
+	Time       time.Time `json:"time"`
+	StatusCode int       `json:"status_code,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	DurationMS int64     `json:"duration_ms"`
+}
+
+// Delivery is the log entry of one webhook. The target's secret is never recorded.
+type Delivery struct {
+	ID        string    `json:"id"`
+	Event     string    `json:"event"`
+	URL       string    `json:"url"`
+	Status    string    `json:"status"`
+	CreatedAt time.Time `json:"created_at"`
+	Attempts  []Attempt `json:"attempts"`
+}
+
+// Dispatcher delivers webhooks in the background, retrying failed attempts with exponential backoff,
+// and keeps a log of recent deliveries
+type Dispatcher struct {
+	logger      logger.Logger
+	httpClient  HttpClientInterface
+	userAgent   string
+	maxAttempts int
+	timeout     time.Duration
+	backoff     func(attempt int) time.Duration
+	now         func() time.Time
+	hosts       *hostpolicy.Policy
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	mu         sync.Mutex
+	deliveries []*Delivery // oldest first
+	byID       map[string]*Delivery
+}
+
+// Option configures a Dispatcher
+type Option func(*Dispatcher)
+
+// WithHostPolicy refuses webhooks to URLs whose host the policy does not permit. The HTTP client
+// should come from the same policy's Client method, so redirects and resolved addresses are checked too.
+func WithHostPolicy(p *hostpolicy.Policy) Option {
+	return func(d *Dispatcher) {
+		d.hosts = p
+	}
+}
+
+// NewDispatcher makes up to maxAttempts POSTs per webhook, each bounded by timeout
+func NewDispatcher(logger logger.Logger, httpClient HttpClientInterface, userAgent string, maxAttempts int, timeout time.Duration, opts ...Option) *Dispatcher {
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	d := &Dispatcher{
+		logger:      logger,
+		httpClient:  httpClient,
+		userAgent:   userAgent,
+		maxAttempts: maxAttempts,
+		timeout:     timeout,
+		backoff:     exponentialBackoff,
+		now:         time.Now,
+		ctx:         ctx,
+		cancel:      cancel,
+		byID:        make(map[string]*Delivery),
+	}
+	for _, opt := range opts {
+		opt(d)
+	}
+	return d
+}
+
+// CheckTarget reports whether webhooks can be sent to target. Targets refused by the host policy
+// return an error wrapping hostpolicy.ErrNotAllowed.
+func (d *Dispatcher) CheckTarget(target Target) error {
+	if err := target.Validate(); err != nil {
+		return err
+	}
+	return d.hosts.CheckURL(target.URL)
+}
+
+// exponentialBackoff waits 1s, 2s, 4s, ... between attempts, capped at one minute
+func exponentialBackoff(attempt int) time.Duration {
+	d := time.Second << (attempt - 1)
+	if d <= 0 || d > time.Minute {
+		return time.Minute
+	}
+	return d
+}
+
+// Send queues data for delivery to target as event and returns the delivery ID
+func (d *Dispatcher) Send(target Target, event string, data interface{}) (string, error) {
+	if err := d.CheckTarget(target); err != nil {
+		return "", err
+	}
+	id, err := newID()
+	if err != nil {
+		return "", err
+	}
+	delivery := &Delivery{ID: id, Event: event, URL: target.URL, Status: StatusPending, CreatedAt: d.now().UTC()}
+	body, err := json.Marshal(Payload{ID: id, Event: event, CreatedAt: delivery.CreatedAt, Data: data})
+	if err != nil {
+		return "", fmt.Errorf("failed to encode webhook payload: %v", err)
+	}
+
+	d.mu.Lock()
+	d.deliveries = append(d.deliveries, delivery)
+	d.byID[id] = delivery
+	if len(d.deliveries) > maxDeliveries {
+		delete(d.byID, d.deliveries[0].ID)
+		d.deliveries = d.deliveries[1:]
+	}
+	d.mu.Unlock()
+
+	d.wg.Add(1)
+	go d.deliver(delivery, target, body)
+	return id, nil
+}
+
+// Deliveries returns up to limit logged deliveries, newest first. A limit of 0 returns all.
+func (d *Dispatcher) Deliveries(limit int) []Delivery {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	n := len(d.deliveries)
+	if limit > 0 && limit < n {
+		n = limit
+	}
+	list := make([]Delivery, 0, n)
+	for i := len(d.deliveries) - 1; i >= 0 && len(list) < n; i-- {
+		list = append(list, d.deliveries[i].copy())
+	}
+	return list
+}
+
+// Delivery returns the logged delivery with id
+func (d *Dispatcher) Delivery(id string) (Delivery, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delivery, ok := d.byID[id]
+	if !ok {
+		return Delivery{}, false
+	}
+	return delivery.copy(), true
+}
+
+// Close abandons pending retries and waits for in-progress attempts to finish
+func (d *Dispatcher) Close() {
+	d.cancel()
+	d.wg.Wait()
+}
+
+func (d *Dispatcher) deliver(delivery *Delivery, target Target, body []byte) {
+	defer d.wg.Done()
+	log := d.logger.With().Str("delivery_id", delivery.ID).Str("event", delivery.Event).Logger()
+
+	for attempt := 1; ; attempt++ {
+		result, retry := d.attempt(delivery, target, body)
+
+		d.mu.Lock()
+		delivery.Attempts = append(delivery.Attempts, result)
+		switch {
+		case result.Error == "":
+			delivery.Status = StatusDelivered
+		case !retry || attempt >= d.maxAttempts:
+			delivery.Status = StatusFailed
+		}
+		status := delivery.Status
+		d.mu.Unlock()
+
+		if status != StatusPending {
+			metrics.WebhookDeliveryCount.WithLabelValues(status).Inc()
+			if status == StatusFailed {
+				log.Warn().Msgf("Webhook delivery to %s failed after %d attempts: %s", target.URL, attempt, result.Error)
+			} else {
+				log.Debug().Msgf("Webhook delivered to %s", target.URL)
+			}
+			return
+		}
+
+		timer := time.NewTimer(d.backoff(attempt))
+		select {
+		case <-d.ctx.Done():
+			timer.Stop()
+			d.mu.Lock()
+			delivery.Status = StatusFailed
+			d.mu.Unlock()
+			metrics.WebhookDeliveryCount.WithLabelValues(StatusFailed).Inc()
+			log.Warn().Msgf("Webhook delivery to %s abandoned on shutdown", target.URL)
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+// attempt POSTs the payload once. Network errors, 408, 429 and 5xx responses are retried.
+func (d *Dispatcher) attempt(delivery *Delivery, target Target, body []byte) (Attempt, bool) {
+	start := d.now()
+	result := Attempt{Time: start.UTC()}
+
+	ctx, cancel := context.WithTimeout(d.ctx, d.timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target.URL, bytes.NewReader(body))
+	if err != nil {
+		result.Error = fmt.Sprintf("failed to create webhook request: %v", err)
+		return result, false
+	}
+	// signed per attempt so receivers can apply a tight timestamp tolerance to retries too
+	timestamp := d.now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(SignatureHeader, Sign(target.Secret, timestamp, body))
+	req.Header.Set(TimestampHeader, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(EventHeader, delivery.Event)
+	req.Header.Set(DeliveryHeader, delivery.ID)
+	if d.userAgent != "" {
+		req.Header.Set("User-Agent", d.userAgent)
+	}
+
+	resp, err := d.httpClient.Do(req)
+	if err != nil {
+		result.Error = fmt.Sprintf("failed to send webhook: %v", err)
+		result.DurationMS = time.Since(start).Milliseconds()
+		return result, true
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024)) // lets the connection be reused
+
+	result.StatusCode = resp.StatusCode
+	result.DurationMS = time.Since(start).Milliseconds()
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return result, false
+	}
+	result.Error = fmt.Sprintf("receiver returned status %d", resp.StatusCode)
+	retry := resp.StatusCode == http.StatusRequestTimeout || resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+	return result, retry
+}
+
+func (delivery *Delivery) copy() Delivery {
+	c := *delivery
+	c.Attempts = append([]Attempt(nil), delivery.Attempts...)
+	return c
+}
+
+func newID() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate delivery id: %v", err)
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,171 @@
+package webhook
+
+import (
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sashithaf16/peekalo/hostpolicy"
+	"github.com/sashithaf16/peekalo/logger"
+)
+
+const testSecret = "s3cret"
+
+func TestSignAndVerify(t *testing.T) {
+	body := []byte(`{"event":"analysis.completed"}`)
+	now := time.Unix(1750000000, 0)
+	sig := Sign(testSecret, now.Unix(), body)
+	assert.Regexp(t, `^sha256=[0-9a-f]{64}$`, sig)
+
+	ts := strconv.FormatInt(now.Unix(), 10)
+	assert.NoError(t, Verify(testSecret, sig, ts, body, 5*time.Minute, now.Add(time.Minute)))
+	assert.ErrorIs(t, Verify("other", sig, ts, body, 5*time.Minute, now), ErrInvalidSignature)
+	assert.ErrorIs(t, Verify(testSecret, sig, ts, []byte(`{}`), 5*time.Minute, now), ErrInvalidSignature)
+	assert.ErrorIs(t, Verify(testSecret, sig, ts, body, 5*time.Minute, now.Add(time.Hour)), ErrInvalidSignature)
+	assert.ErrorIs(t, Verify(testSecret, sig, "yesterday", body, 5*time.Minute, now), ErrInvalidSignature)
+}
+
+func TestTargetValidate(t *testing.T) {
+	assert.NoError(t, Target{URL: "https://hooks.example.com/peekalo", Secret: testSecret}.Validate())
+	assert.Error(t, Target{URL: "https://hooks.example.com/peekalo"}.Validate())
+	assert.Error(t, Target{URL: "ftp://hooks.example.com", Secret: testSecret}.Validate())
+}
+
+func newTestDispatcher(maxAttempts int) *Dispatcher {
+	d := NewDispatcher(logger.CreateLogger("debug"), http.DefaultClient, "Peekalo/1.0", maxAttempts, time.Second)
+	d.backoff = func(int) time.Duration { return time.Millisecond }
+	return d
+}
+
+func waitForStatus(t *testing.T, d *Dispatcher, id string) Delivery {
+	var delivery Delivery
+	require.Eventually(t, func() bool {
+		delivery, _ = d.Delivery(id)
+		return delivery.Status != StatusPending
+	}, 2*time.Second, 5*time.Millisecond)
+	return delivery
+}
+
+func TestDispatcherDelivers(t *testing.T) {
+	received := make(chan *http.Request, 1)
+	var body []byte
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = io.ReadAll(r.Body)
+		received <- r
+	}))
+	defer receiver.Close()
+
+	d := newTestDispatcher(3)
+	defer d.Close()
+
+	id, err := d.Send(Target{URL: receiver.URL, Secret: testSecret}, EventAnalysisCompleted, map[string]string{"url": "https://example.com"})
+	require.NoError(t, err)
+
+	r := <-received
+	assert.Equal(t, EventAnalysisCompleted, r.Header.Get(EventHeader))
+	assert.Equal(t, id, r.Header.Get(DeliveryHeader))
+	assert.Equal(t, "Peekalo/1.0", r.Header.Get("User-Agent"))
+	assert.NoError(t, Verify(testSecret, r.Header.Get(SignatureHeader), r.Header.Get(TimestampHeader), body, time.Minute, time.Now()))
+
+	var payload Payload
+	require.NoError(t, json.Unmarshal(body, &payload))
+	assert.Equal(t, id, payload.ID)
+	assert.Equal(t, "https://example.com", payload.Data.(map[string]interface{})["url"])
+
+	delivery := waitForStatus(t, d, id)
+	assert.Equal(t, StatusDelivered, delivery.Status)
+	require.Len(t, delivery.Attempts, 1)
+	assert.Equal(t, http.StatusOK, delivery.Attempts[0].StatusCode)
+}
+
+func TestDispatcherRetries(t *testing.T) {
+	var calls atomic.Int32
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer receiver.Close()
+
+	d := newTestDispatcher(5)
+	defer d.Close()
+
+	id, err := d.Send(Target{URL: receiver.URL, Secret: testSecret}, EventMonitorAlert, nil)
+	require.NoError(t, err)
+	delivery := waitForStatus(t, d, id)
+	assert.Equal(t, StatusDelivered, delivery.Status)
+	require.Len(t, delivery.Attempts, 3)
+	assert.Equal(t, http.StatusServiceUnavailable, delivery.Attempts[0].StatusCode)
+}
+
+func TestDispatcherGivesUp(t *testing.T) {
+	var calls atomic.Int32
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		if r.URL.Path == "/gone" {
+			w.WriteHeader(http.StatusGone)
+			return
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer receiver.Close()
+
+	d := newTestDispatcher(3)
+	defer d.Close()
+
+	id, err := d.Send(Target{URL: receiver.URL, Secret: testSecret}, EventMonitorAlert, nil)
+	require.NoError(t, err)
+	delivery := waitForStatus(t, d, id)
+	assert.Equal(t, StatusFailed, delivery.Status)
+	assert.Len(t, delivery.Attempts, 3)
+
+	// client errors other than 408 and 429 are not retried
+	id, err = d.Send(Target{URL: receiver.URL + "/gone", Secret: testSecret}, EventMonitorAlert, nil)
+	require.NoError(t, err)
+	delivery = waitForStatus(t, d, id)
+	assert.Equal(t, StatusFailed, delivery.Status)
+	assert.Len(t, delivery.Attempts, 1)
+	assert.Equal(t, int32(4), calls.Load())
+
+	deliveries := d.Deliveries(0)
+	require.Len(t, deliveries, 2)
+	assert.Equal(t, id, deliveries[0].ID)
+}
+
+func TestDispatcherRefusesDeniedTargets(t *testing.T) {
+	hosts, err := hostpolicy.New(nil, DefaultDeniedHosts)
+	require.NoError(t, err)
+	d := NewDispatcher(logger.CreateLogger("debug"), hosts.Client(&http.Client{}), "Peekalo/1.0", 1, time.Second, WithHostPolicy(hosts))
+	defer d.Close()
+
+	for _, target := range []string{"http://127.0.0.1:8080/hook", "http://localhost/hook", "http://169.254.169.254/latest/meta-data/", "http://[::1]/hook", "http://metadata.google.internal/"} {
+		_, err := d.Send(Target{URL: target, Secret: testSecret}, EventAnalysisCompleted, nil)
+		assert.ErrorIs(t, err, hostpolicy.ErrNotAllowed, target)
+	}
+	assert.Empty(t, d.Deliveries(0), "refused webhooks are not queued")
+	assert.NoError(t, d.CheckTarget(Target{URL: "https://hooks.example.com/peekalo", Secret: testSecret}))
+
+	// hostnames resolving to a denied address are refused when connecting
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer receiver.Close()
+	_, port, _ := net.SplitHostPort(receiver.Listener.Addr().String())
+	hosts, err = hostpolicy.New(nil, []string{"127.0.0.0/8"})
+	require.NoError(t, err)
+	d = NewDispatcher(logger.CreateLogger("debug"), hosts.Client(&http.Client{}), "Peekalo/1.0", 1, time.Second, WithHostPolicy(hosts))
+	defer d.Close()
+	id, err := d.Send(Target{URL: "http://localhost:" + port + "/", Secret: testSecret}, EventAnalysisCompleted, nil)
+	require.NoError(t, err)
+	delivery := waitForStatus(t, d, id)
+	assert.Equal(t, StatusFailed, delivery.Status)
+	require.Len(t, delivery.Attempts, 1)
+	assert.Contains(t, delivery.Attempts[0].Error, "denied")
+}